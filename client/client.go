@@ -21,19 +21,17 @@ import (
 	"crypto/ecdh"
 	"crypto/hpke"
 	"crypto/rand"
-	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 	"time"
 
 	log "github.com/golang/glog"
+	"github.com/openconfig/bootz/common/imagehash"
 	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
 	ownershipvoucher "github.com/openconfig/bootz/common/ownership_voucher"
 	"github.com/openconfig/bootz/common/signature"
@@ -76,27 +74,20 @@ func validateChassis(chassis *cpb.Chassis) {
 
 // validateArtifacts checks the Ownership Voucher, Owner Certificate, and Bootstrap Data Signature.
 // Specifically, it:
-// - Checks that the OV is signed by the manufacturer.
+// - Checks that the OV is signed by the manufacturer and is valid according to RFC 8366.
 // - Checks that the serial number in the OV matches the one in the original request.
 // - Verifies that the Owner Certificate is in the chain of signers of the Pinned Domain Cert.
 // - Verifies the signature over the bootstrap data.
-func validateArtifacts(serialNumber string, ov, oc, data []byte, sigString string) error {
-	// Normally, clients should unmarshal the OV CMS struct and verify that it has been signed by a trusted CA.
-	// E.g.:
-	// certPool := x509.NewCertPool()
-	// certPool.AddCert(vendorCA)
-	// parsedOV, err := ownershipvoucher.Unmarshal(ov, certPool)
-	// In this emulator, we don't have a static Vendor Certificate Authority so we unmarshal without verifying.
-	parsedOV, err := ownershipvoucher.Unmarshal(ov, nil)
+func validateArtifacts(serialNumber string, vendorCAPool *x509.CertPool, ov, oc, data []byte, sigString string) error {
+	// A real device would have the vendor CA built in. The emulator takes it from the config file.
+	parsedOV, err := ownershipvoucher.Validate(ov, &ownershipvoucher.ValidateOpts{
+		VendorCAPool: vendorCAPool,
+		SerialNumber: serialNumber,
+	})
 	if err != nil {
 		return fmt.Errorf("unable to verify ownership voucher: %v", err)
 	}
 	log.Infof("Validated ownership voucher signed by vendor")
-
-	// Validate the serial number for this OV
-	if parsedOV.OV.SerialNumber != serialNumber {
-		return fmt.Errorf("serial number from OV does not match request")
-	}
 	log.Infof("Validated serial number: %q", serialNumber)
 
 	// Create a new pool with this PDC.
@@ -131,22 +122,27 @@ func validateArtifacts(serialNumber string, ov, oc, data []byte, sigString strin
 	return nil
 }
 
-// validateImage validates if the hash of the downloaded OS image matches the received image hash.
-func validateImage(image []byte, softwareImage *bpb.SoftwareImage) error {
-	var hashed [32]byte
-	if softwareImage.GetHashAlgorithm() == "ietf-sztp-conveyed-info:sha-256" {
-		hashed = sha256.Sum256(image)
-	} else {
-		return fmt.Errorf("unknown hash algorithm: %q", softwareImage.GetHashAlgorithm())
+// parseVendorCAPool builds the pool of vendor CA certificates used to verify ownership vouchers.
+func parseVendorCAPool(config *cpb.Config) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, v := range config.GetVendorCaCerts() {
+		certBytes, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode vendor CA certificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(certBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vendor CA certificate: %v", err)
+		}
+		pool.AddCert(cert)
 	}
+	return pool, nil
+}
 
-	// The hash string from the server may contain colons, which need to be removed.
-	receivedHashed, err := hex.DecodeString(strings.ReplaceAll(softwareImage.GetOsImageHash(), ":", ""))
-	if err != nil {
-		return fmt.Errorf("can not decode received hashed image to bytes, received hash: %q", softwareImage.GetOsImageHash())
-	}
-	if !bytes.Equal(hashed[:], receivedHashed) {
-		return fmt.Errorf("unmatched hash, received: %x, downloaded: %x", receivedHashed, hashed[:])
+// validateImage validates if the hash of the downloaded OS image matches the received image hash.
+func validateImage(image []byte, softwareImage *bpb.SoftwareImage) error {
+	if err := imagehash.Verify(bytes.NewReader(image), softwareImage); err != nil {
+		return err
 	}
 	log.Info("Verified image hash")
 	return nil
@@ -426,6 +422,10 @@ func main() {
 		PrivateKey:  idevidKey,
 		Leaf:        idevidCert,
 	}
+	vendorCAPool, err := parseVendorCAPool(config)
+	if err != nil {
+		log.Exitf("Error parsing vendor CA certificates: %v", err)
+	}
 
 	// DHCP Discovery of Bootstrap Server
 	// This step retrieves the Bootz server IP address from a DHCP server.
@@ -506,7 +506,7 @@ func main() {
 
 	// Only check OC, OV and response signature if SecureOnly is set.
 	if !*insecureBoot {
-		if err := validateArtifacts(controlCardState.GetSerialNumber(), ov, oc, data, sig); err != nil {
+		if err := validateArtifacts(controlCardState.GetSerialNumber(), vendorCAPool, ov, oc, data, sig); err != nil {
 			log.Exitf("Error validating signed bootstrap data: %v", err)
 		}
 	}
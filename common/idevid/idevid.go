@@ -0,0 +1,232 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package idevid extracts device serial numbers from IEEE 802.1AR IDevID certificates.
+// Vendors encode the serial number differently, so extractors are looked up per manufacturer in a Registry.
+package idevid
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Names of the built-in serial extractors.
+const (
+	// Default is the serial number after "SN:" in the subject serialNumber, or the whole subject serialNumber if it has no "SN:".
+	Default = "default"
+	// SubjectSerialNumber is the subject serialNumber attribute.
+	SubjectSerialNumber = "subject-serial-number"
+	// PIDSerialNumber is the serial number in a subject serialNumber of the form "PID:x SN:y".
+	PIDSerialNumber = "pid-sn"
+	// HardwareModuleName is the serial number in the hardwareModuleName otherName of the subject alternative name (RFC 4108).
+	HardwareModuleName = "hardware-module-name"
+	// CommonName is the subject common name.
+	CommonName = "common-name"
+)
+
+var (
+	// OIDHardwareModuleName is the id-on-hardwareModuleName otherName type defined in RFC 4108.
+	OIDHardwareModuleName = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 8, 4}
+	oidSubjectAltName     = asn1.ObjectIdentifier{2, 5, 29, 17}
+)
+
+// ErrNoSerial is returned when an extractor does not find a serial number in the certificate.
+var ErrNoSerial = errors.New("no serial number found")
+
+// SerialExtractor returns the device serial number encoded in an IDevID certificate.
+type SerialExtractor func(cert *x509.Certificate) (string, error)
+
+var (
+	mu         sync.RWMutex
+	extractors = map[string]SerialExtractor{
+		Default:             defaultSerial,
+		SubjectSerialNumber: subjectSerialNumber,
+		PIDSerialNumber:     pidSerialNumber,
+		HardwareModuleName:  hardwareModuleName,
+		CommonName:          commonName,
+	}
+)
+
+// RegisterExtractor registers a named serial extractor so that it can be referenced from the server config.
+func RegisterExtractor(name string, e SerialExtractor) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := extractors[name]; ok {
+		return fmt.Errorf("serial extractor %q is already registered", name)
+	}
+	extractors[name] = e
+	return nil
+}
+
+// ExtractorByName returns the registered serial extractor with the given name.
+func ExtractorByName(name string) (SerialExtractor, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := extractors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown serial extractor %q", name)
+	}
+	return e, nil
+}
+
+// FirstOf returns an extractor which returns the serial number found by the first of the extractors that succeeds.
+func FirstOf(es ...SerialExtractor) SerialExtractor {
+	return func(cert *x509.Certificate) (string, error) {
+		var errs []error
+		for _, e := range es {
+			serial, err := e(cert)
+			if err == nil {
+				return serial, nil
+			}
+			errs = append(errs, err)
+		}
+		return "", errors.Join(errs...)
+	}
+}
+
+func defaultSerial(cert *x509.Certificate) (string, error) {
+	// The subject serialNumber can come in the format PID:xxxxxxx SN:1234JF or just the serial number as it is.
+	if sn := strings.Split(cert.Subject.SerialNumber, "SN:"); len(sn) == 2 {
+		return nonEmpty(strings.TrimSpace(sn[1]), "subject serialNumber")
+	}
+	return nonEmpty(strings.TrimSpace(cert.Subject.SerialNumber), "subject serialNumber")
+}
+
+func subjectSerialNumber(cert *x509.Certificate) (string, error) {
+	return nonEmpty(strings.TrimSpace(cert.Subject.SerialNumber), "subject serialNumber")
+}
+
+func pidSerialNumber(cert *x509.Certificate) (string, error) {
+	for _, field := range strings.Fields(cert.Subject.SerialNumber) {
+		if serial, ok := strings.CutPrefix(field, "SN:"); ok {
+			return nonEmpty(serial, "SN field of subject serialNumber")
+		}
+	}
+	return "", fmt.Errorf("%w: subject serialNumber %q has no SN field", ErrNoSerial, cert.Subject.SerialNumber)
+}
+
+func commonName(cert *x509.Certificate) (string, error) {
+	return nonEmpty(strings.TrimSpace(cert.Subject.CommonName), "subject common name")
+}
+
+func nonEmpty(serial, source string) (string, error) {
+	if serial == "" {
+		return "", fmt.Errorf("%w: empty %v", ErrNoSerial, source)
+	}
+	return serial, nil
+}
+
+// otherName is the OtherName GeneralName defined in RFC 5280.
+type otherName struct {
+	TypeID asn1.ObjectIdentifier
+	Value  asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// hardwareModuleNameValue is the HardwareModuleName defined in RFC 4108.
+type hardwareModuleNameValue struct {
+	HWType      asn1.ObjectIdentifier
+	HWSerialNum []byte
+}
+
+func hardwareModuleName(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSubjectAltName) {
+			continue
+		}
+		var names asn1.RawValue
+		if rest, err := asn1.Unmarshal(ext.Value, &names); err != nil || len(rest) > 0 || names.Tag != asn1.TagSequence {
+			return "", fmt.Errorf("malformed subject alternative name extension")
+		}
+		for rest := names.Bytes; len(rest) > 0; {
+			var name asn1.RawValue
+			var err error
+			if rest, err = asn1.Unmarshal(rest, &name); err != nil {
+				return "", fmt.Errorf("malformed subject alternative name: %v", err)
+			}
+			if name.Class != asn1.ClassContextSpecific || name.Tag != 0 {
+				continue
+			}
+			var on otherName
+			if _, err := asn1.UnmarshalWithParams(name.FullBytes, &on, "tag:0"); err != nil {
+				return "", fmt.Errorf("malformed otherName: %v", err)
+			}
+			if !on.TypeID.Equal(OIDHardwareModuleName) {
+				continue
+			}
+			var hmn hardwareModuleNameValue
+			if _, err := asn1.Unmarshal(on.Value.Bytes, &hmn); err != nil {
+				return "", fmt.Errorf("malformed hardwareModuleName: %v", err)
+			}
+			return nonEmpty(string(hmn.HWSerialNum), "hardwareModuleName hwSerialNum")
+		}
+	}
+	return "", fmt.Errorf("%w: no hardwareModuleName in subject alternative name", ErrNoSerial)
+}
+
+// HardwareModuleNameExtension returns a subject alternative name extension containing a hardwareModuleName otherName.
+func HardwareModuleNameExtension(hwType asn1.ObjectIdentifier, serial string) (pkix.Extension, error) {
+	hmn, err := asn1.Marshal(hardwareModuleNameValue{HWType: hwType, HWSerialNum: []byte(serial)})
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("unable to marshal hardwareModuleName: %v", err)
+	}
+	on, err := asn1.MarshalWithParams(otherName{
+		TypeID: OIDHardwareModuleName,
+		Value:  asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: hmn},
+	}, "tag:0")
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("unable to marshal otherName: %v", err)
+	}
+	names, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: on})
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("unable to marshal subject alternative name: %v", err)
+	}
+	return pkix.Extension{Id: oidSubjectAltName, Value: names}, nil
+}
+
+// Registry maps chassis manufacturers to the serial extractors used for their IDevID certificates.
+type Registry struct {
+	def            SerialExtractor
+	byManufacturer map[string]SerialExtractor
+}
+
+// NewRegistry returns a registry which uses the Default extractor for all manufacturers.
+func NewRegistry() *Registry {
+	return &Registry{
+		def:            defaultSerial,
+		byManufacturer: make(map[string]SerialExtractor),
+	}
+}
+
+// SetDefault sets the extractor used for manufacturers without one.
+func (r *Registry) SetDefault(e SerialExtractor) {
+	r.def = e
+}
+
+// Register sets the extractor used for the manufacturer, which is matched case-insensitively.
+func (r *Registry) Register(manufacturer string, e SerialExtractor) {
+	r.byManufacturer[strings.ToLower(manufacturer)] = e
+}
+
+// Serial returns the serial number in the IDevID certificate of a chassis of the manufacturer.
+func (r *Registry) Serial(cert *x509.Certificate, manufacturer string) (string, error) {
+	if e, ok := r.byManufacturer[strings.ToLower(manufacturer)]; ok {
+		return e(cert)
+	}
+	return r.def(cert)
+}
@@ -0,0 +1,201 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idevid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testHWType is an arbitrary hardware module type.
+var testHWType = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 9, 12, 3, 1, 3}
+
+func newCert(t *testing.T, subject pkix.Name, hwSerial string, dnsNames ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      subject,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+	}
+	if hwSerial != "" {
+		ext, err := HardwareModuleNameExtension(testHWType, hwSerial)
+		if err != nil {
+			t.Fatalf("HardwareModuleNameExtension() err = %v", err)
+		}
+		if len(dnsNames) > 0 {
+			// Combine the hardwareModuleName with the DNS names in a single SAN extension.
+			ext = sanWithDNSNames(t, ext, dnsNames)
+			template.DNSNames = nil
+		}
+		template.ExtraExtensions = []pkix.Extension{ext}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+	return cert
+}
+
+// sanWithDNSNames prepends dNSName GeneralNames to the SAN extension.
+func sanWithDNSNames(t *testing.T, ext pkix.Extension, dnsNames []string) pkix.Extension {
+	t.Helper()
+	var names asn1.RawValue
+	if _, err := asn1.Unmarshal(ext.Value, &names); err != nil {
+		t.Fatalf("unable to unmarshal SAN: %v", err)
+	}
+	var content []byte
+	for _, n := range dnsNames {
+		b, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(n)})
+		if err != nil {
+			t.Fatalf("unable to marshal dNSName: %v", err)
+		}
+		content = append(content, b...)
+	}
+	content = append(content, names.Bytes...)
+	value, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: content})
+	if err != nil {
+		t.Fatalf("unable to marshal SAN: %v", err)
+	}
+	return pkix.Extension{Id: ext.Id, Value: value}
+}
+
+func TestExtractors(t *testing.T) {
+	plain := newCert(t, pkix.Name{CommonName: "device-cn", SerialNumber: "1234JF"}, "")
+	pidSN := newCert(t, pkix.Name{CommonName: "device-cn", SerialNumber: "PID:8201-32FH SN:FOC1234JF"}, "")
+	pidSNVID := newCert(t, pkix.Name{SerialNumber: "PID:8201-32FH SN:FOC1234JF VID:V01"}, "")
+	hwName := newCert(t, pkix.Name{CommonName: "device-cn"}, "HW5678")
+	hwNameWithDNS := newCert(t, pkix.Name{}, "HW5678", "device.example.com")
+	empty := newCert(t, pkix.Name{}, "")
+
+	tests := []struct {
+		desc      string
+		extractor string
+		cert      *x509.Certificate
+		want      string
+		wantErr   bool
+	}{
+		{desc: "Default plain", extractor: Default, cert: plain, want: "1234JF"},
+		{desc: "Default PID SN", extractor: Default, cert: pidSN, want: "FOC1234JF"},
+		{desc: "Default empty", extractor: Default, cert: empty, wantErr: true},
+		{desc: "Subject serialNumber", extractor: SubjectSerialNumber, cert: pidSN, want: "PID:8201-32FH SN:FOC1234JF"},
+		{desc: "Subject serialNumber empty", extractor: SubjectSerialNumber, cert: hwName, wantErr: true},
+		{desc: "PID SN", extractor: PIDSerialNumber, cert: pidSN, want: "FOC1234JF"},
+		{desc: "PID SN VID", extractor: PIDSerialNumber, cert: pidSNVID, want: "FOC1234JF"},
+		{desc: "PID SN without SN", extractor: PIDSerialNumber, cert: plain, wantErr: true},
+		{desc: "Hardware module name", extractor: HardwareModuleName, cert: hwName, want: "HW5678"},
+		{desc: "Hardware module name after DNS name", extractor: HardwareModuleName, cert: hwNameWithDNS, want: "HW5678"},
+		{desc: "Hardware module name missing", extractor: HardwareModuleName, cert: plain, wantErr: true},
+		{desc: "Common name", extractor: CommonName, cert: plain, want: "device-cn"},
+		{desc: "Common name empty", extractor: CommonName, cert: empty, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			e, err := ExtractorByName(test.extractor)
+			if err != nil {
+				t.Fatalf("ExtractorByName(%q) err = %v", test.extractor, err)
+			}
+			got, err := e(test.cert)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("extractor err = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrNoSerial) {
+					t.Errorf("extractor err = %v, want %v", err, ErrNoSerial)
+				}
+				return
+			}
+			if got != test.want {
+				t.Errorf("extractor got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	cert := newCert(t, pkix.Name{CommonName: "device-cn", SerialNumber: "PID:8201-32FH SN:FOC1234JF"}, "HW5678")
+	r := NewRegistry()
+	r.Register("Vendor A", hardwareModuleName)
+	r.Register("Vendor B", FirstOf(hardwareModuleName, commonName))
+	r.Register("Vendor C", FirstOf(pidSerialNumber, commonName))
+
+	tests := []struct {
+		manufacturer string
+		want         string
+	}{
+		{manufacturer: "Other", want: "FOC1234JF"},
+		{manufacturer: "vendor a", want: "HW5678"},
+		{manufacturer: "Vendor B", want: "HW5678"},
+		{manufacturer: "Vendor C", want: "FOC1234JF"},
+	}
+	for _, test := range tests {
+		got, err := r.Serial(cert, test.manufacturer)
+		if err != nil {
+			t.Errorf("Serial(%q) err = %v", test.manufacturer, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Serial(%q) got %q, want %q", test.manufacturer, got, test.want)
+		}
+	}
+
+	r.SetDefault(commonName)
+	if got, err := r.Serial(cert, "Other"); err != nil || got != "device-cn" {
+		t.Errorf("Serial() with new default got %q, %v, want %q", got, err, "device-cn")
+	}
+	noSAN := newCert(t, pkix.Name{CommonName: "device-cn"}, "")
+	if got, err := r.Serial(noSAN, "Vendor B"); err != nil || got != "device-cn" {
+		t.Errorf("Serial() with fallback got %q, %v, want %q", got, err, "device-cn")
+	}
+	if _, err := r.Serial(noSAN, "Vendor A"); !errors.Is(err, ErrNoSerial) {
+		t.Errorf("Serial() err = %v, want %v", err, ErrNoSerial)
+	}
+}
+
+func TestRegisterExtractor(t *testing.T) {
+	if err := RegisterExtractor(Default, commonName); err == nil {
+		t.Errorf("RegisterExtractor(%q) err = nil, want error", Default)
+	}
+	if err := RegisterExtractor("test-custom", func(*x509.Certificate) (string, error) { return "custom", nil }); err != nil {
+		t.Fatalf("RegisterExtractor() err = %v", err)
+	}
+	e, err := ExtractorByName("test-custom")
+	if err != nil {
+		t.Fatalf("ExtractorByName() err = %v", err)
+	}
+	if got, _ := e(nil); got != "custom" {
+		t.Errorf("custom extractor got %q, want %q", got, "custom")
+	}
+	if _, err := ExtractorByName("unknown"); err == nil {
+		t.Errorf("ExtractorByName(%q) err = nil, want error", "unknown")
+	}
+}
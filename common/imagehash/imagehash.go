@@ -0,0 +1,128 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagehash computes and validates the OS image hashes conveyed in a SoftwareImage.
+package imagehash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+)
+
+// Hash algorithm identities of a SoftwareImage.
+const (
+	SHA256 = "ietf-sztp-conveyed-info:sha-256"
+	SHA384 = "ietf-sztp-conveyed-info:sha-384"
+	SHA512 = "ietf-sztp-conveyed-info:sha-512"
+)
+
+// New returns a new hash of the algorithm identity.
+func New(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA384:
+		return sha512.New384(), nil
+	case SHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+	}
+}
+
+// Decode decodes a hex encoded digest, either plain or with its octets separated by colons.
+func Decode(digest string) ([]byte, error) {
+	plain := digest
+	if strings.Contains(digest, ":") {
+		octets := strings.Split(digest, ":")
+		for _, o := range octets {
+			if len(o) != 2 {
+				return nil, fmt.Errorf("malformed hex string %q", digest)
+			}
+		}
+		plain = strings.Join(octets, "")
+	}
+	b, err := hex.DecodeString(plain)
+	if err != nil {
+		return nil, fmt.Errorf("malformed hex string %q: %v", digest, err)
+	}
+	return b, nil
+}
+
+// Normalize returns the canonical representation of a digest: lowercase hex octets separated by colons, as the
+// hex-string of RFC 6991.
+func Normalize(digest string) (string, error) {
+	b, err := Decode(digest)
+	if err != nil {
+		return "", err
+	}
+	octets := make([]string, len(b))
+	for i, o := range b {
+		octets[i] = hex.EncodeToString([]byte{o})
+	}
+	return strings.Join(octets, ":"), nil
+}
+
+// Equal returns whether two digests are equal, regardless of their representation.
+func Equal(a, b string) bool {
+	da, err := Decode(a)
+	if err != nil {
+		return false
+	}
+	db, err := Decode(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(da, db)
+}
+
+// Validate checks that the hash algorithm of the image is supported, and that its hash is a digest of the algorithm.
+func Validate(image *bpb.SoftwareImage) error {
+	h, err := New(image.GetHashAlgorithm())
+	if err != nil {
+		return err
+	}
+	digest, err := Decode(image.GetOsImageHash())
+	if err != nil {
+		return err
+	}
+	if len(digest) != h.Size() {
+		return fmt.Errorf("os image hash has %d bytes, want %d for %v", len(digest), h.Size(), image.GetHashAlgorithm())
+	}
+	return nil
+}
+
+// Verify checks that the content of the reader matches the hash of the image.
+func Verify(r io.Reader, image *bpb.SoftwareImage) error {
+	if err := Validate(image); err != nil {
+		return err
+	}
+	h, _ := New(image.GetHashAlgorithm())
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("unable to read image: %v", err)
+	}
+	want, _ := Decode(image.GetOsImageHash())
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("unmatched hash, received: %x, downloaded: %x", want, got)
+	}
+	return nil
+}
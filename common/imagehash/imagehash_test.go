@@ -0,0 +1,117 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagehash
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		desc    string
+		digest  string
+		want    string
+		wantErr bool
+	}{{
+		desc:   "Plain",
+		digest: "D9A5d10b",
+		want:   "d9:a5:d1:0b",
+	}, {
+		desc:   "Colon separated",
+		digest: "d9:A5:d1:0b",
+		want:   "d9:a5:d1:0b",
+	}, {
+		desc:    "Odd length",
+		digest:  "d9a5d",
+		wantErr: true,
+	}, {
+		desc:    "Misplaced colon",
+		digest:  "d9a:5d1:0b",
+		wantErr: true,
+	}, {
+		desc:    "Not hex",
+		digest:  "zz",
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := Normalize(test.digest)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Normalize(%q) err = %v, want error %v", test.digest, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("Normalize(%q) = %q, want %q", test.digest, got, test.want)
+			}
+		})
+	}
+	if !Equal("d9a5d10b", "D9:A5:D1:0B") {
+		t.Errorf("Equal() of the plain and colon separated digests = false, want true")
+	}
+}
+
+func colonHex(b []byte) string {
+	s, _ := Normalize(hex.EncodeToString(b))
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	image := "image"
+	sum256 := sha256.Sum256([]byte(image))
+	sum384 := sha512.Sum384([]byte(image))
+	sum512 := sha512.Sum512([]byte(image))
+	tests := []struct {
+		desc    string
+		image   *bpb.SoftwareImage
+		wantErr bool
+	}{{
+		desc:  "SHA-256",
+		image: &bpb.SoftwareImage{OsImageHash: hex.EncodeToString(sum256[:]), HashAlgorithm: SHA256},
+	}, {
+		desc:  "SHA-384 colon separated",
+		image: &bpb.SoftwareImage{OsImageHash: colonHex(sum384[:]), HashAlgorithm: SHA384},
+	}, {
+		desc:  "SHA-512 uppercase",
+		image: &bpb.SoftwareImage{OsImageHash: strings.ToUpper(hex.EncodeToString(sum512[:])), HashAlgorithm: SHA512},
+	}, {
+		desc:    "Digest of another algorithm",
+		image:   &bpb.SoftwareImage{OsImageHash: hex.EncodeToString(sum256[:]), HashAlgorithm: SHA512},
+		wantErr: true,
+	}, {
+		desc:    "Other digest",
+		image:   &bpb.SoftwareImage{OsImageHash: hex.EncodeToString(make([]byte, sha256.Size)), HashAlgorithm: SHA256},
+		wantErr: true,
+	}, {
+		desc:    "Unsupported algorithm",
+		image:   &bpb.SoftwareImage{OsImageHash: hex.EncodeToString(sum256[:]), HashAlgorithm: "md5"},
+		wantErr: true,
+	}, {
+		desc:    "Missing algorithm",
+		image:   &bpb.SoftwareImage{OsImageHash: hex.EncodeToString(sum256[:])},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := Verify(strings.NewReader(image), test.image); (err != nil) != test.wantErr {
+				t.Errorf("Verify() err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
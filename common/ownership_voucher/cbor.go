@@ -0,0 +1,339 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ownershipvoucher
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Constrained vouchers are CBOR encoded vouchers signed with COSE_Sign1 (RFC 9052).
+// The voucher map is keyed by YANG SIDs as assigned by the constrained voucher specification,
+// where the keys of the inner map are deltas from the SID of the voucher container.
+const (
+	sidVoucher = 2451
+
+	sidDeltaAssertion                  = 1
+	sidDeltaCreatedOn                  = 2
+	sidDeltaDomainCertRevocationChecks = 3
+	sidDeltaExpiresOn                  = 4
+	sidDeltaPinnedDomainCert           = 8
+	sidDeltaSerialNumber               = 12
+)
+
+// COSE constants from RFC 9052, RFC 9053, RFC 8812 and RFC 9360.
+const (
+	coseSign1Tag = 18
+
+	coseHeaderAlg     = 1
+	coseHeaderX5Chain = 33
+
+	coseAlgES256 = -7
+	coseAlgES384 = -35
+	coseAlgPS256 = -37
+	coseAlgRS256 = -257
+)
+
+// The assertion enumeration is encoded as an integer in CBOR.
+var cborAssertions = []string{AssertionVerified, AssertionLogged, AssertionProximity}
+
+// cborVoucher is the CBOR representation of OVInner.
+type cborVoucher struct {
+	Assertion                  *int   `cbor:"1,keyasint"`
+	CreatedOn                  string `cbor:"2,keyasint,omitempty"`
+	DomainCertRevocationChecks bool   `cbor:"3,keyasint,omitempty"`
+	ExpiresOn                  string `cbor:"4,keyasint,omitempty"`
+	PinnedDomainCert           []byte `cbor:"8,keyasint,omitempty"`
+	SerialNumber               string `cbor:"12,keyasint,omitempty"`
+}
+
+// coseHeader holds the COSE header parameters used by constrained vouchers.
+type coseHeader struct {
+	Alg     int             `cbor:"1,keyasint,omitempty"`
+	X5Chain cbor.RawMessage `cbor:"33,keyasint,omitempty"`
+}
+
+// coseSign1 is the COSE_Sign1 structure without its tag.
+type coseSign1 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected coseHeader
+	Payload     []byte
+	Signature   []byte
+}
+
+// coseSignedVoucher is a parsed COSE_Sign1 constrained voucher.
+type coseSignedVoucher struct {
+	msg       *coseSign1
+	alg       int
+	certChain []*x509.Certificate
+}
+
+// marshalCBOR encodes the voucher as a SID keyed CBOR map.
+func marshalCBOR(inner *OVInner) ([]byte, error) {
+	assertion := -1
+	for i, v := range cborAssertions {
+		if v == inner.Assertion {
+			assertion = i
+		}
+	}
+	if assertion < 0 {
+		return nil, fmt.Errorf("unsupported assertion for CBOR encoding: %q", inner.Assertion)
+	}
+	return cbor.Marshal(map[int]cborVoucher{
+		sidVoucher: {
+			Assertion:                  &assertion,
+			CreatedOn:                  inner.CreatedOn,
+			DomainCertRevocationChecks: inner.DomainCertRevocationChecks,
+			ExpiresOn:                  inner.ExpiresOn,
+			PinnedDomainCert:           inner.PinnedDomainCert,
+			SerialNumber:               inner.SerialNumber,
+		},
+	})
+}
+
+// unmarshalCBOR decodes a SID keyed CBOR voucher.
+func unmarshalCBOR(in []byte) (*OwnershipVoucher, error) {
+	outer := map[int]cborVoucher{}
+	if err := cbor.Unmarshal(in, &outer); err != nil {
+		return nil, err
+	}
+	v, ok := outer[sidVoucher]
+	if !ok {
+		return nil, fmt.Errorf("voucher container (SID %d) not found", sidVoucher)
+	}
+	if v.Assertion == nil {
+		return nil, fmt.Errorf("voucher has no assertion")
+	}
+	// An unknown assertion value is left empty and rejected by Validate.
+	var assertion string
+	if *v.Assertion >= 0 && *v.Assertion < len(cborAssertions) {
+		assertion = cborAssertions[*v.Assertion]
+	}
+	return &OwnershipVoucher{
+		OV: OVInner{
+			CreatedOn:                  v.CreatedOn,
+			ExpiresOn:                  v.ExpiresOn,
+			Assertion:                  assertion,
+			SerialNumber:               v.SerialNumber,
+			PinnedDomainCert:           v.PinnedDomainCert,
+			DomainCertRevocationChecks: v.DomainCertRevocationChecks,
+		},
+	}, nil
+}
+
+// coseAlgorithm selects the COSE signature algorithm for the private key.
+func coseAlgorithm(key crypto.PrivateKey) (int, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return coseAlgPS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return coseAlgES256, nil
+		case elliptic.P384():
+			return coseAlgES384, nil
+		}
+		return 0, fmt.Errorf("unsupported ECDSA curve: %v", k.Curve.Params().Name)
+	default:
+		return 0, fmt.Errorf("unsupported private key type: %T", key)
+	}
+}
+
+// sigStructure builds the Sig_structure for COSE_Sign1 as defined in RFC 9052 section 4.4.
+func sigStructure(protected, payload []byte) ([]byte, error) {
+	return cbor.Marshal([]any{"Signature1", protected, []byte{}, payload})
+}
+
+// digest hashes the input with the hash function associated with the COSE algorithm.
+func digest(alg int, in []byte) (crypto.Hash, []byte, error) {
+	switch alg {
+	case coseAlgES256, coseAlgPS256, coseAlgRS256:
+		v := sha256.Sum256(in)
+		return crypto.SHA256, v[:], nil
+	case coseAlgES384:
+		v := sha512.Sum384(in)
+		return crypto.SHA384, v[:], nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported COSE algorithm: %d", alg)
+	}
+}
+
+// signCOSE wraps the CBOR payload in a COSE_Sign1 structure signed by the vendor key. The vendor certificate is conveyed in the x5chain header.
+func signCOSE(payload []byte, vendorCert *x509.Certificate, vendorKey crypto.PrivateKey) ([]byte, error) {
+	alg, err := coseAlgorithm(vendorKey)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := cbor.Marshal(map[int]int{coseHeaderAlg: alg})
+	if err != nil {
+		return nil, err
+	}
+	x5chain, err := cbor.Marshal(vendorCert.Raw)
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	hash, hashed, err := digest(alg, toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	switch k := vendorKey.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPSS(rand.Reader, k, hash, hashed, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		if err != nil {
+			return nil, err
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hashed)
+		if err != nil {
+			return nil, err
+		}
+		// COSE encodes ECDSA signatures as the fixed size concatenation of r and s.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	}
+	return cbor.Marshal(cbor.Tag{
+		Number: coseSign1Tag,
+		Content: &coseSign1{
+			Protected:   protected,
+			Unprotected: coseHeader{X5Chain: x5chain},
+			Payload:     payload,
+			Signature:   sig,
+		},
+	})
+}
+
+// parseCOSE decodes a COSE_Sign1 structure, tagged or untagged, and the certificates in its x5chain header.
+func parseCOSE(in []byte) (*coseSignedVoucher, error) {
+	var raw cbor.RawTag
+	content := in
+	if err := cbor.Unmarshal(in, &raw); err == nil {
+		if raw.Number != coseSign1Tag {
+			return nil, fmt.Errorf("unexpected CBOR tag %d, want %d", raw.Number, coseSign1Tag)
+		}
+		content = raw.Content
+	}
+	msg := &coseSign1{}
+	if err := cbor.Unmarshal(content, msg); err != nil {
+		return nil, fmt.Errorf("unable to parse COSE_Sign1: %v", err)
+	}
+	protected := coseHeader{}
+	if len(msg.Protected) > 0 {
+		if err := cbor.Unmarshal(msg.Protected, &protected); err != nil {
+			return nil, fmt.Errorf("unable to parse COSE protected header: %v", err)
+		}
+	}
+	// The algorithm must be integrity protected.
+	if protected.Alg == 0 {
+		return nil, fmt.Errorf("COSE protected header does not contain an algorithm")
+	}
+	x5chain := protected.X5Chain
+	if len(x5chain) == 0 {
+		x5chain = msg.Unprotected.X5Chain
+	}
+	if len(x5chain) == 0 {
+		return nil, fmt.Errorf("COSE header does not contain an x5chain")
+	}
+	// x5chain is a single bstr for one certificate and an array of bstr otherwise.
+	var ders [][]byte
+	var der []byte
+	if err := cbor.Unmarshal(x5chain, &der); err == nil {
+		ders = [][]byte{der}
+	} else if err := cbor.Unmarshal(x5chain, &ders); err != nil {
+		return nil, fmt.Errorf("unable to parse x5chain: %v", err)
+	}
+	var certs []*x509.Certificate
+	for _, d := range ders {
+		cert, err := x509.ParseCertificate(d)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse x5chain certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return &coseSignedVoucher{msg: msg, alg: protected.Alg, certChain: certs}, nil
+}
+
+// verify checks that the signing certificate chains to the pool at the given time, and that the signature is valid.
+func (c *coseSignedVoucher) verify(pool *x509.CertPool, now time.Time) error {
+	leaf := c.certChain[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range c.certChain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("failed to verify certificate chain: %v", err)
+	}
+	toBeSigned, err := sigStructure(c.msg.Protected, c.msg.Payload)
+	if err != nil {
+		return err
+	}
+	hash, hashed, err := digest(c.alg, toBeSigned)
+	if err != nil {
+		return err
+	}
+	switch pub := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		switch c.alg {
+		case coseAlgPS256:
+			err = rsa.VerifyPSS(pub, hash, hashed, c.msg.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		case coseAlgRS256:
+			err = rsa.VerifyPKCS1v15(pub, hash, hashed, c.msg.Signature)
+		default:
+			return fmt.Errorf("COSE algorithm %d does not match RSA key", c.alg)
+		}
+		if err != nil {
+			return fmt.Errorf("signature not verified: %v", err)
+		}
+	case *ecdsa.PublicKey:
+		if c.alg != coseAlgES256 && c.alg != coseAlgES384 {
+			return fmt.Errorf("COSE algorithm %d does not match ECDSA key", c.alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(c.msg.Signature) != 2*size {
+			return fmt.Errorf("invalid ECDSA signature length %d", len(c.msg.Signature))
+		}
+		r := new(big.Int).SetBytes(c.msg.Signature[:size])
+		s := new(big.Int).SetBytes(c.msg.Signature[size:])
+		if !ecdsa.Verify(pub, hashed, r, s) {
+			return fmt.Errorf("signature not verified")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type: %T", pub)
+	}
+	return nil
+}
@@ -18,9 +18,12 @@ package ownershipvoucher
 import (
 	"crypto"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mozilla.org/pkcs7"
@@ -42,38 +45,256 @@ type OVInner struct {
 	DomainCertRevocationChecks bool     `json:"domain-cert-revocation-checks" xml:"domain-cert-revocation-checks"`
 }
 
-// Unmarshal unmarshals the contents of an ownership voucher. If a certPool is provided, it is used to verify the contents.
-func Unmarshal(in []byte, certPool *x509.CertPool) (*OwnershipVoucher, error) {
+// xmlOVInner is the XML representation of OVInner. RFC 8366 encodes binary leaves such as the pinned domain cert in base64.
+type xmlOVInner struct {
+	XMLName                    xml.Name `xml:"voucher"`
+	CreatedOn                  string   `xml:"created-on"`
+	ExpiresOn                  string   `xml:"expires-on"`
+	Assertion                  string   `xml:"assertion"`
+	SerialNumber               string   `xml:"serial-number"`
+	PinnedDomainCert           string   `xml:"pinned-domain-cert"`
+	DomainCertRevocationChecks bool     `xml:"domain-cert-revocation-checks"`
+}
+
+// MarshalXML implements xml.Marshaler.
+func (o OVInner) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(xmlOVInner{
+		CreatedOn:                  o.CreatedOn,
+		ExpiresOn:                  o.ExpiresOn,
+		Assertion:                  o.Assertion,
+		SerialNumber:               o.SerialNumber,
+		PinnedDomainCert:           base64.StdEncoding.EncodeToString(o.PinnedDomainCert),
+		DomainCertRevocationChecks: o.DomainCertRevocationChecks,
+	})
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (o *OVInner) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	x := &xmlOVInner{}
+	if err := d.DecodeElement(x, &start); err != nil {
+		return err
+	}
+	pdc, err := base64.StdEncoding.DecodeString(strings.TrimSpace(x.PinnedDomainCert))
+	if err != nil {
+		return fmt.Errorf("unable to base64 decode pinned-domain-cert: %v", err)
+	}
+	*o = OVInner{
+		XMLName:                    x.XMLName,
+		CreatedOn:                  x.CreatedOn,
+		ExpiresOn:                  x.ExpiresOn,
+		Assertion:                  x.Assertion,
+		SerialNumber:               x.SerialNumber,
+		PinnedDomainCert:           pdc,
+		DomainCertRevocationChecks: x.DomainCertRevocationChecks,
+	}
+	return nil
+}
+
+// Assertion values defined in RFC 8366 section 5.3.
+const (
+	AssertionVerified  = "verified"
+	AssertionLogged    = "logged"
+	AssertionProximity = "proximity"
+)
+
+// Errors wrapped by ValidationError to indicate which check an ownership voucher failed.
+var (
+	ErrMalformed         = errors.New("malformed ownership voucher")
+	ErrSignature         = errors.New("ownership voucher signature verification failed")
+	ErrNotYetValid       = errors.New("ownership voucher is not yet valid")
+	ErrExpired           = errors.New("ownership voucher has expired")
+	ErrInvalidAssertion  = errors.New("invalid ownership voucher assertion")
+	ErrSerialMismatch    = errors.New("ownership voucher serial number mismatch")
+	ErrInvalidPDC        = errors.New("invalid pinned domain cert")
+	ErrRevocationChecks  = errors.New("pinned domain cert revocation checks failed")
+	ErrMissingVendorPool = errors.New("no vendor CA pool provided")
+)
+
+// ValidationError is returned by Validate. Use errors.Is with the Err* values above to determine which check failed.
+type ValidationError struct {
+	// Err is one of the Err* values above.
+	Err error
+	// Detail describes the failure.
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Detail)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func validationErrorf(err error, format string, a ...any) *ValidationError {
+	return &ValidationError{Err: err, Detail: fmt.Sprintf(format, a...)}
+}
+
+// ValidateOpts holds the parameters used by Validate.
+type ValidateOpts struct {
+	// VendorCAPool is the pool of vendor CA certificates that the voucher's CMS signer must chain to.
+	VendorCAPool *x509.CertPool
+	// SerialNumber is the expected device serial number. It is not checked if empty.
+	SerialNumber string
+	// Now returns the current time used for the validity window checks. Defaults to time.Now.
+	Now func() time.Time
+	// CheckRevocation is called with the pinned domain cert when the voucher sets domain-cert-revocation-checks.
+	// If the voucher requires revocation checks and this is nil, validation fails.
+	CheckRevocation func(pdc *x509.Certificate) error
+}
+
+// signedVoucher is a parsed ownership voucher together with the envelope carrying its signature.
+// Exactly one of p7 and cose is set.
+type signedVoucher struct {
+	ov   *OwnershipVoucher
+	p7   *pkcs7.PKCS7
+	cose *coseSignedVoucher
+}
+
+// verifyAt verifies the signature of the voucher and that the signer chains to the pool at the given time.
+func (s *signedVoucher) verifyAt(certPool *x509.CertPool, now time.Time) error {
+	if s.cose != nil {
+		return s.cose.verify(certPool, now)
+	}
+	return s.p7.VerifyWithChainAtTime(certPool, now)
+}
+
+// parse decodes the signed voucher. CMS signed vouchers carry the inner content in JSON or XML format,
+// and COSE signed constrained vouchers carry it in CBOR format.
+func parse(in []byte) (*signedVoucher, error) {
 	if len(in) == 0 {
 		return nil, fmt.Errorf("ownership voucher is empty")
 	}
-	p7, err := pkcs7.Parse(in)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse into pkcs7 format: %v", err)
+	p7, p7Err := pkcs7.Parse(in)
+	if p7Err != nil {
+		cose, coseErr := parseCOSE(in)
+		if coseErr != nil {
+			return nil, fmt.Errorf("unable to parse into pkcs7 or COSE format: pkcs7 err: %v, COSE err: %v", p7Err, coseErr)
+		}
+		ov, err := unmarshalCBOR(cose.msg.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed unmarshalling ownership voucher in cbor format: %v", err)
+		}
+		return &signedVoucher{ov: ov, cose: cose}, nil
 	}
 	ov := &OwnershipVoucher{}
 	jsonErr := json.Unmarshal(p7.Content, ov)
 	if jsonErr != nil {
+		ov = &OwnershipVoucher{}
 		xmlErr := xml.Unmarshal(p7.Content, &ov.OV)
 		if xmlErr != nil {
 			return nil, fmt.Errorf("failed unmarshalling ownership voucher in json or xml format: json err: %v, xml err: %v", jsonErr, xmlErr)
 		}
 	}
+	return &signedVoucher{ov: ov, p7: p7}, nil
+}
+
+// Unmarshal unmarshals the contents of an ownership voucher. If a certPool is provided, it is used to verify the contents.
+// Unmarshal does not check the voucher fields; use Validate for that.
+func Unmarshal(in []byte, certPool *x509.CertPool) (*OwnershipVoucher, error) {
+	sv, err := parse(in)
+	if err != nil {
+		return nil, err
+	}
 	if certPool != nil {
-		if err = p7.VerifyWithChain(certPool); err != nil {
+		if sv.p7 != nil {
+			err = sv.p7.VerifyWithChain(certPool)
+		} else {
+			err = sv.verifyAt(certPool, time.Now())
+		}
+		if err != nil {
 			return nil, fmt.Errorf("failed to verify ownership voucher: %v", err)
 		}
 	}
+	return sv.ov, nil
+}
+
+// Validate unmarshals an ownership voucher and validates it according to RFC 8366.
+// It verifies the CMS signature chains to opts.VendorCAPool, that the current time is within the created-on and expires-on window,
+// that the assertion is a known value, that the pinned domain cert is a valid certificate and, if requested, that the serial number matches.
+// All validation failures are returned as a *ValidationError.
+func Validate(in []byte, opts *ValidateOpts) (*OwnershipVoucher, error) {
+	if opts == nil || opts.VendorCAPool == nil {
+		return nil, &ValidationError{Err: ErrMissingVendorPool}
+	}
+	now := time.Now()
+	if opts.Now != nil {
+		now = opts.Now()
+	}
+	sv, err := parse(in)
+	if err != nil {
+		return nil, &ValidationError{Err: ErrMalformed, Detail: err.Error()}
+	}
+	if err := sv.verifyAt(opts.VendorCAPool, now); err != nil {
+		return nil, &ValidationError{Err: ErrSignature, Detail: err.Error()}
+	}
+	ov := sv.ov
+	inner := ov.OV
+
+	if inner.CreatedOn == "" {
+		return nil, validationErrorf(ErrMalformed, "created-on is required")
+	}
+	createdOn, err := time.Parse(time.RFC3339, inner.CreatedOn)
+	if err != nil {
+		return nil, validationErrorf(ErrMalformed, "unable to parse created-on %q: %v", inner.CreatedOn, err)
+	}
+	if now.Before(createdOn) {
+		return nil, validationErrorf(ErrNotYetValid, "created-on %v is after current time %v", createdOn, now)
+	}
+	// expires-on is optional. A voucher without it never expires.
+	if inner.ExpiresOn != "" {
+		expiresOn, err := time.Parse(time.RFC3339, inner.ExpiresOn)
+		if err != nil {
+			return nil, validationErrorf(ErrMalformed, "unable to parse expires-on %q: %v", inner.ExpiresOn, err)
+		}
+		if expiresOn.Before(createdOn) {
+			return nil, validationErrorf(ErrMalformed, "expires-on %v is before created-on %v", expiresOn, createdOn)
+		}
+		if !now.Before(expiresOn) {
+			return nil, validationErrorf(ErrExpired, "expired on %v, current time %v", expiresOn, now)
+		}
+	}
+
+	switch inner.Assertion {
+	case AssertionVerified, AssertionLogged, AssertionProximity:
+	default:
+		return nil, validationErrorf(ErrInvalidAssertion, "got %q, want one of %q, %q or %q", inner.Assertion, AssertionVerified, AssertionLogged, AssertionProximity)
+	}
+
+	if inner.SerialNumber == "" {
+		return nil, validationErrorf(ErrMalformed, "serial-number is required")
+	}
+	if opts.SerialNumber != "" && !strings.EqualFold(inner.SerialNumber, opts.SerialNumber) {
+		return nil, validationErrorf(ErrSerialMismatch, "got %q, want %q", inner.SerialNumber, opts.SerialNumber)
+	}
+
+	pdc, err := x509.ParseCertificate(inner.PinnedDomainCert)
+	if err != nil {
+		return nil, validationErrorf(ErrInvalidPDC, "%v", err)
+	}
+	if inner.DomainCertRevocationChecks {
+		if opts.CheckRevocation == nil {
+			return nil, validationErrorf(ErrRevocationChecks, "voucher requires revocation checks but no revocation checker is configured")
+		}
+		if err := opts.CheckRevocation(pdc); err != nil {
+			return nil, validationErrorf(ErrRevocationChecks, "%v", err)
+		}
+	}
 	return ov, nil
 }
 
 // NewOwnershipVoucher generates an ownership voucher signed by the vendor certificate.
+// The "json" and "xml" encodings produce a CMS signed voucher as defined in RFC 8366,
+// and the "cbor" encoding produces a COSE signed constrained voucher.
 func NewOwnershipVoucher(encoding string, deviceSerial string, pdc, vendorCert *x509.Certificate, vendorKey crypto.PrivateKey) ([]byte, error) {
 	ov := &OwnershipVoucher{
 		OV: OVInner{
 			CreatedOn:        time.Now().Format(time.RFC3339),
 			ExpiresOn:        time.Now().AddDate(999, 0, 0).Format(time.RFC3339),
-			Assertion:        "verified",
+			Assertion:        AssertionVerified,
 			SerialNumber:     deviceSerial,
 			PinnedDomainCert: pdc.Raw,
 		},
@@ -89,6 +310,11 @@ func NewOwnershipVoucher(encoding string, deviceSerial string, pdc, vendorCert *
 		if ovBytes, err = xml.Marshal(ov.OV); err != nil {
 			return nil, err
 		}
+	case "cbor":
+		if ovBytes, err = marshalCBOR(&ov.OV); err != nil {
+			return nil, err
+		}
+		return signCOSE(ovBytes, vendorCert, vendorKey)
 	default:
 		return nil, fmt.Errorf("unsupported encoding: %v", encoding)
 	}
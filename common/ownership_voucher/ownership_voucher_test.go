@@ -16,10 +16,21 @@ package ownershipvoucher
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
 	"testing"
+	"time"
 
+	"github.com/fxamacker/cbor/v2"
 	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
+	"go.mozilla.org/pkcs7"
 )
 
 var (
@@ -80,9 +91,309 @@ func TestEndToEndXML(t *testing.T) {
 	if err != nil {
 		t.Errorf("VerifyAndUnmarshal err = %v, want nil", err)
 	}
-	// Don't check the PDC here as XML encoding doesn't nicely encode it in base64 as json does.
-	// All we really care about is the serial number anyway.
+	if !bytes.Equal(got.OV.PinnedDomainCert, pdc.Raw) {
+		t.Errorf("got PDC = %v, want %v", got.OV.PinnedDomainCert, pdc.Raw)
+	}
 	if gotSerial := got.OV.SerialNumber; gotSerial != wantSerial {
 		t.Errorf("got serial = %v, want %v", gotSerial, wantSerial)
 	}
 }
+
+// newECDSACA generates a self signed ECDSA CA certificate on the given curve.
+func newECDSACA(t *testing.T, curve elliptic.Curve) (*x509.Certificate, crypto.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate ECDSA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Vendor ECDSA Certificate Authority"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create ECDSA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse ECDSA certificate: %v", err)
+	}
+	return cert, key
+}
+
+func TestEndToEndCBOR(t *testing.T) {
+	pdc, _, err := ownercertificate.NewRSACertificate("Pinned Domain Cert", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate PDC: %v", err)
+	}
+	rsaCA, rsaCAKey, err := ownercertificate.NewRSACertificate("Vendor Certificate Authority", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate Vendor CA: %v", err)
+	}
+	p256CA, p256CAKey := newECDSACA(t, elliptic.P256())
+	p384CA, p384CAKey := newECDSACA(t, elliptic.P384())
+
+	tests := []struct {
+		desc string
+		cert *x509.Certificate
+		key  crypto.PrivateKey
+	}{
+		{desc: "PS256", cert: rsaCA, key: rsaCAKey},
+		{desc: "ES256", cert: p256CA, key: p256CAKey},
+		{desc: "ES384", cert: p384CA, key: p384CAKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ov, err := NewOwnershipVoucher("cbor", wantSerial, pdc, tt.cert, tt.key)
+			if err != nil {
+				t.Fatalf("unable to generate ownership voucher: err = %v, want nil", err)
+			}
+			vendorCAPool := x509.NewCertPool()
+			vendorCAPool.AddCert(tt.cert)
+
+			got, err := Unmarshal(ov, vendorCAPool)
+			if err != nil {
+				t.Fatalf("Unmarshal() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got.OV.PinnedDomainCert, pdc.Raw) {
+				t.Errorf("got PDC = %v, want %v", got.OV.PinnedDomainCert, pdc.Raw)
+			}
+			if gotSerial := got.OV.SerialNumber; gotSerial != wantSerial {
+				t.Errorf("got serial = %v, want %v", gotSerial, wantSerial)
+			}
+			if got.OV.Assertion != AssertionVerified {
+				t.Errorf("got assertion = %v, want %v", got.OV.Assertion, AssertionVerified)
+			}
+			if _, err := Validate(ov, &ValidateOpts{VendorCAPool: vendorCAPool, SerialNumber: wantSerial}); err != nil {
+				t.Errorf("Validate() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCBORTampered(t *testing.T) {
+	pdc, _, err := ownercertificate.NewRSACertificate("Pinned Domain Cert", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate PDC: %v", err)
+	}
+	vendorca, vendorcaKey := newECDSACA(t, elliptic.P256())
+	ov, err := NewOwnershipVoucher("cbor", wantSerial, pdc, vendorca, vendorcaKey)
+	if err != nil {
+		t.Fatalf("unable to generate ownership voucher: err = %v, want nil", err)
+	}
+	// Replace the serial number in the payload without re-signing.
+	tampered := bytes.Replace(ov, []byte(wantSerial), []byte("999Z"), 1)
+	if bytes.Equal(tampered, ov) {
+		t.Fatalf("serial number not found in encoded voucher")
+	}
+	vendorCAPool := x509.NewCertPool()
+	vendorCAPool.AddCert(vendorca)
+	if _, err := Validate(tampered, &ValidateOpts{VendorCAPool: vendorCAPool}); !errors.Is(err, ErrSignature) {
+		t.Errorf("Validate() err = %v, want %v", err, ErrSignature)
+	}
+}
+
+func TestCBORMissingAssertion(t *testing.T) {
+	pdc, _, err := ownercertificate.NewRSACertificate("Pinned Domain Cert", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate PDC: %v", err)
+	}
+	vendorca, vendorcaKey := newECDSACA(t, elliptic.P256())
+	now := time.Now()
+	payload, err := cbor.Marshal(map[int]map[int]any{sidVoucher: {
+		sidDeltaCreatedOn:        now.Add(-time.Hour).Format(time.RFC3339),
+		sidDeltaExpiresOn:        now.Add(time.Hour).Format(time.RFC3339),
+		sidDeltaPinnedDomainCert: pdc.Raw,
+		sidDeltaSerialNumber:     wantSerial,
+	}})
+	if err != nil {
+		t.Fatalf("unable to marshal voucher: %v", err)
+	}
+	ov, err := signCOSE(payload, vendorca, vendorcaKey)
+	if err != nil {
+		t.Fatalf("unable to sign voucher: %v", err)
+	}
+	vendorCAPool := x509.NewCertPool()
+	vendorCAPool.AddCert(vendorca)
+	if _, err := Validate(ov, &ValidateOpts{VendorCAPool: vendorCAPool, SerialNumber: wantSerial}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Validate() err = %v, want %v", err, ErrMalformed)
+	}
+}
+
+// signVoucher wraps the given voucher in a CMS structure signed by the vendor certificate.
+func signVoucher(t *testing.T, inner OVInner, vendorCert *x509.Certificate, vendorKey crypto.PrivateKey) []byte {
+	t.Helper()
+	content, err := json.Marshal(&OwnershipVoucher{OV: inner})
+	if err != nil {
+		t.Fatalf("unable to marshal voucher: %v", err)
+	}
+	signedMessage, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		t.Fatalf("unable to create signed data: %v", err)
+	}
+	signedMessage.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := signedMessage.AddSigner(vendorCert, vendorKey, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatalf("unable to add signer: %v", err)
+	}
+	ov, err := signedMessage.Finish()
+	if err != nil {
+		t.Fatalf("unable to finish signed data: %v", err)
+	}
+	return ov
+}
+
+func TestValidate(t *testing.T) {
+	pdc, _, err := ownercertificate.NewRSACertificate("Pinned Domain Cert", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate PDC: %v", err)
+	}
+	vendorca, vendorcaPrivateKey, err := ownercertificate.NewRSACertificate("Vendor Certificate Authority", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate Vendor CA: %v", err)
+	}
+	otherca, _, err := ownercertificate.NewRSACertificate("Other Certificate Authority", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate other CA: %v", err)
+	}
+	vendorCAPool := x509.NewCertPool()
+	vendorCAPool.AddCert(vendorca)
+	otherCAPool := x509.NewCertPool()
+	otherCAPool.AddCert(otherca)
+
+	now := time.Now().Truncate(time.Second)
+	validInner := OVInner{
+		CreatedOn:        now.Add(-time.Hour).Format(time.RFC3339),
+		ExpiresOn:        now.Add(time.Hour).Format(time.RFC3339),
+		Assertion:        AssertionVerified,
+		SerialNumber:     wantSerial,
+		PinnedDomainCert: pdc.Raw,
+	}
+	withInner := func(f func(*OVInner)) OVInner {
+		inner := validInner
+		f(&inner)
+		return inner
+	}
+
+	tests := []struct {
+		desc    string
+		inner   OVInner
+		opts    *ValidateOpts
+		wantErr error
+	}{{
+		desc:  "valid",
+		inner: validInner,
+		opts:  &ValidateOpts{VendorCAPool: vendorCAPool, SerialNumber: wantSerial},
+	}, {
+		desc:  "valid without expiry",
+		inner: withInner(func(o *OVInner) { o.ExpiresOn = "" }),
+		opts:  &ValidateOpts{VendorCAPool: vendorCAPool},
+	}, {
+		desc:  "valid with case insensitive serial",
+		inner: validInner,
+		opts:  &ValidateOpts{VendorCAPool: vendorCAPool, SerialNumber: "123a"},
+	}, {
+		desc:    "no vendor pool",
+		inner:   validInner,
+		opts:    &ValidateOpts{},
+		wantErr: ErrMissingVendorPool,
+	}, {
+		desc:    "signed by untrusted vendor",
+		inner:   validInner,
+		opts:    &ValidateOpts{VendorCAPool: otherCAPool},
+		wantErr: ErrSignature,
+	}, {
+		desc:    "expired",
+		inner:   validInner,
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool, Now: func() time.Time { return now.Add(2 * time.Hour) }},
+		wantErr: ErrExpired,
+	}, {
+		desc: "not yet valid",
+		inner: withInner(func(o *OVInner) {
+			o.CreatedOn = now.Add(time.Hour).Format(time.RFC3339)
+			o.ExpiresOn = now.Add(2 * time.Hour).Format(time.RFC3339)
+		}),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool, Now: func() time.Time { return now }},
+		wantErr: ErrNotYetValid,
+	}, {
+		desc:    "missing created-on",
+		inner:   withInner(func(o *OVInner) { o.CreatedOn = "" }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool},
+		wantErr: ErrMalformed,
+	}, {
+		desc:    "unparsable expires-on",
+		inner:   withInner(func(o *OVInner) { o.ExpiresOn = "tomorrow" }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool},
+		wantErr: ErrMalformed,
+	}, {
+		desc:    "expires-on before created-on",
+		inner:   withInner(func(o *OVInner) { o.CreatedOn, o.ExpiresOn = o.ExpiresOn, o.CreatedOn }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool, Now: func() time.Time { return now.Add(2 * time.Hour) }},
+		wantErr: ErrMalformed,
+	}, {
+		desc:    "unknown assertion",
+		inner:   withInner(func(o *OVInner) { o.Assertion = "trusted" }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool},
+		wantErr: ErrInvalidAssertion,
+	}, {
+		desc:    "serial mismatch",
+		inner:   validInner,
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool, SerialNumber: "456B"},
+		wantErr: ErrSerialMismatch,
+	}, {
+		desc:    "invalid pinned domain cert",
+		inner:   withInner(func(o *OVInner) { o.PinnedDomainCert = []byte("not a cert") }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool},
+		wantErr: ErrInvalidPDC,
+	}, {
+		desc:    "revocation checks without checker",
+		inner:   withInner(func(o *OVInner) { o.DomainCertRevocationChecks = true }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool},
+		wantErr: ErrRevocationChecks,
+	}, {
+		desc:  "revocation checks pass",
+		inner: withInner(func(o *OVInner) { o.DomainCertRevocationChecks = true }),
+		opts: &ValidateOpts{VendorCAPool: vendorCAPool, CheckRevocation: func(got *x509.Certificate) error {
+			if !got.Equal(pdc) {
+				return errors.New("unexpected certificate")
+			}
+			return nil
+		}},
+	}, {
+		desc:    "revocation checks fail",
+		inner:   withInner(func(o *OVInner) { o.DomainCertRevocationChecks = true }),
+		opts:    &ValidateOpts{VendorCAPool: vendorCAPool, CheckRevocation: func(*x509.Certificate) error { return errors.New("revoked") }},
+		wantErr: ErrRevocationChecks,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ov := signVoucher(t, tt.inner, vendorca, vendorcaPrivateKey)
+			got, err := Validate(ov, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var vErr *ValidationError
+				if !errors.As(err, &vErr) {
+					t.Errorf("Validate() err = %T, want *ValidationError", err)
+				}
+				return
+			}
+			if got.OV.SerialNumber != wantSerial {
+				t.Errorf("Validate() got serial = %v, want %v", got.OV.SerialNumber, wantSerial)
+			}
+		})
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	vendorCAPool := x509.NewCertPool()
+	if _, err := Validate([]byte("not a voucher"), &ValidateOpts{VendorCAPool: vendorCAPool}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Validate() err = %v, want %v", err, ErrMalformed)
+	}
+}
@@ -58,8 +58,15 @@ type Chassis struct {
 	ActiveSerial string
 	// The reported IP address of the management interface that sent the bootstrap request.
 	IPAddress string
+	// The DHCP lease of IPAddress, if it was leased by the Bootz DHCP server.
+	Lease *DHCPLease
+	// Whether IPAddress differs from the address the DHCP server leased to the active control card.
+	LeaseMismatch bool
 	// The identity presented by this chassis.
 	Identity *bpb.Identity
+	// The part numbers reported in the chassis descriptor, keyed by control card serial number. For fixed form factor
+	// devices, it contains the part number of this chassis itself.
+	PartNumbers map[string]string
 
 	// ================================================================================
 	// All the fields above are initialized from device bootstrap request.
@@ -81,3 +88,13 @@ type Chassis struct {
 	// The part number of this chassis.
 	PartNumber string
 }
+
+// DHCPLease describes the DHCP client an IP address was leased to.
+type DHCPLease struct {
+	// The leased IP address.
+	IP string
+	// The MAC address of the client.
+	MAC string
+	// The client identifier sent by the client, e.g. its serial number.
+	ClientID string
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package urlsig signs download URLs for a device serial number with an expiry, and verifies them.
+// A signed URL carries the serial number, the expiry, the ID of the signing key and an HMAC-SHA256 of the URL path,
+// the serial number and the expiry in its query. Keys are rotated by signing with a new key while the previous ones
+// still verify the URLs they signed.
+package urlsig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Query parameters of a signed URL.
+const (
+	SerialParam  = "serial"
+	ExpiresParam = "expires"
+	KeyIDParam   = "kid"
+	TokenParam   = "token"
+)
+
+// minSecretLen is the minimum length of a key secret, the output length of the HMAC hash.
+const minSecretLen = sha256.Size
+
+var (
+	// ErrUnsigned is returned when a URL is not signed.
+	ErrUnsigned = errors.New("url is not signed")
+	// ErrExpired is returned when a signed URL has expired.
+	ErrExpired = errors.New("signed url has expired")
+	// ErrInvalid is returned when a URL signature is invalid.
+	ErrInvalid = errors.New("invalid url signature")
+)
+
+// Key is a URL signing key.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Signer signs and verifies URLs.
+type Signer struct {
+	keys     []Key
+	lifetime time.Duration
+	// now returns the current time. It is replaced by tests.
+	now func() time.Time
+}
+
+// New returns a signer which signs URLs valid for the lifetime with the first key, and verifies them with any key.
+func New(keys []Key, lifetime time.Duration) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no url signing keys")
+	}
+	if lifetime <= 0 {
+		return nil, fmt.Errorf("invalid signed url lifetime %v", lifetime)
+	}
+	ids := map[string]bool{}
+	for _, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("url signing key id must be set")
+		}
+		if ids[k.ID] {
+			return nil, fmt.Errorf("duplicate url signing key id %q", k.ID)
+		}
+		ids[k.ID] = true
+		if len(k.Secret) < minSecretLen {
+			return nil, fmt.Errorf("url signing key %q must be at least %d bytes long", k.ID, minSecretLen)
+		}
+	}
+	return &Signer{keys: keys, lifetime: lifetime, now: time.Now}, nil
+}
+
+// Sign returns the URL signed for the serial number.
+func (s *Signer) Sign(rawURL, serial string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %v", rawURL, err)
+	}
+	expires := strconv.FormatInt(s.now().Add(s.lifetime).Unix(), 10)
+	k := s.keys[0]
+	q := u.Query()
+	q.Set(SerialParam, serial)
+	q.Set(ExpiresParam, expires)
+	q.Set(KeyIDParam, k.ID)
+	q.Set(TokenParam, token(k, u.EscapedPath(), serial, expires))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Verify verifies the signature of the URL, given by its escaped path and its query, and returns the serial number
+// it is signed for.
+func (s *Signer) Verify(path string, query url.Values) (string, error) {
+	serial, expires, id, tok := query.Get(SerialParam), query.Get(ExpiresParam), query.Get(KeyIDParam), query.Get(TokenParam)
+	if tok == "" {
+		return "", ErrUnsigned
+	}
+	var key *Key
+	for i := range s.keys {
+		if s.keys[i].ID == id {
+			key = &s.keys[i]
+		}
+	}
+	if key == nil {
+		return "", fmt.Errorf("%w: unknown key %q", ErrInvalid, id)
+	}
+	if !hmac.Equal([]byte(tok), []byte(token(*key, path, serial, expires))) {
+		return "", ErrInvalid
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid expiry %q", ErrInvalid, expires)
+	}
+	if s.now().After(time.Unix(exp, 0)) {
+		return "", ErrExpired
+	}
+	return serial, nil
+}
+
+// token returns the URL-safe HMAC of the path, serial number and expiry.
+func token(k Key, path, serial, expires string) string {
+	mac := hmac.New(sha256.New, k.Secret)
+	// The fields are length-prefixed so that they cannot be shifted into each other.
+	for _, f := range []string{path, serial, expires} {
+		fmt.Fprintf(mac, "%d:%s", len(f), f)
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package urlsig
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	oldKey = Key{ID: "old", Secret: bytes.Repeat([]byte{1}, 32)}
+	newKey = Key{ID: "new", Secret: bytes.Repeat([]byte{2}, 32)}
+)
+
+func newSigner(t *testing.T, now time.Time, keys ...Key) *Signer {
+	t.Helper()
+	s, err := New(keys, time.Hour)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		desc     string
+		keys     []Key
+		lifetime time.Duration
+		wantErr  bool
+	}{{
+		desc:     "Valid",
+		keys:     []Key{newKey, oldKey},
+		lifetime: time.Hour,
+	}, {
+		desc:     "No keys",
+		lifetime: time.Hour,
+		wantErr:  true,
+	}, {
+		desc:    "No lifetime",
+		keys:    []Key{newKey},
+		wantErr: true,
+	}, {
+		desc:     "Short secret",
+		keys:     []Key{{ID: "short", Secret: []byte("secret")}},
+		lifetime: time.Hour,
+		wantErr:  true,
+	}, {
+		desc:     "Missing key id",
+		keys:     []Key{{Secret: newKey.Secret}},
+		lifetime: time.Hour,
+		wantErr:  true,
+	}, {
+		desc:     "Duplicate key id",
+		keys:     []Key{newKey, {ID: "new", Secret: oldKey.Secret}},
+		lifetime: time.Hour,
+		wantErr:  true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := New(test.keys, test.lifetime); (err != nil) != test.wantErr {
+				t.Errorf("New() err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signed, err := newSigner(t, now, oldKey).Sign("https://10.0.0.1:8080/sha256/abcd?x=1", "SN123")
+	if err != nil {
+		t.Fatalf("Sign() err = %v, want nil", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := func(k, v string) url.Values {
+		q := u.Query()
+		q.Set(k, v)
+		return q
+	}
+
+	tests := []struct {
+		desc       string
+		signer     *Signer
+		path       string
+		query      url.Values
+		wantSerial string
+		wantErr    error
+	}{{
+		desc:       "Valid",
+		signer:     newSigner(t, now.Add(time.Minute), oldKey),
+		path:       "/sha256/abcd",
+		query:      u.Query(),
+		wantSerial: "SN123",
+	}, {
+		desc:       "Rotated key",
+		signer:     newSigner(t, now, newKey, oldKey),
+		path:       "/sha256/abcd",
+		query:      u.Query(),
+		wantSerial: "SN123",
+	}, {
+		desc:    "Retired key",
+		signer:  newSigner(t, now, newKey),
+		path:    "/sha256/abcd",
+		query:   u.Query(),
+		wantErr: ErrInvalid,
+	}, {
+		desc:    "Expired",
+		signer:  newSigner(t, now.Add(2*time.Hour), oldKey),
+		path:    "/sha256/abcd",
+		query:   u.Query(),
+		wantErr: ErrExpired,
+	}, {
+		desc:    "Other path",
+		signer:  newSigner(t, now, oldKey),
+		path:    "/sha256/ef01",
+		query:   u.Query(),
+		wantErr: ErrInvalid,
+	}, {
+		desc:    "Other serial",
+		signer:  newSigner(t, now, oldKey),
+		path:    "/sha256/abcd",
+		query:   tampered(SerialParam, "SN456"),
+		wantErr: ErrInvalid,
+	}, {
+		desc:    "Extended expiry",
+		signer:  newSigner(t, now, oldKey),
+		path:    "/sha256/abcd",
+		query:   tampered(ExpiresParam, "1900000000"),
+		wantErr: ErrInvalid,
+	}, {
+		desc:    "Unsigned",
+		signer:  newSigner(t, now, oldKey),
+		path:    "/sha256/abcd",
+		query:   url.Values{},
+		wantErr: ErrUnsigned,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := test.signer.Verify(test.path, test.query)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify() err = %v, want %v", err, test.wantErr)
+			}
+			if got != test.wantSerial {
+				t.Errorf("Verify() = %q, want %q", got, test.wantSerial)
+			}
+		})
+	}
+	if got := u.Query().Get("x"); got != "1" {
+		t.Errorf("Sign() dropped the query of the URL, x = %q", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	plslease "github.com/openconfig/bootz/dhcp/plugins/slease"
+
+	apb "github.com/openconfig/bootz/dhcp/proto/dhcpadmin"
+	cpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
+)
+
+// adminService implements the DHCPAdmin service on top of the leases of the slease plugin.
+type adminService struct{}
+
+// ListLeases returns the current leases, optionally filtered by client.
+func (*adminService) ListLeases(_ context.Context, req *apb.ListLeasesRequest) (*apb.ListLeasesResponse, error) {
+	resp := &apb.ListLeasesResponse{}
+	for _, l := range plslease.Leases() {
+		if f := req.GetFilter(); f != "" && !matchLease(l, f) {
+			continue
+		}
+		resp.Leases = append(resp.Leases, &apb.Lease{
+			Key:            l.Key,
+			ClientId:       l.ClientID,
+			Mac:            l.MAC,
+			Ip:             l.IP,
+			Expiry:         timestamppb.New(l.Expiry),
+			LastSeen:       timestamppb.New(l.LastSeen),
+			BootzRequested: l.BootzRequested,
+		})
+	}
+	return resp, nil
+}
+
+func matchLease(l plslease.Lease, filter string) bool {
+	for _, v := range []string{l.Key, l.ClientID, l.MAC, l.IP} {
+		if v != "" && strings.EqualFold(v, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminCredentials loads the TLS credentials of the DHCPAdmin service, which requires client certificates signed by
+// the client CAs.
+func adminCredentials(conf *cpb.AdminTLS) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(conf.GetCertFile(), conf.GetKeyFile())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load admin certificate: %v", err)
+	}
+	pem, err := os.ReadFile(conf.GetClientCaFile())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read admin client CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in admin client CA %v", conf.GetClientCaFile())
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// startAdmin serves the DHCPAdmin service on the admin address of the configuration, over TLS if configured.
+func startAdmin(conf *cpb.Config) (*grpc.Server, error) {
+	var opts []grpc.ServerOption
+	if conf.GetAdminTls() != nil {
+		creds, err := adminCredentials(conf.GetAdminTls())
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	lis, err := net.Listen("tcp", conf.GetAdminAddress())
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on admin address %v: %v", conf.GetAdminAddress(), err)
+	}
+	s := grpc.NewServer(opts...)
+	apb.RegisterDHCPAdminServer(s, &adminService{})
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Errorf("DHCP admin server stopped: %v", err)
+		}
+	}()
+	log.Infof("DHCP admin service listening on %v", lis.Addr())
+	return s, nil
+}
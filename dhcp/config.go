@@ -0,0 +1,501 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	cdconfig "github.com/coredhcp/coredhcp/config"
+	plbootz "github.com/openconfig/bootz/dhcp/plugins/bootz"
+	plscope "github.com/openconfig/bootz/dhcp/plugins/scope"
+	plslease "github.com/openconfig/bootz/dhcp/plugins/slease"
+
+	cpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
+)
+
+// defaultLeaseTime is the lease time of the clients which get it neither from a pool nor from a scope.
+const defaultLeaseTime = "3600s"
+
+// serverArgs accumulates the plugin arguments of the DHCPv4 or DHCPv6 server.
+type serverArgs struct {
+	dns    []string
+	scopes []string
+	leases []string
+}
+
+// buildConfig validates the configuration and converts it to the coredhcp configuration. Errors name the offending
+// field of the configuration.
+func buildConfig(conf *cpb.Config) (*cdconfig.Config, error) {
+	if conf.GetInterface() == "" {
+		return nil, fmt.Errorf("interface must be set")
+	}
+	intf, err := lookupInterface(conf.GetInterface())
+	if err != nil {
+		return nil, fmt.Errorf("unknown interface %v: %v", conf.GetInterface(), err)
+	}
+
+	enableV4 := conf.GetMode() != cpb.Config_MODE_IPV6_ONLY
+	enableV6 := conf.GetMode() != cpb.Config_MODE_IPV4_ONLY
+	v4, v6 := &serverArgs{}, &serverArgs{}
+	// family returns the arguments of the server of the address family, or an error if the family is disabled.
+	family := func(field string, ipv6 bool) (*serverArgs, error) {
+		switch {
+		case ipv6 && !enableV6:
+			return nil, fmt.Errorf("%v is IPv6 but the DHCP server is IPv4-only", field)
+		case !ipv6 && !enableV4:
+			return nil, fmt.Errorf("%v is IPv4 but the DHCP server is IPv6-only", field)
+		case ipv6:
+			return v6, nil
+		}
+		return v4, nil
+	}
+
+	// The DHCPv4 server identifier is the IPv4 address of the interface, the DHCPv6 one is derived from its MAC address.
+	var ipv4Addr net.IP
+	if enableV4 {
+		if ipv4Addr = getIPv4Address(intf.addrs); ipv4Addr == nil {
+			return nil, fmt.Errorf("unable to find IPv4 address for interface %v", conf.GetInterface())
+		}
+	}
+	if enableV6 && len(intf.mac) == 0 {
+		return nil, fmt.Errorf("unable to derive a DHCPv6 server identifier: interface %v has no MAC address", conf.GetInterface())
+	}
+
+	for i, d := range conf.GetDns() {
+		field := fmt.Sprintf("dns[%d] %q", i, d)
+		ip, err := netip.ParseAddr(d)
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid address", field)
+		}
+		args, err := family(field, ip.Is6())
+		if err != nil {
+			return nil, err
+		}
+		args.dns = append(args.dns, ip.String())
+	}
+
+	bootzArgs := []string{}
+	for i, u := range conf.GetBootzUrls() {
+		if err := validateBootzURL(u); err != nil {
+			return nil, fmt.Errorf("bootz_urls[%d] %q: %v", i, u, err)
+		}
+		bootzArgs = append(bootzArgs, u)
+	}
+
+	var legacyServer *url.URL
+	if ls := conf.GetLegacyBootServer(); ls != "" {
+		u, err := url.Parse(ls)
+		if err != nil || !legacySchemes[u.Scheme] || u.Host == "" {
+			return nil, fmt.Errorf("legacy_boot_server %q: must be an http, https or tftp URL", ls)
+		}
+		legacyServer = u
+	}
+
+	machines := map[string]int{}
+	for i, r := range conf.GetRecords() {
+		key, err := recordKey(r)
+		if err != nil {
+			return nil, fmt.Errorf("records[%d]: %v", i, err)
+		}
+		field := fmt.Sprintf("records[%d] (%v)", i, key)
+		if key == "" {
+			return nil, fmt.Errorf("records[%d]: machine must be set", i)
+		}
+		if len(r.GetAlternateMachines()) > 0 && r.GetMachine() == "" {
+			return nil, fmt.Errorf("%v: alternate_machines require machine", field)
+		}
+		keys := append([]string{key}, r.GetAlternateMachines()...)
+		for _, k := range keys {
+			if k == "" || strings.ContainsAny(k, ",; \t") {
+				return nil, fmt.Errorf("%v: machine %q must not be empty or contain commas, semicolons or spaces", field, k)
+			}
+		}
+		ip, _, err := net.ParseCIDR(r.GetIp())
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid ip %q, which must be in CIDR notation", field, r.GetIp())
+		}
+		ipv6 := ip.To4() == nil
+		args, err := family(field, ipv6)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			k := fmt.Sprintf("%v/%v", strings.ToLower(k), ipv6)
+			if j, ok := machines[k]; ok {
+				return nil, fmt.Errorf("%v: duplicate of records[%d]", field, j)
+			}
+			machines[k] = i
+		}
+		if r.GetBootFile() != "" {
+			bootFile, err := resolveBootFile(r.GetBootFile(), legacyServer)
+			if err != nil {
+				return nil, fmt.Errorf("%v: boot_file %q: %v", field, r.GetBootFile(), err)
+			}
+			for _, criteria := range recordCriteria(r) {
+				// format: legacy:<criteria>,bootfile-url
+				bootzArgs = append(bootzArgs, plbootz.LegacyPrefix+criteria.Encode()+","+bootFile)
+			}
+		}
+		// The machines of a record share its address.
+		key = strings.Join(keys, plslease.KeySeparator)
+		if ipv6 {
+			if r.GetGateway() != "" {
+				return nil, fmt.Errorf("%v: gateway must not be set for an IPv6 address", field)
+			}
+			args.leases = append(args.leases, fmt.Sprintf("%s,%s", key, r.GetIp()))
+			continue
+		}
+		if gw := net.ParseIP(r.GetGateway()); gw == nil || gw.To4() == nil {
+			return nil, fmt.Errorf("%v: invalid gateway %q", field, r.GetGateway())
+		}
+		args.leases = append(args.leases, fmt.Sprintf("%s,%s,%s", key, r.GetIp(), r.GetGateway()))
+	}
+
+	for i, p := range conf.GetPools() {
+		field := fmt.Sprintf("pools[%d] (%v)", i, p.GetSubnet())
+		prefix, err := netip.ParsePrefix(p.GetSubnet())
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid subnet, which must be in CIDR notation", field)
+		}
+		ipv6 := prefix.Addr().Is6()
+		args, err := family(field, ipv6)
+		if err != nil {
+			return nil, err
+		}
+		if gw := p.GetGateway(); gw != "" {
+			if ipv6 {
+				return nil, fmt.Errorf("%v: gateway must not be set for an IPv6 pool", field)
+			}
+			if a, err := netip.ParseAddr(gw); err != nil || !a.Is4() {
+				return nil, fmt.Errorf("%v: invalid gateway %q", field, gw)
+			}
+		}
+		for j, e := range p.GetExclusions() {
+			if err := validateRange(e, ipv6); err != nil {
+				return nil, fmt.Errorf("%v: exclusions[%d] %q: %v", field, j, e, err)
+			}
+		}
+		var leaseTime string
+		if p.GetLeaseTimeSeconds() != 0 {
+			leaseTime = fmt.Sprintf("%ds", p.GetLeaseTimeSeconds())
+		}
+		// format: pool:subnet/len,gateway,leasetime,exclusion;exclusion...
+		args.leases = append(args.leases, fmt.Sprintf("pool:%s,%s,%s,%s", p.GetSubnet(), p.GetGateway(), leaseTime, strings.Join(p.GetExclusions(), ";")))
+	}
+
+	for i, r := range conf.GetBootzRedirects() {
+		field := fmt.Sprintf("bootz_redirects[%d]", i)
+		criteria := url.Values{}
+		for k, v := range map[string]string{
+			plbootz.MatchVendorClass: r.GetVendorClass(),
+			plbootz.MatchClientID:    r.GetClientId(),
+			plbootz.MatchMACPrefix:   r.GetMacPrefix(),
+			plbootz.MatchCircuitID:   r.GetCircuitId(),
+			plbootz.MatchRemoteID:    r.GetRemoteId(),
+		} {
+			if v != "" {
+				criteria.Set(k, v)
+			}
+		}
+		if len(criteria) == 0 {
+			return nil, fmt.Errorf("%v: at least one criterion must be set", field)
+		}
+		if mp := r.GetMacPrefix(); mp != "" {
+			if h := strings.NewReplacer(":", "", "-", "", ".", "").Replace(mp); strings.Trim(strings.ToLower(h), "0123456789abcdef") != "" {
+				return nil, fmt.Errorf("%v: invalid mac_prefix %q", field, mp)
+			}
+		}
+		if r.GetBootFile() != "" {
+			if len(r.GetBootzUrls()) > 0 {
+				return nil, fmt.Errorf("%v: bootz_urls and boot_file are mutually exclusive", field)
+			}
+			bootFile, err := resolveBootFile(r.GetBootFile(), legacyServer)
+			if err != nil {
+				return nil, fmt.Errorf("%v: boot_file %q: %v", field, r.GetBootFile(), err)
+			}
+			// format: legacy:<criteria>,bootfile-url
+			bootzArgs = append(bootzArgs, plbootz.LegacyPrefix+criteria.Encode()+","+bootFile)
+			continue
+		}
+		if len(r.GetBootzUrls()) == 0 {
+			return nil, fmt.Errorf("%v: bootz_urls or boot_file must be set", field)
+		}
+		for j, u := range r.GetBootzUrls() {
+			if err := validateBootzURL(u); err != nil {
+				return nil, fmt.Errorf("%v: bootz_urls[%d] %q: %v", field, j, u, err)
+			}
+		}
+		// format: match:<criteria>,url,url...
+		bootzArgs = append(bootzArgs, plbootz.MatchPrefix+strings.Join(append([]string{criteria.Encode()}, r.GetBootzUrls()...), ","))
+	}
+
+	bootz := len(bootzArgs) > 0
+	for i, sc := range conf.GetScopes() {
+		field := fmt.Sprintf("scopes[%d] (%v)", i, sc.GetSubnet())
+		prefix, err := netip.ParsePrefix(sc.GetSubnet())
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid subnet, which must be in CIDR notation", field)
+		}
+		ipv6 := prefix.Addr().Is6()
+		args, err := family(field, ipv6)
+		if err != nil {
+			return nil, err
+		}
+		v := url.Values{}
+		v.Set(plscope.KeySubnet, prefix.String())
+		if r := sc.GetRouter(); r != "" {
+			if ipv6 {
+				return nil, fmt.Errorf("%v: router must not be set for an IPv6 scope", field)
+			}
+			if a, err := netip.ParseAddr(r); err != nil || !a.Is4() {
+				return nil, fmt.Errorf("%v: invalid router %q", field, r)
+			}
+			v.Set(plscope.KeyRouter, r)
+		}
+		for j, d := range sc.GetDns() {
+			if a, err := netip.ParseAddr(d); err != nil || a.Is6() != ipv6 {
+				return nil, fmt.Errorf("%v: invalid dns[%d] %q", field, j, d)
+			}
+			v.Add(plscope.KeyDNS, d)
+		}
+		if sc.GetLeaseTimeSeconds() != 0 {
+			v.Set(plscope.KeyLeaseTime, fmt.Sprintf("%ds", sc.GetLeaseTimeSeconds()))
+		}
+		for j, u := range sc.GetBootzUrls() {
+			if err := validateBootzURL(u); err != nil {
+				return nil, fmt.Errorf("%v: bootz_urls[%d] %q: %v", field, j, u, err)
+			}
+			v.Add(plscope.KeyBootzURL, u)
+			bootz = true
+		}
+		args.scopes = append(args.scopes, v.Encode())
+	}
+
+	if conf.GetLeaseDb() != "" {
+		// The database is shared by the DHCPv4 and DHCPv6 servers.
+		db := "db:" + conf.GetLeaseDb()
+		v4.leases = append(v4.leases, db)
+		v6.leases = append(v6.leases, db)
+	}
+	if a := conf.GetAdminAddress(); a != "" {
+		host, _, err := net.SplitHostPort(a)
+		if err != nil {
+			return nil, fmt.Errorf("admin_address %q: %v", a, err)
+		}
+		if t := conf.GetAdminTls(); t != nil {
+			if t.GetCertFile() == "" || t.GetKeyFile() == "" || t.GetClientCaFile() == "" {
+				return nil, fmt.Errorf("admin_tls: cert_file, key_file and client_ca_file are required")
+			}
+		} else if !isLoopback(host) {
+			// The service is not authenticated without TLS.
+			return nil, fmt.Errorf("admin_address %q: not a loopback address and admin_tls is not set", a)
+		}
+	}
+
+	c := cdconfig.New()
+	if enableV6 {
+		plugins := []cdconfig.PluginConfig{{Name: "server_id", Args: []string{"LL", intf.mac.String()}}}
+		plugins = appendPlugins(plugins, bootz, bootzArgs, v6)
+		c.Server6 = &cdconfig.ServerConfig{
+			Addresses: []net.UDPAddr{
+				{IP: dhcpv6.AllDHCPRelayAgentsAndServers, Port: dhcpv6.DefaultServerPort, Zone: conf.GetInterface()},
+				{IP: dhcpv6.AllDHCPServers, Port: dhcpv6.DefaultServerPort},
+			},
+			Plugins: plugins,
+		}
+	}
+	if enableV4 {
+		plugins := []cdconfig.PluginConfig{
+			{Name: "lease_time", Args: []string{defaultLeaseTime}},
+			{Name: "server_id", Args: []string{ipv4Addr.String()}},
+		}
+		plugins = appendPlugins(plugins, bootz, bootzArgs, v4)
+		c.Server4 = &cdconfig.ServerConfig{
+			Addresses: []net.UDPAddr{{Port: dhcpv4.ServerPort}},
+			Plugins:   plugins,
+		}
+	}
+	return c, nil
+}
+
+// appendPlugins appends the plugins common to the DHCPv4 and DHCPv6 servers, in the order they handle the requests.
+func appendPlugins(plugins []cdconfig.PluginConfig, bootz bool, bootzArgs []string, args *serverArgs) []cdconfig.PluginConfig {
+	if bootz {
+		plugins = append(plugins, cdconfig.PluginConfig{Name: "bootz", Args: bootzArgs})
+	}
+	if len(args.dns) > 0 {
+		plugins = append(plugins, cdconfig.PluginConfig{Name: "dns", Args: args.dns})
+	}
+	if len(args.scopes) > 0 {
+		plugins = append(plugins, cdconfig.PluginConfig{Name: "scope", Args: args.scopes})
+	}
+	if len(args.leases) > 0 {
+		plugins = append(plugins, cdconfig.PluginConfig{Name: "slease", Args: args.leases})
+	}
+	return plugins
+}
+
+// validateBootzURL checks that the URL is a bootz server URL which can be passed to the bootz plugin.
+func validateBootzURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "bootz" || parsed.Host == "" {
+		return fmt.Errorf("must be of the form bootz://host:port")
+	}
+	if strings.Contains(u, ",") {
+		// Commas separate the URLs of a redirect rule.
+		return fmt.Errorf("must not contain commas")
+	}
+	return nil
+}
+
+// legacySchemes are the URL schemes of the boot files legacy ZTP clients can fetch.
+var legacySchemes = map[string]bool{"http": true, "https": true, "tftp": true}
+
+// resolveBootFile returns the absolute URL of a legacy boot file, resolving a relative path against the legacy boot
+// server.
+func resolveBootFile(f string, server *url.URL) (string, error) {
+	u, err := url.Parse(f)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() {
+		if server == nil {
+			return "", fmt.Errorf("relative path requires legacy_boot_server")
+		}
+		u = server.ResolveReference(u)
+	}
+	if !legacySchemes[u.Scheme] || u.Host == "" {
+		return "", fmt.Errorf("must be an http, https or tftp URL")
+	}
+	return u.String(), nil
+}
+
+// recordCriteria returns the bootz plugin rule criteria matching the machines of the record.
+func recordCriteria(r *cpb.Record) []url.Values {
+	if r.GetCircuitId() != "" || r.GetRemoteId() != "" {
+		criteria := url.Values{}
+		if r.GetCircuitId() != "" {
+			criteria.Set(plbootz.MatchCircuitID, r.GetCircuitId())
+		}
+		if r.GetRemoteId() != "" {
+			criteria.Set(plbootz.MatchRemoteID, r.GetRemoteId())
+		}
+		return []url.Values{criteria}
+	}
+	var all []url.Values
+	for _, m := range append([]string{r.GetMachine()}, r.GetAlternateMachines()...) {
+		criteria := url.Values{}
+		if mac, err := net.ParseMAC(m); err == nil {
+			// A prefix as long as the address matches it exactly.
+			criteria.Set(plbootz.MatchMACPrefix, mac.String())
+		} else {
+			criteria.Set(plbootz.MatchClientID, m)
+		}
+		all = append(all, criteria)
+	}
+	return all
+}
+
+// validateRange checks a pool exclusion: an address or a first-last range of addresses of the pool family.
+func validateRange(s string, ipv6 bool) error {
+	firstStr, lastStr, isRange := strings.Cut(s, "-")
+	first, err := netip.ParseAddr(firstStr)
+	if err != nil || first.Is6() != ipv6 {
+		return fmt.Errorf("invalid address %q", firstStr)
+	}
+	if !isRange {
+		return nil
+	}
+	last, err := netip.ParseAddr(lastStr)
+	if err != nil || last.Is6() != ipv6 {
+		return fmt.Errorf("invalid address %q", lastStr)
+	}
+	if last.Less(first) {
+		return fmt.Errorf("range ends before it starts")
+	}
+	return nil
+}
+
+// recordKey returns the slease key of the record: the machine, or the relay agent IDs of its port.
+func recordKey(r *cpb.Record) (string, error) {
+	if r.GetCircuitId() == "" && r.GetRemoteId() == "" {
+		return r.GetMachine(), nil
+	}
+	if r.GetMachine() != "" {
+		return "", fmt.Errorf("record %v sets both a machine and relay agent ids", r.GetMachine())
+	}
+	ids := url.Values{}
+	if r.GetCircuitId() != "" {
+		ids.Set(plslease.KeyCircuitID, r.GetCircuitId())
+	}
+	if r.GetRemoteId() != "" {
+		ids.Set(plslease.KeyRemoteID, r.GetRemoteId())
+	}
+	return plslease.RelayPrefix + ids.Encode(), nil
+}
+
+// intfInfo is the part of a network interface the configuration is derived from.
+type intfInfo struct {
+	mac   net.HardwareAddr
+	addrs []net.Addr
+}
+
+// lookupInterface returns the network interface with the given name. It is replaced by tests.
+var lookupInterface = func(name string) (*intfInfo, error) {
+	intf, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := intf.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	return &intfInfo{mac: intf.HardwareAddr, addrs: addrs}, nil
+}
+
+func getIPv4Address(addrs []net.Addr) net.IP {
+	for _, a := range addrs {
+		var ip net.IP
+		switch a := a.(type) {
+		case *net.IPNet:
+			ip = a.IP
+		case *net.IPAddr:
+			ip = a.IP
+		}
+		if v4 := ip.To4(); v4 != nil {
+			return v4
+		}
+	}
+	return nil
+}
+
+// isLoopback returns whether the host of an address only listens on the loopback interface.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	a, err := netip.ParseAddr(host)
+	return err == nil && a.IsLoopback()
+}
@@ -0,0 +1,414 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	cdconfig "github.com/coredhcp/coredhcp/config"
+	plslease "github.com/openconfig/bootz/dhcp/plugins/slease"
+	cpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
+)
+
+// fakeInterfaces replaces the network interfaces of the host for the duration of the test.
+func fakeInterfaces(t *testing.T, intfs map[string]*intfInfo) {
+	t.Helper()
+	orig := lookupInterface
+	lookupInterface = func(name string) (*intfInfo, error) {
+		if i, ok := intfs[name]; ok {
+			return i, nil
+		}
+		return nil, fmt.Errorf("no such interface")
+	}
+	t.Cleanup(func() { lookupInterface = orig })
+}
+
+func mustCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	ip, n, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatalf("ParseCIDR(%q) err = %v", s, err)
+	}
+	n.IP = ip
+	return n
+}
+
+// plugins returns the plugins of a server and their arguments, or nil if the server is not configured.
+func plugins(sc *cdconfig.ServerConfig) map[string][]string {
+	if sc == nil {
+		return nil
+	}
+	m := map[string][]string{}
+	for _, p := range sc.Plugins {
+		m[p.Name] = p.Args
+	}
+	return m
+}
+
+func TestBuildConfigModes(t *testing.T) {
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	fakeInterfaces(t, map[string]*intfInfo{
+		"dual": {mac: mac, addrs: []net.Addr{mustCIDR(t, "10.0.0.1/24"), mustCIDR(t, "2001:db8::1/64")}},
+		"v6":   {mac: mac, addrs: []net.Addr{mustCIDR(t, "2001:db8::1/64"), mustCIDR(t, "fe80::1/64")}},
+		"v4":   {addrs: []net.Addr{&net.IPAddr{IP: net.ParseIP("10.0.0.1")}}},
+	})
+	tests := []struct {
+		desc    string
+		conf    *cpb.Config
+		want4   map[string][]string
+		want6   map[string][]string
+		wantErr bool
+	}{{
+		desc: "dual stack",
+		conf: &cpb.Config{
+			Interface: "dual",
+			Dns:       []string{"10.0.0.53", "2001:db8::53"},
+			BootzUrls: []string{"bootz://10.0.0.1:8008"},
+		},
+		want4: map[string][]string{
+			"lease_time": {"3600s"},
+			"server_id":  {"10.0.0.1"},
+			"bootz":      {"bootz://10.0.0.1:8008"},
+			"dns":        {"10.0.0.53"},
+		},
+		want6: map[string][]string{
+			"server_id": {"LL", "02:00:00:00:00:01"},
+			"bootz":     {"bootz://10.0.0.1:8008"},
+			"dns":       {"2001:db8::53"},
+		},
+	}, {
+		desc:    "dual stack without ipv4 address",
+		conf:    &cpb.Config{Interface: "v6"},
+		wantErr: true,
+	}, {
+		desc: "ipv6 only",
+		conf: &cpb.Config{
+			Interface: "v6",
+			Mode:      cpb.Config_MODE_IPV6_ONLY,
+			Dns:       []string{"2001:db8::53"},
+			BootzUrls: []string{"bootz://[2001:db8::1]:8008"},
+			Records:   []*cpb.Record{{Machine: "02:00:00:00:00:02", Ip: "2001:db8::2/64"}},
+		},
+		want6: map[string][]string{
+			"server_id": {"LL", "02:00:00:00:00:01"},
+			"bootz":     {"bootz://[2001:db8::1]:8008"},
+			"dns":       {"2001:db8::53"},
+			"slease":    {"02:00:00:00:00:02,2001:db8::2/64"},
+		},
+	}, {
+		desc: "ipv6 only with ipv4 record",
+		conf: &cpb.Config{
+			Interface: "v6",
+			Mode:      cpb.Config_MODE_IPV6_ONLY,
+			Records:   []*cpb.Record{{Machine: "02:00:00:00:00:02", Ip: "10.0.0.2/24", Gateway: "10.0.0.1"}},
+		},
+		wantErr: true,
+	}, {
+		desc:    "ipv6 only with ipv4 dns",
+		conf:    &cpb.Config{Interface: "v6", Mode: cpb.Config_MODE_IPV6_ONLY, Dns: []string{"10.0.0.53"}},
+		wantErr: true,
+	}, {
+		desc:    "ipv6 only without mac address",
+		conf:    &cpb.Config{Interface: "v4", Mode: cpb.Config_MODE_IPV6_ONLY},
+		wantErr: true,
+	}, {
+		desc: "ipv4 only",
+		conf: &cpb.Config{
+			Interface: "v4",
+			Mode:      cpb.Config_MODE_IPV4_ONLY,
+			Pools:     []*cpb.Pool{{Subnet: "10.0.0.0/24", Gateway: "10.0.0.1"}},
+		},
+		want4: map[string][]string{
+			"lease_time": {"3600s"},
+			"server_id":  {"10.0.0.1"},
+			"slease":     {"pool:10.0.0.0/24,10.0.0.1,,"},
+		},
+	}, {
+		desc:    "ipv4 only with ipv6 pool",
+		conf:    &cpb.Config{Interface: "v4", Mode: cpb.Config_MODE_IPV4_ONLY, Pools: []*cpb.Pool{{Subnet: "2001:db8::/64"}}},
+		wantErr: true,
+	}, {
+		desc:    "unknown interface",
+		conf:    &cpb.Config{Interface: "eth0"},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c, err := buildConfig(test.conf)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("buildConfig() err = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(test.want4, plugins(c.Server4)); diff != "" {
+				t.Errorf("DHCPv4 plugins diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.want6, plugins(c.Server6)); diff != "" {
+				t.Errorf("DHCPv6 plugins diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildConfigValidation(t *testing.T) {
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	fakeInterfaces(t, map[string]*intfInfo{
+		"eth0": {mac: mac, addrs: []net.Addr{mustCIDR(t, "10.0.0.1/24"), mustCIDR(t, "2001:db8::1/64")}},
+	})
+	tests := []struct {
+		desc string
+		conf *cpb.Config
+		// wantErr is a substring of the error, naming the offending field.
+		wantErr string
+	}{{
+		desc: "valid",
+		conf: &cpb.Config{
+			Interface: "eth0",
+			Dns:       []string{"10.0.0.53"},
+			BootzUrls: []string{"bootz://10.0.0.1:8008"},
+			Records: []*cpb.Record{
+				{Machine: "02:00:00:00:00:02", Ip: "10.0.0.2/24", Gateway: "10.0.0.1"},
+				{Machine: "02:00:00:00:00:02", Ip: "2001:db8::2/64"},
+				{CircuitId: "eth1/1", Ip: "10.0.0.3/24", Gateway: "10.0.0.1"},
+			},
+			Pools:          []*cpb.Pool{{Subnet: "10.0.1.0/24", Gateway: "10.0.1.1", Exclusions: []string{"10.0.1.2-10.0.1.9", "10.0.1.99"}}},
+			BootzRedirects: []*cpb.BootzRedirect{{VendorClass: "Arista Networks", BootzUrls: []string{"bootz://10.0.0.2:8008"}}},
+			Scopes:         []*cpb.Scope{{Subnet: "10.0.1.0/24", Router: "10.0.1.1", Dns: []string{"10.0.0.53"}}},
+			AdminAddress:   "localhost:8079",
+		},
+	}, {
+		desc:    "no interface",
+		conf:    &cpb.Config{},
+		wantErr: "interface",
+	}, {
+		desc:    "invalid dns",
+		conf:    &cpb.Config{Interface: "eth0", Dns: []string{"10.0.0.1", "dns"}},
+		wantErr: `dns[1] "dns"`,
+	}, {
+		desc:    "invalid bootz url",
+		conf:    &cpb.Config{Interface: "eth0", BootzUrls: []string{"https://10.0.0.1:8008"}},
+		wantErr: "bootz_urls[0]",
+	}, {
+		desc:    "bootz url with comma",
+		conf:    &cpb.Config{Interface: "eth0", BootzUrls: []string{"bootz://10.0.0.1:8008/a,b"}},
+		wantErr: "bootz_urls[0]",
+	}, {
+		desc:    "record without machine",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Ip: "10.0.0.2/24", Gateway: "10.0.0.1"}}},
+		wantErr: "records[0]",
+	}, {
+		desc:    "record machine with space",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Machine: "serial 1", Ip: "10.0.0.2/24", Gateway: "10.0.0.1"}}},
+		wantErr: "records[0] (serial 1)",
+	}, {
+		desc:    "record with invalid ip",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Machine: "serial-1", Ip: "10.0.0.2", Gateway: "10.0.0.1"}}},
+		wantErr: "records[0] (serial-1): invalid ip",
+	}, {
+		desc:    "record without gateway",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Machine: "serial-1", Ip: "10.0.0.2/24"}}},
+		wantErr: "records[0] (serial-1): invalid gateway",
+	}, {
+		desc:    "ipv6 record with gateway",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Machine: "serial-1", Ip: "2001:db8::2/64", Gateway: "2001:db8::1"}}},
+		wantErr: "records[0] (serial-1): gateway",
+	}, {
+		desc: "duplicate record",
+		conf: &cpb.Config{Interface: "eth0", Records: []*cpb.Record{
+			{Machine: "serial-1", Ip: "10.0.0.2/24", Gateway: "10.0.0.1"},
+			{Machine: "SERIAL-1", Ip: "10.0.0.3/24", Gateway: "10.0.0.1"},
+		}},
+		wantErr: "records[1] (SERIAL-1): duplicate of records[0]",
+	}, {
+		desc:    "record with machine and circuit id",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Machine: "serial-1", CircuitId: "eth1", Ip: "10.0.0.2/24", Gateway: "10.0.0.1"}}},
+		wantErr: "records[0]",
+	}, {
+		desc:    "invalid pool subnet",
+		conf:    &cpb.Config{Interface: "eth0", Pools: []*cpb.Pool{{Subnet: "10.0.1.0"}}},
+		wantErr: "pools[0] (10.0.1.0)",
+	}, {
+		desc:    "invalid pool exclusion",
+		conf:    &cpb.Config{Interface: "eth0", Pools: []*cpb.Pool{{Subnet: "10.0.1.0/24", Exclusions: []string{"10.0.1.9-10.0.1.2"}}}},
+		wantErr: "pools[0] (10.0.1.0/24): exclusions[0]",
+	}, {
+		desc:    "ipv6 pool with gateway",
+		conf:    &cpb.Config{Interface: "eth0", Pools: []*cpb.Pool{{Subnet: "2001:db8:1::/64", Gateway: "2001:db8:1::1"}}},
+		wantErr: "pools[0] (2001:db8:1::/64): gateway",
+	}, {
+		desc:    "redirect without criteria",
+		conf:    &cpb.Config{Interface: "eth0", BootzRedirects: []*cpb.BootzRedirect{{BootzUrls: []string{"bootz://10.0.0.2:8008"}}}},
+		wantErr: "bootz_redirects[0]",
+	}, {
+		desc:    "redirect without urls",
+		conf:    &cpb.Config{Interface: "eth0", BootzRedirects: []*cpb.BootzRedirect{{ClientId: "serial-1"}}},
+		wantErr: "bootz_redirects[0]: bootz_urls",
+	}, {
+		desc:    "redirect with invalid mac prefix",
+		conf:    &cpb.Config{Interface: "eth0", BootzRedirects: []*cpb.BootzRedirect{{MacPrefix: "zz:00", BootzUrls: []string{"bootz://10.0.0.2:8008"}}}},
+		wantErr: "bootz_redirects[0]: invalid mac_prefix",
+	}, {
+		desc:    "scope with invalid router",
+		conf:    &cpb.Config{Interface: "eth0", Scopes: []*cpb.Scope{{Subnet: "10.0.1.0/24", Router: "router"}}},
+		wantErr: "scopes[0] (10.0.1.0/24): invalid router",
+	}, {
+		desc:    "scope with dns of another family",
+		conf:    &cpb.Config{Interface: "eth0", Scopes: []*cpb.Scope{{Subnet: "10.0.1.0/24", Dns: []string{"2001:db8::53"}}}},
+		wantErr: "scopes[0] (10.0.1.0/24): invalid dns[0]",
+	}, {
+		desc:    "redirect with urls and boot file",
+		conf:    &cpb.Config{Interface: "eth0", BootzRedirects: []*cpb.BootzRedirect{{ClientId: "serial-1", BootzUrls: []string{"bootz://10.0.0.2:8008"}, BootFile: "http://10.0.0.1/ztp.sh"}}},
+		wantErr: "bootz_redirects[0]: bootz_urls and boot_file",
+	}, {
+		desc:    "invalid legacy boot server",
+		conf:    &cpb.Config{Interface: "eth0", LegacyBootServer: "10.0.0.1:8080"},
+		wantErr: "legacy_boot_server",
+	}, {
+		desc:    "relative boot file without legacy boot server",
+		conf:    &cpb.Config{Interface: "eth0", Records: []*cpb.Record{{Machine: "serial-1", Ip: "10.0.0.2/24", Gateway: "10.0.0.1", BootFile: "ztp.sh"}}},
+		wantErr: `records[0] (serial-1): boot_file "ztp.sh"`,
+	}, {
+		desc:    "boot file with unsupported scheme",
+		conf:    &cpb.Config{Interface: "eth0", BootzRedirects: []*cpb.BootzRedirect{{VendorClass: "Cisco", BootFile: "ftp://10.0.0.1/ztp.sh"}}},
+		wantErr: "bootz_redirects[0]: boot_file",
+	}, {
+		desc:    "invalid admin address",
+		conf:    &cpb.Config{Interface: "eth0", AdminAddress: "localhost"},
+		wantErr: "admin_address",
+	}, {
+		desc: "admin address on a network interface with TLS",
+		conf: &cpb.Config{Interface: "eth0", AdminAddress: "10.0.0.1:8079", AdminTls: &cpb.AdminTLS{CertFile: "admin.pem", KeyFile: "admin.key", ClientCaFile: "ca.pem"}},
+	}, {
+		desc:    "admin address on all interfaces without TLS",
+		conf:    &cpb.Config{Interface: "eth0", AdminAddress: ":8079"},
+		wantErr: "admin_address",
+	}, {
+		desc:    "admin address on a network interface without TLS",
+		conf:    &cpb.Config{Interface: "eth0", AdminAddress: "10.0.0.1:8079"},
+		wantErr: "admin_address",
+	}, {
+		desc:    "incomplete admin TLS",
+		conf:    &cpb.Config{Interface: "eth0", AdminAddress: "10.0.0.1:8079", AdminTls: &cpb.AdminTLS{CertFile: "admin.pem", KeyFile: "admin.key"}},
+		wantErr: "admin_tls",
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := buildConfig(test.conf)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("buildConfig() err = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("buildConfig() err = %v, want error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildConfigSpecialCharacters(t *testing.T) {
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	fakeInterfaces(t, map[string]*intfInfo{"eth0": {mac: mac, addrs: []net.Addr{mustCIDR(t, "10.0.0.1/24")}}})
+	c, err := buildConfig(&cpb.Config{
+		Interface:      "eth0",
+		Mode:           cpb.Config_MODE_IPV4_ONLY,
+		BootzRedirects: []*cpb.BootzRedirect{{VendorClass: "Vendor <A> & B", BootzUrls: []string{"bootz://10.0.0.2:8008"}}},
+	})
+	if err != nil {
+		t.Fatalf("buildConfig() err = %v", err)
+	}
+	// Values are passed to the plugins unescaped and unsplit.
+	want := []string{"match:vendor-class=Vendor+%3CA%3E+%26+B,bootz://10.0.0.2:8008"}
+	if diff := cmp.Diff(want, plugins(c.Server4)["bootz"]); diff != "" {
+		t.Errorf("bootz plugin args diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildConfigLegacy(t *testing.T) {
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	fakeInterfaces(t, map[string]*intfInfo{"eth0": {mac: mac, addrs: []net.Addr{mustCIDR(t, "10.0.0.1/24")}}})
+	c, err := buildConfig(&cpb.Config{
+		Interface:        "eth0",
+		Mode:             cpb.Config_MODE_IPV4_ONLY,
+		LegacyBootServer: "http://10.0.0.1:8080/images/",
+		Records: []*cpb.Record{
+			{Machine: "02:00:00:00:00:02", Ip: "10.0.0.2/24", Gateway: "10.0.0.1", BootFile: "ztp.sh"},
+			{Machine: "serial-3", Ip: "10.0.0.3/24", Gateway: "10.0.0.1", BootFile: "tftp://10.0.0.9/boot.cfg"},
+			{CircuitId: "eth1", Ip: "10.0.0.4/24", Gateway: "10.0.0.1", BootFile: "/ztp/port.sh"},
+			{Machine: "serial-5", Ip: "10.0.0.5/24", Gateway: "10.0.0.1"},
+		},
+		BootzRedirects: []*cpb.BootzRedirect{{VendorClass: "Cisco", BootFile: "cisco.py"}},
+	})
+	if err != nil {
+		t.Fatalf("buildConfig() err = %v", err)
+	}
+	want := []string{
+		"legacy:mac-prefix=02%3A00%3A00%3A00%3A00%3A02,http://10.0.0.1:8080/images/ztp.sh",
+		"legacy:client-id=serial-3,tftp://10.0.0.9/boot.cfg",
+		"legacy:circuit-id=eth1,http://10.0.0.1:8080/ztp/port.sh",
+		"legacy:vendor-class=Cisco,http://10.0.0.1:8080/images/cisco.py",
+	}
+	if diff := cmp.Diff(want, plugins(c.Server4)["bootz"]); diff != "" {
+		t.Errorf("bootz plugin args diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildConfigAlternateMachines(t *testing.T) {
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	fakeInterfaces(t, map[string]*intfInfo{"eth0": {mac: mac, addrs: []net.Addr{mustCIDR(t, "10.0.0.1/24"), mustCIDR(t, "2001:db8::1/64")}}})
+	// The record the Bootz server derives from a control card with two management ports.
+	c, err := buildConfig(&cpb.Config{
+		Interface:        "eth0",
+		LegacyBootServer: "http://10.0.0.1:8080/",
+		Records: []*cpb.Record{
+			{Machine: "02:00:00:00:00:02", AlternateMachines: []string{"02:00:00:00:00:03"}, Ip: "10.0.0.2/24", Gateway: "10.0.0.1", BootFile: "ztp.sh"},
+			{Machine: "02:00:00:00:00:02", AlternateMachines: []string{"02:00:00:00:00:03"}, Ip: "2001:db8::2/64"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("buildConfig() err = %v", err)
+	}
+	wantLegacy := []string{
+		"legacy:mac-prefix=02%3A00%3A00%3A00%3A00%3A02,http://10.0.0.1:8080/ztp.sh",
+		"legacy:mac-prefix=02%3A00%3A00%3A00%3A00%3A03,http://10.0.0.1:8080/ztp.sh",
+	}
+	if diff := cmp.Diff(wantLegacy, plugins(c.Server4)["bootz"]); diff != "" {
+		t.Errorf("bootz plugin args diff (-want +got):\n%s", diff)
+	}
+	// The slease plugin accepts the machines sharing the address.
+	if _, err := plslease.Plugin.Setup4(plugins(c.Server4)["slease"]...); err != nil {
+		t.Errorf("slease Setup4() err = %v", err)
+	}
+	if _, err := plslease.Plugin.Setup6(plugins(c.Server6)["slease"]...); err != nil {
+		t.Errorf("slease Setup6() err = %v", err)
+	}
+
+	for _, r := range []*cpb.Record{
+		{AlternateMachines: []string{"02:00:00:00:00:03"}, CircuitId: "eth1", Ip: "10.0.0.2/24", Gateway: "10.0.0.1"},
+		{Machine: "02:00:00:00:00:02", AlternateMachines: []string{""}, Ip: "10.0.0.2/24", Gateway: "10.0.0.1"},
+		{Machine: "02:00:00:00:00:02", AlternateMachines: []string{"02:00:00:00:00:02"}, Ip: "10.0.0.2/24", Gateway: "10.0.0.1"},
+	} {
+		if _, err := buildConfig(&cpb.Config{Interface: "eth0", Records: []*cpb.Record{r}}); err == nil {
+			t.Errorf("buildConfig() of record %v err = nil, want error", r)
+		}
+	}
+}
@@ -17,13 +17,10 @@ package dhcp
 
 import (
 	"fmt"
-	"html/template"
-	"net"
-	"os"
-	"strings"
 	"sync"
 
 	"github.com/coredhcp/coredhcp/logger"
+	"google.golang.org/grpc"
 
 	cdconfig "github.com/coredhcp/coredhcp/config"
 	cdplugins "github.com/coredhcp/coredhcp/plugins"
@@ -32,40 +29,12 @@ import (
 	plserverid "github.com/coredhcp/coredhcp/plugins/serverid"
 	cdserver "github.com/coredhcp/coredhcp/server"
 	plbootz "github.com/openconfig/bootz/dhcp/plugins/bootz"
+	plscope "github.com/openconfig/bootz/dhcp/plugins/scope"
 	plslease "github.com/openconfig/bootz/dhcp/plugins/slease"
 
 	cpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
 )
 
-const confTemplate = `
-# CoreDHCP configuration (yaml)
-server6:
-   plugins:
-     - server_id: LL {{ .IntfMacAddr }}
-     {{ if .BootzURLs }}
-     - bootz: {{ .BootzURLs }}
-     {{ end }}
-     {{ if .DNSv6 }}
-     - DNS: {{ .DNSv6 }}
-     {{ end }}
-     {{ if .IPv6Leases }}
-     - slease: {{ .IPv6Leases }}
-     {{ end }}
-server4:
-  plugins:
-    - lease_time: 3600s
-    - server_id: {{ .IntfIPAddr }}
-    {{ if .BootzURLs }}
-    - bootz: {{ .BootzURLs }}
-    {{ end }}
-    {{ if .DNSv4 }}
-    - DNS: {{ .DNSv4 }}
-    {{ end }}
-    {{ if .IPv4Leases }}
-    - slease: {{ .IPv4Leases }}
-    {{ end }}
-`
-
 type Opts struct {
 	Config *cpb.Config
 }
@@ -74,6 +43,9 @@ func (*Opts) IsBootzServerOpts() {}
 
 type Server struct {
 	server *cdserver.Servers
+	// config is the configuration the server runs with, restored if a reload fails.
+	config *cdconfig.Config
+	admin  *grpc.Server
 }
 
 var instance *Server = nil
@@ -85,6 +57,7 @@ var desiredPlugins = []*cdplugins.Plugin{
 	&plleasetime.Plugin,
 	&plDNS.Plugin,
 	&plbootz.Plugin,
+	&plscope.Plugin,
 	&plslease.Plugin,
 }
 
@@ -105,16 +78,10 @@ func Start(conf *cpb.Config) error {
 		return fmt.Errorf("dhcp server already started")
 	}
 
-	configFile, err := generateConfigFile(conf)
+	c, err := buildConfig(conf)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(configFile)
-
-	c, err := cdconfig.Load(configFile)
-	if err != nil {
-		return fmt.Errorf("failed to load configuration: %v", err)
-	}
 
 	srv, err := cdserver.Start(c)
 	if err != nil {
@@ -123,95 +90,64 @@ func Start(conf *cpb.Config) error {
 
 	instance = &Server{
 		server: srv,
+		config: c,
+	}
+	if conf.GetAdminAddress() != "" {
+		if instance.admin, err = startAdmin(conf); err != nil {
+			srv.Close()
+			srv.Wait()
+			instance = nil
+			return err
+		}
 	}
 	return nil
 }
 
-// Stop stops the DHCP server.
-func Stop() {
+// Reload restarts the running dhcp server with the given configuration, which is validated first. Leases are kept. If
+// the server fails to start with the new configuration, it is restarted with the previous one.
+func Reload(conf *cpb.Config) error {
 	lock.Lock()
 	defer lock.Unlock()
-	if instance != nil {
-		instance.server.Close()
-		instance.server.Wait()
-	}
-	instance = nil
-}
 
-func generateConfigFile(conf *cpb.Config) (string, error) {
-	configFile, err := os.CreateTemp("", "coredhcp_conf_*.yml")
-	if err != nil {
-		return "", fmt.Errorf("error creating configuration file: %v", err)
+	if instance == nil {
+		return fmt.Errorf("dhcp server not started")
 	}
-	defer configFile.Close()
 
-	confTmpl, err := template.New("coredhcp").Parse(confTemplate)
+	c, err := buildConfig(conf)
 	if err != nil {
-		return "", fmt.Errorf("error parsing configuration template: %v", err)
+		return err
 	}
 
-	intf, err := net.InterfaceByName(conf.GetInterface())
+	instance.server.Close()
+	instance.server.Wait()
+	srv, err := cdserver.Start(c)
 	if err != nil {
-		return "", fmt.Errorf("unknown interface %v: %v", conf.GetInterface(), err)
-	}
-
-	IPv4Addr := getIPv4Address(intf)
-	if IPv4Addr == nil {
-		return "", fmt.Errorf("unable to find IPv4 address for interface %v", conf.GetInterface())
-	}
-
-	DNSv4, DNSv6 := []string{}, []string{}
-	for _, v := range conf.GetDns() {
-		if isIPv6(v) {
-			DNSv6 = append(DNSv6, v)
-		} else {
-			DNSv4 = append(DNSv4, v)
-		}
-	}
-
-	v6Records, v4Records := []string{}, []string{}
-	for _, v := range conf.GetRecords() {
-		if isIPv6(v.GetIp()) {
-			v6Records = append(v6Records, fmt.Sprintf("%s,%s", v.GetMachine(), v.GetIp()))
-		} else {
-			v4Records = append(v4Records, fmt.Sprintf("%s,%s,%s", v.GetMachine(), v.GetIp(), v.GetGateway()))
+		prev, restoreErr := cdserver.Start(instance.config)
+		if restoreErr != nil {
+			if instance.admin != nil {
+				instance.admin.Stop()
+			}
+			instance = nil
+			return fmt.Errorf("error restarting DHCP server: %v, and with the previous configuration: %v", err, restoreErr)
 		}
+		instance.server = prev
+		return fmt.Errorf("error restarting DHCP server, the previous configuration is kept: %v", err)
 	}
-
-	if err := confTmpl.Execute(configFile, struct {
-		IntfIPAddr  string
-		IntfMacAddr string
-		DNSv4       string
-		DNSv6       string
-		IPv4Leases  string
-		IPv6Leases  string
-		BootzURLs   string
-	}{
-		IntfIPAddr:  IPv4Addr.String(),
-		IntfMacAddr: intf.HardwareAddr.String(),
-		DNSv4:       strings.Join(DNSv4, " "),
-		DNSv6:       strings.Join(DNSv6, " "),
-		IPv4Leases:  strings.Join(v4Records, " "),
-		IPv6Leases:  strings.Join(v6Records, " "),
-		BootzURLs:   strings.Join(conf.GetBootzUrls(), " "),
-	}); err != nil {
-		return "", fmt.Errorf("error generating configuration template: %v", err)
-	}
-	return configFile.Name(), nil
-}
-
-func isIPv6(address string) bool {
-	return strings.Count(address, ":") >= 2
+	instance.server, instance.config = srv, c
+	return nil
 }
 
-func getIPv4Address(i *net.Interface) net.IP {
-	if addrs, err := i.Addrs(); err == nil {
-		for _, a := range addrs {
-			v4 := a.(*net.IPNet).IP.To4()
-			if v4 != nil {
-				return v4
-			}
+// Stop stops the DHCP server.
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+	if instance != nil {
+		instance.server.Close()
+		instance.server.Wait()
+		if instance.admin != nil {
+			instance.admin.Stop()
 		}
+		plslease.Flush()
 	}
-	return nil
+	instance = nil
 }
@@ -19,12 +19,15 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/coredhcp/coredhcp/handler"
 	"github.com/coredhcp/coredhcp/logger"
 	"github.com/coredhcp/coredhcp/plugins"
 	"github.com/insomniacslk/dhcp/dhcpv4"
 	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
 )
 
 var log = logger.GetLogger("plugins/bootz")
@@ -43,6 +46,8 @@ const (
 var (
 	ztpV4Opt *dhcpv4.Option
 	ztpV6Opt dhcpv6.Option
+	rules4   []*rule
+	rules6   []*rule
 )
 
 func encodeBootstrapServerList(urls []string) []byte {
@@ -78,36 +83,134 @@ func parseArgs(args ...string) ([]string, error) {
 	return urls, nil
 }
 
+// parseConfig splits the plugin arguments into the default bootz server URLs, sent to the clients which match no
+// rule or relay scope, and the redirect and legacy rules in the order they are evaluated.
+func parseConfig(args ...string) ([]string, []*rule, error) {
+	var defaults []string
+	var rules []*rule
+	for _, arg := range args {
+		if strings.HasPrefix(arg, MatchPrefix) || strings.HasPrefix(arg, LegacyPrefix) {
+			r, err := parseRule(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			rules = append(rules, r)
+			continue
+		}
+		defaults = append(defaults, arg)
+	}
+	if len(defaults) == 0 {
+		// Without default URLs, only the clients matching a rule or a relay scope with bootz URLs are redirected.
+		return nil, rules, nil
+	}
+	urls, err := parseArgs(defaults...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return urls, rules, nil
+}
+
 func setup4(args ...string) (handler.Handler4, error) {
-	urls, err := parseArgs(args...)
+	urls, rules, err := parseConfig(args...)
 	if err != nil {
 		return nil, err
 	}
 
-	ztpV4Opt = &dhcpv4.Option{
-		Code:  dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT),
-		Value: dhcpv4.String(string(encodeBootstrapServerList(urls))),
+	ztpV4Opt = nil
+	if urls != nil {
+		ztpV4Opt = newV4Option(urls)
 	}
+	rules4 = rules
 	return handler4, nil
 }
 
 func setup6(args ...string) (handler.Handler6, error) {
-	urls, err := parseArgs(args...)
+	urls, rules, err := parseConfig(args...)
 	if err != nil {
 		return nil, err
 	}
-	ztpV6Opt = &dhcpv6.OptionGeneric{
+	ztpV6Opt = nil
+	if urls != nil {
+		ztpV6Opt = newV6Option(urls)
+	}
+	rules6 = rules
+	return handler6, nil
+}
+
+func newV4Option(urls []string) *dhcpv4.Option {
+	return &dhcpv4.Option{
+		Code:  dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT),
+		Value: dhcpv4.String(string(encodeBootstrapServerList(urls))),
+	}
+}
+
+func newV6Option(urls []string) dhcpv6.Option {
+	return &dhcpv6.OptionGeneric{
 		OptionCode: dhcpv6.OptionCode(OPTION_V6_SZTP_REDIRECT),
 		OptionData: encodeBootstrapServerList(urls),
 	}
-	return handler6, nil
+}
+
+// firstMatch returns the first rule matching the client, or nil.
+func firstMatch(rules []*rule, c *clientInfo) *rule {
+	for _, r := range rules {
+		if r.matches(c) {
+			return r
+		}
+	}
+	return nil
+}
+
+// select4 returns the redirect option of the client: the one of its matching rule, the one of the relay scope of the
+// client, or the default one.
+func select4(req *dhcpv4.DHCPv4, r *rule) *dhcpv4.Option {
+	if r != nil {
+		return newV4Option(r.urls)
+	}
+	if s := scope.Lookup4(req); s != nil && len(s.BootzURLs) > 0 {
+		return newV4Option(s.BootzURLs)
+	}
+	return ztpV4Opt
+}
+
+// select6 returns the redirect option of the client: the one of its matching rule, the one of the relay scope of the
+// client, or the default one.
+func select6(req dhcpv6.DHCPv6, r *rule) dhcpv6.Option {
+	if r != nil {
+		return newV6Option(r.urls)
+	}
+	if s := scope.Lookup6(req); s != nil && len(s.BootzURLs) > 0 {
+		return newV6Option(s.BootzURLs)
+	}
+	return ztpV6Opt
+}
+
+// legacy4 sets the classic ZTP options of a legacy client: the boot file URL (option 67) and the name of the server
+// serving it (option 66).
+func legacy4(resp *dhcpv4.DHCPv4, bootFile string) {
+	if u, err := url.Parse(bootFile); err == nil {
+		resp.Options.Update(dhcpv4.OptTFTPServerName(u.Hostname()))
+	}
+	resp.Options.Update(dhcpv4.OptBootFileName(bootFile))
+	log.Debugf("Added legacy ZTP options: %v", resp.Summary())
 }
 
 func handler4(req, resp *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, bool) {
+	var r *rule
+	if len(rules4) > 0 {
+		r = firstMatch(rules4, clientInfo4(req))
+	}
+	if r != nil && r.bootFile != "" {
+		// Legacy clients get the boot file options whether they request them or not, and never a redirect.
+		legacy4(resp, r.bootFile)
+		return resp, false
+	}
 	for _, p := range req.ParameterRequestList() {
 		if p.Code() == OPTION_V4_SZTP_REDIRECT {
-			resp.Options.Update(*ztpV4Opt)
-			log.Debugf("Added ZTP option: %v", resp.Summary())
+			if opt := select4(req, r); opt != nil {
+				resp.Options.Update(*opt)
+				log.Debugf("Added ZTP option: %v", resp.Summary())
+			}
 			break
 		}
 	}
@@ -121,10 +224,23 @@ func handler6(req, resp dhcpv6.DHCPv6) (dhcpv6.DHCPv6, bool) {
 		return nil, false
 	}
 
+	var r *rule
+	if len(rules6) > 0 {
+		r = firstMatch(rules6, clientInfo6(req, decap))
+	}
+	if r != nil && r.bootFile != "" {
+		// Legacy clients get the boot file URL option (option 59) whether they request it or not, and never a redirect.
+		resp.UpdateOption(dhcpv6.OptBootFileURL(r.bootFile))
+		log.Debugf("Added legacy ZTP option: %v", resp.Summary())
+		return resp, false
+	}
 	for _, code := range decap.Options.RequestedOptions() {
-		if code == ztpV6Opt.Code() {
-			resp.AddOption(ztpV6Opt)
-			log.Debugf("Added ZTP option: %v", resp.Summary())
+		if code == dhcpv6.OptionCode(OPTION_V6_SZTP_REDIRECT) {
+			if opt := select6(req, r); opt != nil {
+				resp.AddOption(opt)
+				log.Debugf("Added ZTP option: %v", resp.Summary())
+			}
+			break
 		}
 	}
 	return resp, false
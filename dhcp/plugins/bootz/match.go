@@ -0,0 +1,231 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootz
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
+)
+
+// MatchPrefix marks the plugin arguments which define a redirect rule rather than a default bootz server URL.
+// format: match:<criteria>,url,url... where the criteria are URL query encoded, e.g.
+// match:vendor-class=Arista&circuit-id=eth1,bootz://10.0.0.1:8008
+const MatchPrefix = "match:"
+
+// LegacyPrefix marks the plugin arguments which define a legacy rule: the matching clients do not speak Bootz and get
+// the classic ZTP boot file options instead of a redirect.
+// format: legacy:<criteria>,bootfile-url where the criteria are those of a redirect rule, e.g.
+// legacy:mac-prefix=00:1c:73:00:00:01,http://10.0.0.1:8080/ztp.sh
+const LegacyPrefix = "legacy:"
+
+// The criteria of a redirect or legacy rule.
+const (
+	// MatchVendorClass matches the start of the DHCPv4 class identifier (option 60) or of one of the DHCPv6 vendor
+	// class (option 16) data.
+	MatchVendorClass = "vendor-class"
+	// MatchClientID matches the DHCPv4 client identifier (option 61), either as text without its type byte or hex
+	// encoded, or the DHCPv6 DUID: either the identifier of a DUID-EN or the hex encoded DUID. The match is
+	// case-insensitive.
+	MatchClientID = "client-id"
+	// MatchMACPrefix matches the start of the client hardware address, ignoring separators and case.
+	MatchMACPrefix = "mac-prefix"
+	// MatchCircuitID matches the relay agent circuit ID (DHCPv4 option 82 sub-option 1), or the interface ID (DHCPv6
+	// option 18) of the relay closest to the client, as text or hex encoded.
+	MatchCircuitID = "circuit-id"
+	// MatchRemoteID matches the relay agent remote ID (DHCPv4 option 82 sub-option 2), or the remote ID (DHCPv6 option
+	// 37) of the relay closest to the client, as text or hex encoded.
+	MatchRemoteID = "remote-id"
+)
+
+// rule selects the bootz server URLs, or the legacy boot file, of the clients which match all its criteria.
+type rule struct {
+	vendorClass string
+	clientID    string
+	macPrefix   string
+	circuitID   string
+	remoteID    string
+	urls        []string
+	// bootFile is the boot file URL of a legacy rule, which has no urls.
+	bootFile string
+}
+
+// clientInfo is the request information the rules match against.
+type clientInfo struct {
+	vendorClasses []string
+	clientIDs     []string
+	mac           net.HardwareAddr
+	agent         scope.AgentIDs
+}
+
+// parseRule parses a redirect or legacy rule plugin argument.
+func parseRule(arg string) (*rule, error) {
+	if spec, ok := strings.CutPrefix(arg, LegacyPrefix); ok {
+		criteria, bootFile, _ := strings.Cut(spec, ",")
+		r, err := parseCriteria(arg, criteria)
+		if err != nil {
+			return nil, err
+		}
+		if r.bootFile, err = parseBootFile(bootFile); err != nil {
+			return nil, fmt.Errorf("invalid legacy rule %v: %v", arg, err)
+		}
+		return r, nil
+	}
+	spec, ok := strings.CutPrefix(arg, MatchPrefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid redirect rule %v", arg)
+	}
+	parts := strings.Split(spec, ",")
+	r, err := parseCriteria(arg, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if r.urls, err = parseArgs(parts[1:]...); err != nil {
+		return nil, fmt.Errorf("invalid redirect rule %v: %v", arg, err)
+	}
+	return r, nil
+}
+
+// parseBootFile verifies that the boot file of a legacy rule is an absolute URL which legacy ZTP clients can fetch.
+func parseBootFile(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http", "https", "tftp":
+	default:
+		return "", fmt.Errorf("boot file %q must be an http, https or tftp URL", s)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("boot file %q has no host", s)
+	}
+	return u.String(), nil
+}
+
+// parseCriteria parses the URL query encoded criteria of the rule argument arg.
+func parseCriteria(arg, spec string) (*rule, error) {
+	criteria, err := url.ParseQuery(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule criteria %v: %v", spec, err)
+	}
+	r := &rule{}
+	for k, v := range criteria {
+		if len(v) != 1 || v[0] == "" {
+			return nil, fmt.Errorf("rule %v must set %v once", arg, k)
+		}
+		switch k {
+		case MatchVendorClass:
+			r.vendorClass = v[0]
+		case MatchClientID:
+			r.clientID = v[0]
+		case MatchMACPrefix:
+			r.macPrefix = normalizeMAC(v[0])
+			if strings.Trim(r.macPrefix, "0123456789abcdef") != "" {
+				return nil, fmt.Errorf("invalid mac prefix %v", v[0])
+			}
+		case MatchCircuitID:
+			r.circuitID = v[0]
+		case MatchRemoteID:
+			r.remoteID = v[0]
+		default:
+			return nil, fmt.Errorf("unknown rule criterion %v", k)
+		}
+	}
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("rule %v has no criteria", arg)
+	}
+	return r, nil
+}
+
+func normalizeMAC(s string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(s))
+}
+
+// matches returns whether the client matches all the criteria of the rule.
+func (r *rule) matches(c *clientInfo) bool {
+	if r.vendorClass != "" && !anyOf(c.vendorClasses, func(v string) bool { return strings.HasPrefix(v, r.vendorClass) }) {
+		return false
+	}
+	if r.clientID != "" && !anyOf(c.clientIDs, func(v string) bool { return strings.EqualFold(v, r.clientID) }) {
+		return false
+	}
+	if r.macPrefix != "" && (c.mac == nil || !strings.HasPrefix(normalizeMAC(c.mac.String()), r.macPrefix)) {
+		return false
+	}
+	if r.circuitID != "" && !scope.MatchAgentID(c.agent.CircuitID, r.circuitID) {
+		return false
+	}
+	if r.remoteID != "" && !scope.MatchAgentID(c.agent.RemoteID, r.remoteID) {
+		return false
+	}
+	return true
+}
+
+func anyOf(values []string, f func(string) bool) bool {
+	for _, v := range values {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientInfo4 extracts the information the rules match against from a DHCPv4 request.
+func clientInfo4(req *dhcpv4.DHCPv4) *clientInfo {
+	c := &clientInfo{mac: req.ClientHWAddr, agent: scope.AgentIDs4(req)}
+	if vc := req.ClassIdentifier(); vc != "" {
+		c.vendorClasses = []string{vc}
+	}
+	if cid := req.Options.Get(dhcpv4.OptionClientIdentifier); len(cid) > 0 {
+		c.clientIDs = []string{ClientID(cid), fmt.Sprintf("%x", cid)}
+	}
+	return c
+}
+
+// ClientID returns a client identifier or DUID enterprise identifier as text, without the leading type byte and the
+// other non-graphic characters around it, as the static records and leases are keyed.
+func ClientID(b []byte) string {
+	return strings.TrimFunc(string(b), func(r rune) bool {
+		return !unicode.IsGraphic(r)
+	})
+}
+
+// clientInfo6 extracts the information the rules match against from a DHCPv6 request, which may be relayed.
+func clientInfo6(req dhcpv6.DHCPv6, m *dhcpv6.Message) *clientInfo {
+	c := &clientInfo{agent: scope.AgentIDs6(req)}
+	for _, vc := range m.Options.VendorClasses() {
+		for _, d := range vc.Data {
+			c.vendorClasses = append(c.vendorClasses, string(d))
+		}
+	}
+	if duid := m.Options.ClientID(); duid != nil {
+		if en, ok := duid.(*dhcpv6.DUIDEN); ok {
+			c.clientIDs = append(c.clientIDs, ClientID(en.EnterpriseIdentifier))
+		}
+		c.clientIDs = append(c.clientIDs, fmt.Sprintf("%x", duid.ToBytes()))
+	}
+	if mac, err := dhcpv6.ExtractMAC(req); err == nil {
+		c.mac = mac
+	}
+	return c
+}
@@ -0,0 +1,398 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootz
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
+)
+
+var testArgs = []string{
+	"bootz://default:8008",
+	"match:vendor-class=Arista&circuit-id=eth1,bootz://arista-lab:8008",
+	"match:vendor-class=Arista,bootz://arista:8008,bootz://arista-backup:8008",
+	"match:client-id=serial-1,bootz://serial:8008",
+	"match:mac-prefix=00-1C-73,bootz://mac:8008",
+	"match:remote-id=001c73000009,bootz://tor:8008",
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		arg     string
+		wantErr bool
+	}{
+		{arg: "match:vendor-class=Cisco+Systems,bootz://a:8008"},
+		{arg: "match:mac-prefix=00:1c:73&circuit-id=Gi0%2F1,bootz://a:8008,bootz://b:8008"},
+		{arg: "match:,bootz://a:8008", wantErr: true},
+		{arg: "match:vendor-class=Cisco", wantErr: true},
+		{arg: "match:model=Cisco,bootz://a:8008", wantErr: true},
+		{arg: "match:mac-prefix=zz,bootz://a:8008", wantErr: true},
+		{arg: "match:client-id=a&client-id=b,bootz://a:8008", wantErr: true},
+		{arg: "match:client-id=,bootz://a:8008", wantErr: true},
+		{arg: "match:client-id=%zz,bootz://a:8008", wantErr: true},
+		{arg: "legacy:mac-prefix=00:1c:73:00:00:01,http://10.0.0.1:8080/ztp.sh"},
+		{arg: "legacy:vendor-class=Cisco,tftp://10.0.0.1/boot,script.py"},
+		{arg: "legacy:vendor-class=Cisco", wantErr: true},
+		{arg: "legacy:vendor-class=Cisco,ztp.sh", wantErr: true},
+		{arg: "legacy:vendor-class=Cisco,bootz://10.0.0.1:8008", wantErr: true},
+		{arg: "legacy:,http://10.0.0.1:8080/ztp.sh", wantErr: true},
+	}
+	for _, test := range tests {
+		if _, err := parseRule(test.arg); (err != nil) != test.wantErr {
+			t.Errorf("parseRule(%q) err = %v, want error %v", test.arg, err, test.wantErr)
+		}
+	}
+}
+
+func TestHandler4Redirect(t *testing.T) {
+	if _, err := setup4(testArgs...); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	tests := []struct {
+		desc string
+		mac  string
+		opts []dhcpv4.Option
+		want []string
+	}{{
+		desc: "no match",
+		mac:  "02:00:00:00:00:01",
+		want: []string{"bootz://default:8008"},
+	}, {
+		desc: "vendor class",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv4.Option{dhcpv4.OptClassIdentifier("Arista;DCS-7280")},
+		want: []string{"bootz://arista:8008", "bootz://arista-backup:8008"},
+	}, {
+		desc: "vendor class and circuit id",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv4.Option{
+			dhcpv4.OptClassIdentifier("Arista;DCS-7280"),
+			dhcpv4.OptRelayAgentInfo(dhcpv4.OptGeneric(dhcpv4.AgentCircuitIDSubOption, []byte("eth1"))),
+		},
+		want: []string{"bootz://arista-lab:8008"},
+	}, {
+		desc: "circuit id without vendor class",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv4.Option{
+			dhcpv4.OptRelayAgentInfo(dhcpv4.OptGeneric(dhcpv4.AgentCircuitIDSubOption, []byte("eth1"))),
+		},
+		want: []string{"bootz://default:8008"},
+	}, {
+		desc: "hex remote id",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv4.Option{
+			dhcpv4.OptRelayAgentInfo(dhcpv4.OptGeneric(dhcpv4.AgentRemoteIDSubOption, []byte{0x00, 0x1c, 0x73, 0x00, 0x00, 0x09})),
+		},
+		want: []string{"bootz://tor:8008"},
+	}, {
+		desc: "client id",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv4.Option{dhcpv4.OptClientIdentifier([]byte("SERIAL-1"))},
+		want: []string{"bootz://serial:8008"},
+	}, {
+		desc: "client id with type byte",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv4.Option{dhcpv4.OptClientIdentifier([]byte("\x00SERIAL-1"))},
+		want: []string{"bootz://serial:8008"},
+	}, {
+		desc: "mac prefix",
+		mac:  "00:1c:73:00:00:01",
+		want: []string{"bootz://mac:8008"},
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			hw, _ := net.ParseMAC(test.mac)
+			mods := []dhcpv4.Modifier{dhcpv4.WithRequestedOptions(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT))}
+			for _, o := range test.opts {
+				mods = append(mods, dhcpv4.WithOption(o))
+			}
+			req, err := dhcpv4.NewDiscovery(hw, mods...)
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			resp, _ = handler4(req, resp)
+			got := resp.Options.Get(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT))
+			if want := encodeBootstrapServerList(test.want); !bytes.Equal(got, want) {
+				t.Errorf("handler4() redirect = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandler6Redirect(t *testing.T) {
+	if _, err := setup6(testArgs...); err != nil {
+		t.Fatalf("setup6() err = %v", err)
+	}
+	tests := []struct {
+		desc        string
+		mac         string
+		opts        []dhcpv6.Option
+		interfaceID string
+		want        []string
+	}{{
+		desc: "no match",
+		mac:  "02:00:00:00:00:01",
+		want: []string{"bootz://default:8008"},
+	}, {
+		desc: "vendor class",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv6.Option{&dhcpv6.OptVendorClass{EnterpriseNumber: 30065, Data: [][]byte{[]byte("Arista")}}},
+		want: []string{"bootz://arista:8008", "bootz://arista-backup:8008"},
+	}, {
+		desc:        "vendor class and relay interface id",
+		mac:         "02:00:00:00:00:01",
+		opts:        []dhcpv6.Option{&dhcpv6.OptVendorClass{EnterpriseNumber: 30065, Data: [][]byte{[]byte("Arista")}}},
+		interfaceID: "eth1",
+		want:        []string{"bootz://arista-lab:8008"},
+	}, {
+		desc: "duid-en identifier",
+		mac:  "02:00:00:00:00:01",
+		opts: []dhcpv6.Option{dhcpv6.OptClientID(&dhcpv6.DUIDEN{EnterpriseNumber: 30065, EnterpriseIdentifier: []byte("SERIAL-1")})},
+		want: []string{"bootz://serial:8008"},
+	}, {
+		desc:        "relayed mac prefix",
+		mac:         "00:1c:73:00:00:01",
+		interfaceID: "eth2",
+		want:        []string{"bootz://mac:8008"},
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			hw, _ := net.ParseMAC(test.mac)
+			mods := []dhcpv6.Modifier{dhcpv6.WithRequestedOptions(dhcpv6.OptionCode(OPTION_V6_SZTP_REDIRECT))}
+			for _, o := range test.opts {
+				mods = append(mods, dhcpv6.WithOption(o))
+			}
+			msg, err := dhcpv6.NewSolicit(hw, mods...)
+			if err != nil {
+				t.Fatalf("NewSolicit() err = %v", err)
+			}
+			var req dhcpv6.DHCPv6 = msg
+			if test.interfaceID != "" {
+				relay, err := dhcpv6.EncapsulateRelay(msg, dhcpv6.MessageTypeRelayForward, net.ParseIP("2001:db8::1"), net.ParseIP("fe80::1"))
+				if err != nil {
+					t.Fatalf("EncapsulateRelay() err = %v", err)
+				}
+				relay.AddOption(dhcpv6.OptInterfaceID([]byte(test.interfaceID)))
+				req = relay
+			}
+			resp, err := dhcpv6.NewAdvertiseFromSolicit(msg)
+			if err != nil {
+				t.Fatalf("NewAdvertiseFromSolicit() err = %v", err)
+			}
+			got, _ := handler6(req, resp)
+			opt := got.GetOneOption(dhcpv6.OptionCode(OPTION_V6_SZTP_REDIRECT))
+			if opt == nil {
+				t.Fatalf("handler6() did not add the redirect option")
+			}
+			if want := encodeBootstrapServerList(test.want); !bytes.Equal(opt.ToBytes(), want) {
+				t.Errorf("handler6() redirect = %q, want %q", opt.ToBytes(), want)
+			}
+		})
+	}
+}
+
+func TestRulesOnly(t *testing.T) {
+	if _, err := setup4("match:vendor-class=Arista,bootz://arista:8008"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	hw, _ := net.ParseMAC("02:00:00:00:00:01")
+	req, err := dhcpv4.NewDiscovery(hw, dhcpv4.WithRequestedOptions(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT)))
+	if err != nil {
+		t.Fatalf("NewDiscovery() err = %v", err)
+	}
+	resp, err := dhcpv4.NewReplyFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewReplyFromRequest() err = %v", err)
+	}
+	resp, _ = handler4(req, resp)
+	if resp.Options.Has(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT)) {
+		t.Errorf("handler4() added a redirect for a client matching no rule without default URLs")
+	}
+}
+
+func TestHandler4ScopeRedirect(t *testing.T) {
+	if _, err := scope.Plugin.Setup4("subnet=10.1.0.0%2F24&bootz-url=bootz%3A%2F%2Fscope%3A8008"); err != nil {
+		t.Fatalf("scope Setup4() err = %v", err)
+	}
+	defer scope.Plugin.Setup4()
+	if _, err := setup4(testArgs...); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	tests := []struct {
+		desc   string
+		giaddr net.IP
+		opts   []dhcpv4.Option
+		want   []string
+	}{
+		{desc: "relayed from scope", giaddr: net.ParseIP("10.1.0.1"), want: []string{"bootz://scope:8008"}},
+		{desc: "relayed from another subnet", giaddr: net.ParseIP("10.2.0.1"), want: []string{"bootz://default:8008"}},
+		{
+			desc:   "rule takes precedence",
+			giaddr: net.ParseIP("10.1.0.1"),
+			opts:   []dhcpv4.Option{dhcpv4.OptClientIdentifier([]byte("serial-1"))},
+			want:   []string{"bootz://serial:8008"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			hw, _ := net.ParseMAC("02:00:00:00:00:01")
+			mods := []dhcpv4.Modifier{
+				dhcpv4.WithGatewayIP(test.giaddr),
+				dhcpv4.WithRequestedOptions(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT)),
+			}
+			for _, o := range test.opts {
+				mods = append(mods, dhcpv4.WithOption(o))
+			}
+			req, err := dhcpv4.NewDiscovery(hw, mods...)
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			resp, _ = handler4(req, resp)
+			got := resp.Options.Get(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT))
+			if want := encodeBootstrapServerList(test.want); !bytes.Equal(got, want) {
+				t.Errorf("handler4() redirect = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+var legacyArgs = []string{
+	"bootz://default:8008",
+	"legacy:mac-prefix=02:00:00:00:00:02,http://10.0.0.1:8080/device.sh",
+	"legacy:vendor-class=Cisco,http://10.0.0.1:8080/cisco.py",
+	"legacy:client-id=SERIAL-3,http://10.0.0.1:8080/serial.sh",
+	"match:vendor-class=Arista,bootz://arista:8008",
+}
+
+func TestHandler4Legacy(t *testing.T) {
+	if _, err := setup4(legacyArgs...); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	tests := []struct {
+		desc         string
+		mac          string
+		opts         []dhcpv4.Option
+		wantBootFile string
+		wantRedirect []string
+	}{{
+		desc:         "bootz client",
+		mac:          "02:00:00:00:00:01",
+		wantRedirect: []string{"bootz://default:8008"},
+	}, {
+		desc:         "legacy device",
+		mac:          "02:00:00:00:00:02",
+		wantBootFile: "http://10.0.0.1:8080/device.sh",
+	}, {
+		desc:         "legacy vendor class",
+		mac:          "02:00:00:00:00:01",
+		opts:         []dhcpv4.Option{dhcpv4.OptClassIdentifier("Cisco Systems")},
+		wantBootFile: "http://10.0.0.1:8080/cisco.py",
+	}, {
+		desc:         "legacy client id with type byte",
+		mac:          "02:00:00:00:00:01",
+		opts:         []dhcpv4.Option{dhcpv4.OptClientIdentifier([]byte("\x00SERIAL-3"))},
+		wantBootFile: "http://10.0.0.1:8080/serial.sh",
+	}, {
+		desc:         "redirect vendor class",
+		mac:          "02:00:00:00:00:01",
+		opts:         []dhcpv4.Option{dhcpv4.OptClassIdentifier("Arista")},
+		wantRedirect: []string{"bootz://arista:8008"},
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			hw, _ := net.ParseMAC(test.mac)
+			mods := []dhcpv4.Modifier{dhcpv4.WithRequestedOptions(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT))}
+			for _, o := range test.opts {
+				mods = append(mods, dhcpv4.WithOption(o))
+			}
+			req, err := dhcpv4.NewDiscovery(hw, mods...)
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			resp, _ = handler4(req, resp)
+			if got := resp.BootFileNameOption(); got != test.wantBootFile {
+				t.Errorf("handler4() boot file = %q, want %q", got, test.wantBootFile)
+			}
+			wantServer := ""
+			if test.wantBootFile != "" {
+				wantServer = "10.0.0.1"
+			}
+			if got := resp.TFTPServerName(); got != wantServer {
+				t.Errorf("handler4() tftp server = %q, want %q", got, wantServer)
+			}
+			got := resp.Options.Get(dhcpv4.GenericOptionCode(OPTION_V4_SZTP_REDIRECT))
+			var want []byte
+			if test.wantRedirect != nil {
+				want = encodeBootstrapServerList(test.wantRedirect)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("handler4() redirect = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandler6Legacy(t *testing.T) {
+	if _, err := setup6(legacyArgs...); err != nil {
+		t.Fatalf("setup6() err = %v", err)
+	}
+	tests := []struct {
+		desc         string
+		mac          string
+		wantBootFile string
+	}{
+		{desc: "bootz client", mac: "02:00:00:00:00:01"},
+		{desc: "legacy device", mac: "02:00:00:00:00:02", wantBootFile: "http://10.0.0.1:8080/device.sh"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			hw, _ := net.ParseMAC(test.mac)
+			msg, err := dhcpv6.NewSolicit(hw, dhcpv6.WithRequestedOptions(dhcpv6.OptionCode(OPTION_V6_SZTP_REDIRECT)))
+			if err != nil {
+				t.Fatalf("NewSolicit() err = %v", err)
+			}
+			resp, err := dhcpv6.NewAdvertiseFromSolicit(msg)
+			if err != nil {
+				t.Fatalf("NewAdvertiseFromSolicit() err = %v", err)
+			}
+			got, _ := handler6(msg, resp)
+			m := got.(*dhcpv6.Message)
+			if bf := m.Options.BootFileURL(); bf != test.wantBootFile {
+				t.Errorf("handler6() boot file url = %q, want %q", bf, test.wantBootFile)
+			}
+			if redirect := m.GetOneOption(dhcpv6.OptionCode(OPTION_V6_SZTP_REDIRECT)); (redirect == nil) != (test.wantBootFile != "") {
+				t.Errorf("handler6() redirect = %v, want redirect only for bootz clients", redirect)
+			}
+		})
+	}
+}
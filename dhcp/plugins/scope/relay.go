@@ -0,0 +1,77 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scope
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+// AgentIDs identifies the port a client is connected to, as reported by the relay agent.
+type AgentIDs struct {
+	// CircuitID is the DHCPv4 relay agent circuit ID (option 82 sub-option 1) or the DHCPv6 interface ID (option 18).
+	CircuitID []byte
+	// RemoteID is the DHCPv4 relay agent remote ID (option 82 sub-option 2) or the DHCPv6 remote ID (option 37),
+	// without its enterprise number.
+	RemoteID []byte
+}
+
+// AgentIDs4 returns the relay agent information of a DHCPv4 request. The IDs are nil if not sent.
+func AgentIDs4(req *dhcpv4.DHCPv4) AgentIDs {
+	var ids AgentIDs
+	rai := req.RelayAgentInfo()
+	if rai == nil {
+		return ids
+	}
+	if rai.Has(dhcpv4.AgentCircuitIDSubOption) {
+		ids.CircuitID = append([]byte{}, rai.Get(dhcpv4.AgentCircuitIDSubOption)...)
+	}
+	if rai.Has(dhcpv4.AgentRemoteIDSubOption) {
+		ids.RemoteID = append([]byte{}, rai.Get(dhcpv4.AgentRemoteIDSubOption)...)
+	}
+	return ids
+}
+
+// AgentIDs6 returns the relay agent information of a DHCPv6 request, as set by the relay closest to the client. The
+// IDs are nil if not sent.
+func AgentIDs6(req dhcpv6.DHCPv6) AgentIDs {
+	var ids AgentIDs
+	// The relays are nested from the one closest to the server to the one closest to the client.
+	for req != nil && req.IsRelay() {
+		relay, ok := req.(*dhcpv6.RelayMessage)
+		if !ok {
+			break
+		}
+		if id := relay.Options.InterfaceID(); id != nil {
+			ids.CircuitID = id
+		}
+		if rid := relay.Options.RemoteID(); rid != nil {
+			ids.RemoteID = rid.RemoteID
+		}
+		req = relay.Options.RelayMessage()
+	}
+	return ids
+}
+
+// MatchAgentID returns whether the relay agent ID is want, compared either as text or hex encoded, ignoring case.
+func MatchAgentID(id []byte, want string) bool {
+	if id == nil {
+		return false
+	}
+	return strings.EqualFold(string(id), want) || strings.EqualFold(hex.EncodeToString(id), want)
+}
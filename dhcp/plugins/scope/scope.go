@@ -0,0 +1,264 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scope implements a dhcp server plugin which sets the options of relayed requests based on the subnet of the
+// relay: the gateway address (giaddr) of DHCPv4 requests and the link address of DHCPv6 relay-forward messages.
+package scope
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/coredhcp/coredhcp/handler"
+	"github.com/coredhcp/coredhcp/logger"
+	"github.com/coredhcp/coredhcp/plugins"
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+var log = logger.GetLogger("plugins/scope")
+
+var Plugin = plugins.Plugin{
+	Name:   "scope",
+	Setup4: setup4,
+	Setup6: setup6,
+}
+
+// The keys of the URL query encoded plugin arguments, one argument per scope.
+// format: subnet=10.1.0.0%2F24&router=10.1.0.1&dns=10.0.0.53&lease-time=10m&bootz-url=bootz%3A%2F%2F10.0.0.1%3A8008
+const (
+	KeySubnet    = "subnet"
+	KeyRouter    = "router"
+	KeyDNS       = "dns"
+	KeyLeaseTime = "lease-time"
+	KeyBootzURL  = "bootz-url"
+)
+
+// Scope holds the options of the clients of a relayed subnet.
+type Scope struct {
+	// Subnet is matched against the relay address of the requests.
+	Subnet netip.Prefix
+	// Router is the default gateway of the DHCPv4 clients, if set.
+	Router net.IP
+	// DNS are the DNS server addresses, if set.
+	DNS []net.IP
+	// LeaseTime is the lease time, if set.
+	LeaseTime time.Duration
+	// BootzURLs are the bootz server URLs, if set.
+	BootzURLs []string
+}
+
+var (
+	mu      sync.RWMutex
+	scopes4 []*Scope
+	scopes6 []*Scope
+)
+
+// parseScope parses a scope plugin argument.
+func parseScope(arg string) (*Scope, error) {
+	v, err := url.ParseQuery(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scope %v: %v", arg, err)
+	}
+	s := &Scope{}
+	for k := range v {
+		switch k {
+		case KeySubnet, KeyRouter, KeyDNS, KeyLeaseTime, KeyBootzURL:
+		default:
+			return nil, fmt.Errorf("invalid scope %v: unknown key %v", arg, k)
+		}
+	}
+	if s.Subnet, err = netip.ParsePrefix(v.Get(KeySubnet)); err != nil {
+		return nil, fmt.Errorf("invalid scope subnet %v", v.Get(KeySubnet))
+	}
+	s.Subnet = s.Subnet.Masked()
+	if r := v.Get(KeyRouter); r != "" {
+		if s.Router = net.ParseIP(r); s.Router == nil {
+			return nil, fmt.Errorf("invalid scope router %v", r)
+		}
+	}
+	for _, d := range v[KeyDNS] {
+		ip := net.ParseIP(d)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid scope dns %v", d)
+		}
+		s.DNS = append(s.DNS, ip)
+	}
+	if lt := v.Get(KeyLeaseTime); lt != "" {
+		if s.LeaseTime, err = time.ParseDuration(lt); err != nil || s.LeaseTime <= 0 {
+			return nil, fmt.Errorf("invalid scope lease time %v", lt)
+		}
+	}
+	for _, u := range v[KeyBootzURL] {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope bootz url %v: %v", u, err)
+		}
+		s.BootzURLs = append(s.BootzURLs, parsed.String())
+	}
+	return s, nil
+}
+
+func parseScopes(ipv6 bool, args ...string) ([]*Scope, error) {
+	var scopes []*Scope
+	for _, arg := range args {
+		s, err := parseScope(arg)
+		if err != nil {
+			return nil, err
+		}
+		if s.Subnet.Addr().Is6() != ipv6 {
+			return nil, fmt.Errorf("scope subnet %v is not of the address family of the server", s.Subnet)
+		}
+		for _, other := range scopes {
+			if other.Subnet.Overlaps(s.Subnet) {
+				return nil, fmt.Errorf("scope %v overlaps scope %v", s.Subnet, other.Subnet)
+			}
+		}
+		scopes = append(scopes, s)
+		log.Debugf("Added scope: %v, %v, %v, %v, %v", s.Subnet, s.Router, s.DNS, s.LeaseTime, s.BootzURLs)
+	}
+	return scopes, nil
+}
+
+func setup4(args ...string) (handler.Handler4, error) {
+	scopes, err := parseScopes(false, args...)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	scopes4 = scopes
+	return handler4, nil
+}
+
+func setup6(args ...string) (handler.Handler6, error) {
+	scopes, err := parseScopes(true, args...)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	scopes6 = scopes
+	return handler6, nil
+}
+
+func lookup(scopes []*Scope, a netip.Addr) *Scope {
+	for _, s := range scopes {
+		if s.Subnet.Contains(a) {
+			return s
+		}
+	}
+	return nil
+}
+
+// RelayAddr4 returns the gateway address of a relayed DHCPv4 request.
+func RelayAddr4(req *dhcpv4.DHCPv4) (netip.Addr, bool) {
+	if req.GatewayIPAddr == nil || req.GatewayIPAddr.IsUnspecified() {
+		return netip.Addr{}, false
+	}
+	a, ok := netip.AddrFromSlice(req.GatewayIPAddr)
+	return a.Unmap(), ok
+}
+
+// RelayAddr6 returns the link address of the relay closest to the client of a relayed DHCPv6 request. Relays which
+// leave the link address unspecified are skipped.
+func RelayAddr6(req dhcpv6.DHCPv6) (netip.Addr, bool) {
+	var link netip.Addr
+	for req != nil && req.IsRelay() {
+		relay, ok := req.(*dhcpv6.RelayMessage)
+		if !ok {
+			break
+		}
+		if a, ok := netip.AddrFromSlice(relay.LinkAddr); ok && !a.IsUnspecified() {
+			link = a
+		}
+		req = relay.Options.RelayMessage()
+	}
+	return link, link.IsValid()
+}
+
+// Lookup4 returns the scope of a DHCPv4 request, or nil if it is not relayed from a scope subnet.
+func Lookup4(req *dhcpv4.DHCPv4) *Scope {
+	a, ok := RelayAddr4(req)
+	if !ok {
+		return nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return lookup(scopes4, a)
+}
+
+// Lookup6 returns the scope of a DHCPv6 request, or nil if it is not relayed from a scope subnet.
+func Lookup6(req dhcpv6.DHCPv6) *Scope {
+	a, ok := RelayAddr6(req)
+	if !ok {
+		return nil
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return lookup(scopes6, a)
+}
+
+// Remote returns whether the prefix overlaps the subnet of a scope, meaning that its addresses are only for relayed
+// clients.
+func Remote(p netip.Prefix) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range append(append([]*Scope{}, scopes4...), scopes6...) {
+		if s.Subnet.Overlaps(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func handler4(req, resp *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, bool) {
+	s := Lookup4(req)
+	if s == nil {
+		return resp, false
+	}
+	resp.Options.Update(dhcpv4.OptSubnetMask(net.CIDRMask(s.Subnet.Bits(), 32)))
+	if s.Router != nil {
+		resp.Options.Update(dhcpv4.OptRouter(s.Router))
+	}
+	if len(s.DNS) > 0 && req.IsOptionRequested(dhcpv4.OptionDomainNameServer) {
+		resp.Options.Update(dhcpv4.OptDNS(s.DNS...))
+	}
+	if s.LeaseTime > 0 {
+		resp.Options.Update(dhcpv4.OptIPAddressLeaseTime(s.LeaseTime))
+	}
+	log.Debugf("Applied scope %v: %v", s.Subnet, resp.Summary())
+	return resp, false
+}
+
+func handler6(req, resp dhcpv6.DHCPv6) (dhcpv6.DHCPv6, bool) {
+	s := Lookup6(req)
+	if s == nil {
+		return resp, false
+	}
+	decap, err := req.GetInnerMessage()
+	if err != nil {
+		log.Errorf("Could not decapsulate request: %v", err)
+		return nil, true
+	}
+	if len(s.DNS) > 0 && decap.IsOptionRequested(dhcpv6.OptionDNSRecursiveNameServer) {
+		resp.UpdateOption(dhcpv6.OptDNS(s.DNS...))
+	}
+	log.Debugf("Applied scope %v: %v", s.Subnet, resp.Summary())
+	return resp, false
+}
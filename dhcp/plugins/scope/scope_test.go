@@ -0,0 +1,141 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scope
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		desc    string
+		ipv6    bool
+		args    []string
+		wantErr bool
+	}{
+		{desc: "full", args: []string{"subnet=10.1.0.0%2F24&router=10.1.0.1&dns=10.0.0.53&dns=10.0.0.54&lease-time=10m&bootz-url=bootz%3A%2F%2F10.0.0.1%3A8008"}},
+		{desc: "ipv6", ipv6: true, args: []string{"subnet=2001%3Adb8%3A1%3A%3A%2F64&dns=2001%3Adb8%3A%3A53"}},
+		{desc: "two scopes", args: []string{"subnet=10.1.0.0%2F24", "subnet=10.2.0.0%2F24"}},
+		{desc: "overlapping scopes", args: []string{"subnet=10.1.0.0%2F16", "subnet=10.1.2.0%2F24"}, wantErr: true},
+		{desc: "no subnet", args: []string{"router=10.1.0.1"}, wantErr: true},
+		{desc: "wrong family", args: []string{"subnet=2001%3Adb8%3A1%3A%3A%2F64"}, wantErr: true},
+		{desc: "invalid router", args: []string{"subnet=10.1.0.0%2F24&router=gw"}, wantErr: true},
+		{desc: "invalid dns", args: []string{"subnet=10.1.0.0%2F24&dns=dns"}, wantErr: true},
+		{desc: "invalid lease time", args: []string{"subnet=10.1.0.0%2F24&lease-time=0s"}, wantErr: true},
+		{desc: "unknown key", args: []string{"subnet=10.1.0.0%2F24&domain=example.com"}, wantErr: true},
+	}
+	for _, test := range tests {
+		if _, err := parseScopes(test.ipv6, test.args...); (err != nil) != test.wantErr {
+			t.Errorf("%s: parseScopes() err = %v, want error %v", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+func TestHandler4(t *testing.T) {
+	if _, err := setup4("subnet=10.1.0.0%2F24&router=10.1.0.1&dns=10.0.0.53&lease-time=10m"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	hw, _ := net.ParseMAC("02:00:00:00:00:01")
+	tests := []struct {
+		desc      string
+		giaddr    net.IP
+		wantScope bool
+	}{
+		{desc: "direct", giaddr: net.IPv4zero},
+		{desc: "relayed from scope", giaddr: net.ParseIP("10.1.0.1"), wantScope: true},
+		{desc: "relayed from another subnet", giaddr: net.ParseIP("10.2.0.1")},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			req, err := dhcpv4.NewDiscovery(hw,
+				dhcpv4.WithGatewayIP(test.giaddr),
+				dhcpv4.WithRequestedOptions(dhcpv4.OptionDomainNameServer))
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			resp, _ = handler4(req, resp)
+			if got := Lookup4(req) != nil; got != test.wantScope {
+				t.Fatalf("Lookup4() found scope %v, want %v", got, test.wantScope)
+			}
+			if !test.wantScope {
+				if resp.Options.Has(dhcpv4.OptionRouter) || resp.Options.Has(dhcpv4.OptionDomainNameServer) {
+					t.Errorf("handler4() set options for a request outside the scopes: %v", resp.Summary())
+				}
+				return
+			}
+			if got := resp.Router(); len(got) != 1 || !got[0].Equal(net.ParseIP("10.1.0.1")) {
+				t.Errorf("handler4() router = %v, want 10.1.0.1", got)
+			}
+			if got := resp.DNS(); len(got) != 1 || !got[0].Equal(net.ParseIP("10.0.0.53")) {
+				t.Errorf("handler4() dns = %v, want 10.0.0.53", got)
+			}
+			if got := resp.IPAddressLeaseTime(0); got != 10*time.Minute {
+				t.Errorf("handler4() lease time = %v, want %v", got, 10*time.Minute)
+			}
+			if got := resp.SubnetMask(); got.String() != net.CIDRMask(24, 32).String() {
+				t.Errorf("handler4() subnet mask = %v, want /24", got)
+			}
+		})
+	}
+}
+
+func TestHandler6(t *testing.T) {
+	if _, err := setup6("subnet=2001%3Adb8%3A1%3A%3A%2F64&dns=2001%3Adb8%3A%3A53"); err != nil {
+		t.Fatalf("setup6() err = %v", err)
+	}
+	hw, _ := net.ParseMAC("02:00:00:00:00:01")
+	msg, err := dhcpv6.NewSolicit(hw, dhcpv6.WithRequestedOptions(dhcpv6.OptionDNSRecursiveNameServer))
+	if err != nil {
+		t.Fatalf("NewSolicit() err = %v", err)
+	}
+	// The relay closest to the client sets the link address; the second relay leaves it unspecified.
+	inner, err := dhcpv6.EncapsulateRelay(msg, dhcpv6.MessageTypeRelayForward, net.ParseIP("2001:db8:1::1"), net.ParseIP("fe80::1"))
+	if err != nil {
+		t.Fatalf("EncapsulateRelay() err = %v", err)
+	}
+	outer, err := dhcpv6.EncapsulateRelay(inner, dhcpv6.MessageTypeRelayForward, net.IPv6unspecified, net.ParseIP("2001:db8:1::1"))
+	if err != nil {
+		t.Fatalf("EncapsulateRelay() err = %v", err)
+	}
+	if got, ok := RelayAddr6(outer); !ok || got != netip.MustParseAddr("2001:db8:1::1") {
+		t.Errorf("RelayAddr6() = %v, %v, want 2001:db8:1::1", got, ok)
+	}
+	if _, ok := RelayAddr6(msg); ok {
+		t.Errorf("RelayAddr6() of a direct request found a relay")
+	}
+
+	resp, err := dhcpv6.NewAdvertiseFromSolicit(msg)
+	if err != nil {
+		t.Fatalf("NewAdvertiseFromSolicit() err = %v", err)
+	}
+	got, _ := handler6(outer, resp)
+	dns := got.(*dhcpv6.Message).Options.DNS()
+	if len(dns) != 1 || !dns[0].Equal(net.ParseIP("2001:db8::53")) {
+		t.Errorf("handler6() dns = %v, want 2001:db8::53", dns)
+	}
+	if !Remote(netip.MustParsePrefix("2001:db8:1::/120")) || Remote(netip.MustParsePrefix("2001:db8:2::/64")) {
+		t.Errorf("Remote() does not match the scope subnets")
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slease
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dbPrefix marks the plugin argument which sets the path of the lease database.
+const dbPrefix = "db:"
+
+// saveDelay is how long the changes of the leases are batched before the database is written.
+var saveDelay = time.Second
+
+// Lease is an address handed out by the plugin.
+type Lease struct {
+	// Key is the static record or pool allocation key of the client: its MAC address or client identifier.
+	Key string `json:"key"`
+	// ClientID is the DHCPv4 client identifier or the DHCPv6 DUID of the client, if it sent one.
+	ClientID string `json:"client_id,omitempty"`
+	// MAC is the hardware address of the client, if known.
+	MAC string `json:"mac,omitempty"`
+	// IP is the leased address.
+	IP string `json:"ip"`
+	// Expiry is when the lease expires unless the client renews it.
+	Expiry time.Time `json:"expiry"`
+	// LastSeen is when the client last sent a request.
+	LastSeen time.Time `json:"last_seen"`
+	// BootzRequested is whether the client requested the bootz server option in its last request.
+	BootzRequested bool `json:"bootz_requested"`
+}
+
+func (l *Lease) isIPv6() bool {
+	return strings.Contains(l.IP, ":")
+}
+
+// leaseStore keeps the leases, keyed by address family and client key, and persists them to disk if a path is set.
+// Callers must hold muRw, except for flush.
+type leaseStore struct {
+	path   string
+	leases map[string]*Lease
+	now    func() time.Time
+	// pending is the scheduled write of the database, nil if the database is up to date.
+	pending *time.Timer
+}
+
+var leases = &leaseStore{leases: map[string]*Lease{}, now: time.Now}
+
+// muSave serializes the writes of the database, which happen without holding muRw.
+var muSave sync.Mutex
+
+func storeKey(ipv6 bool, key string) string {
+	if ipv6 {
+		return "6/" + key
+	}
+	return "4/" + key
+}
+
+// open loads the leases from the database at path. Expired leases are dropped.
+func (s *leaseStore) open(path string) error {
+	if s.path == path {
+		// The database is shared by the DHCPv4 and DHCPv6 servers.
+		return nil
+	}
+	if s.path != "" {
+		return fmt.Errorf("lease database already set to %v", s.path)
+	}
+	s.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read lease database: %v", err)
+	}
+	var stored []*Lease
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return fmt.Errorf("unable to parse lease database %v: %v", path, err)
+	}
+	now := s.now()
+	for _, l := range stored {
+		if now.Before(l.Expiry) {
+			s.leases[storeKey(l.isIPv6(), l.Key)] = l
+		}
+	}
+	log.Infof("Loaded %d leases from %v", len(s.leases), path)
+	return nil
+}
+
+// update records the lease and schedules a write of the database.
+func (s *leaseStore) update(l *Lease) {
+	s.leases[storeKey(l.isIPv6(), l.Key)] = l
+	s.save()
+}
+
+// get returns the unexpired lease of the client.
+func (s *leaseStore) get(ipv6 bool, key string) (*Lease, bool) {
+	l, ok := s.leases[storeKey(ipv6, key)]
+	if !ok || !s.now().Before(l.Expiry) {
+		return nil, false
+	}
+	return l, true
+}
+
+// find returns the unexpired lease of the client with the MAC address or client identifier, ignoring case.
+func (s *leaseStore) find(id string) (*Lease, bool) {
+	for _, l := range s.list() {
+		if strings.EqualFold(l.MAC, id) || strings.EqualFold(l.ClientID, id) {
+			return s.leases[storeKey(l.isIPv6(), l.Key)], true
+		}
+	}
+	return nil, false
+}
+
+// expire drops the expired leases.
+func (s *leaseStore) expire() {
+	now := s.now()
+	for k, l := range s.leases {
+		if !now.Before(l.Expiry) {
+			delete(s.leases, k)
+		}
+	}
+}
+
+// list returns copies of the unexpired leases, ordered by address.
+func (s *leaseStore) list() []Lease {
+	s.expire()
+	out := make([]Lease, 0, len(s.leases))
+	for _, l := range s.leases {
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, errA := netip.ParseAddr(out[i].IP)
+		b, errB := netip.ParseAddr(out[j].IP)
+		if errA != nil || errB != nil {
+			return out[i].IP < out[j].IP
+		}
+		return a.Less(b)
+	})
+	return out
+}
+
+// clear drops all the leases.
+func (s *leaseStore) clear() {
+	s.leases = map[string]*Lease{}
+	s.save()
+}
+
+// save schedules a write of the database, so that the changes within saveDelay are written at once and the DHCP
+// requests are not held up by disk I/O.
+func (s *leaseStore) save() {
+	if s.path == "" || s.pending != nil {
+		return
+	}
+	s.pending = time.AfterFunc(saveDelay, s.flush)
+}
+
+// flush atomically writes the unexpired leases to the database if they changed since the last write. Callers must
+// not hold muRw.
+func (s *leaseStore) flush() {
+	muSave.Lock()
+	defer muSave.Unlock()
+	muRw.Lock()
+	if s.pending == nil {
+		muRw.Unlock()
+		return
+	}
+	s.pending.Stop()
+	s.pending = nil
+	path := s.path
+	b, err := json.MarshalIndent(s.list(), "", "  ")
+	muRw.Unlock()
+	if err != nil {
+		log.Errorf("Unable to marshal leases: %v", err)
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".leases-*")
+	if err != nil {
+		log.Errorf("Unable to save leases: %v", err)
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		log.Errorf("Unable to save leases: %v", err)
+		return
+	}
+	if err := f.Close(); err != nil {
+		log.Errorf("Unable to save leases: %v", err)
+		return
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		log.Errorf("Unable to save leases: %v", err)
+	}
+}
+
+// restore reserves the pool addresses of the stored leases so that clients keep them across restarts.
+func (s *leaseStore) restore(al *allocator, ipv6 bool) {
+	for _, l := range s.leases {
+		if l.isIPv6() != ipv6 {
+			continue
+		}
+		a, err := netip.ParseAddr(l.IP)
+		if err != nil {
+			continue
+		}
+		for _, p := range al.pools {
+			if p.contains(a) {
+				pl := &poolLease{key: l.Key, addr: a, pool: p, expiry: l.Expiry}
+				al.byAddr[a] = pl
+				al.byClient[l.Key] = pl
+				break
+			}
+		}
+	}
+}
+
+// Flush writes the pending changes of the leases to the database.
+func Flush() {
+	muRw.Lock()
+	s := leases
+	muRw.Unlock()
+	s.flush()
+}
+
+// Leases returns the current leases, ordered by address.
+func Leases() []Lease {
+	muRw.Lock()
+	defer muRw.Unlock()
+	return leases.list()
+}
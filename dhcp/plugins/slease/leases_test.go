@@ -0,0 +1,230 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slease
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	plbootz "github.com/openconfig/bootz/dhcp/plugins/bootz"
+)
+
+func discover(t *testing.T, mac string, opts ...dhcpv4.Modifier) *dhcpv4.DHCPv4 {
+	t.Helper()
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		t.Fatalf("ParseMAC(%q) err = %v", mac, err)
+	}
+	req, err := dhcpv4.NewDiscovery(hw, opts...)
+	if err != nil {
+		t.Fatalf("NewDiscovery() err = %v", err)
+	}
+	resp, err := dhcpv4.NewReplyFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewReplyFromRequest() err = %v", err)
+	}
+	resp, _ = handler4(req, resp)
+	return resp
+}
+
+func TestLeasePersistence(t *testing.T) {
+	reset(t)
+	db := "db:" + filepath.Join(t.TempDir(), "leases.json")
+	args := []string{db, "00:00:00:00:00:01,10.0.0.20/24,10.0.0.1", "pool:10.0.0.0/24,10.0.0.1,10m,"}
+	if _, err := setup4(args...); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	discover(t, "00:00:00:00:00:01", dhcpv4.WithRequestedOptions(dhcpv4.GenericOptionCode(plbootz.OPTION_V4_SZTP_REDIRECT)))
+	pooled := discover(t, "00:00:00:00:00:02", dhcpv4.WithOption(dhcpv4.OptClientIdentifier([]byte("SERIAL-2"))))
+
+	want := map[string]Lease{
+		"00:00:00:00:00:01": {Key: "00:00:00:00:00:01", MAC: "00:00:00:00:00:01", IP: "10.0.0.20", BootzRequested: true},
+		"serial-2":          {Key: "serial-2", ClientID: "SERIAL-2", MAC: "00:00:00:00:00:02", IP: pooled.YourIPAddr.String()},
+	}
+	check := func(desc string) {
+		t.Helper()
+		got := Leases()
+		if len(got) != len(want) {
+			t.Fatalf("%s: Leases() = %+v, want %d leases", desc, got, len(want))
+		}
+		for _, l := range got {
+			w, ok := want[l.Key]
+			if !ok {
+				t.Errorf("%s: unexpected lease %+v", desc, l)
+				continue
+			}
+			if l.ClientID != w.ClientID || l.MAC != w.MAC || l.IP != w.IP || l.BootzRequested != w.BootzRequested {
+				t.Errorf("%s: lease = %+v, want %+v", desc, l, w)
+			}
+			if l.LastSeen.IsZero() || !l.Expiry.After(l.LastSeen) {
+				t.Errorf("%s: lease %v has last seen %v and expiry %v", desc, l.Key, l.LastSeen, l.Expiry)
+			}
+		}
+	}
+	check("before restart")
+
+	// The leases, and the pool addresses of the clients, are restored when the server restarts.
+	Flush()
+	reset(t)
+	if _, err := setup4(args...); err != nil {
+		t.Fatalf("setup4() after restart err = %v", err)
+	}
+	check("after restart")
+	if got := AssignedIP("SERIAL-2"); got != pooled.YourIPAddr.String() {
+		t.Errorf("AssignedIP() after restart = %v, want %v", got, pooled.YourIPAddr)
+	}
+	l, ok := ipv4Pools.byAddr[netip.MustParseAddr(pooled.YourIPAddr.String())]
+	if !ok || l.key != "serial-2" {
+		t.Errorf("pool address %v was not reserved for serial-2 after restart", pooled.YourIPAddr)
+	}
+
+	// Expired leases are dropped, and not reloaded.
+	later := func() time.Time { return time.Now().Add(2 * time.Hour) }
+	muRw.Lock()
+	leases.now = later
+	muRw.Unlock()
+	if got := Leases(); len(got) != 0 {
+		t.Errorf("Leases() after expiry = %+v, want none", got)
+	}
+	if got := AssignedIP("00:00:00:00:00:01"); got != "" {
+		t.Errorf("AssignedIP() after expiry = %v, want none", got)
+	}
+	reset(t)
+	leases.now = later
+	if _, err := setup4(args...); err != nil {
+		t.Fatalf("setup4() after expiry err = %v", err)
+	}
+	if got := Leases(); len(got) != 0 {
+		t.Errorf("Leases() reloaded after expiry = %+v, want none", got)
+	}
+}
+
+func TestLeaseSaveBatched(t *testing.T) {
+	reset(t)
+	delay := saveDelay
+	saveDelay = time.Hour
+	t.Cleanup(func() { saveDelay = delay })
+	path := filepath.Join(t.TempDir(), "leases.json")
+	if _, err := setup4("db:"+path, "pool:10.0.0.0/24,10.0.0.1,10m,"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	discover(t, "00:00:00:00:00:01")
+	discover(t, "00:00:00:00:00:02")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("lease database written before the save delay: err = %v", err)
+	}
+	Flush()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read lease database: %v", err)
+	}
+	var stored []*Lease
+	if err := json.Unmarshal(b, &stored); err != nil || len(stored) != 2 {
+		t.Errorf("lease database = %s, want 2 leases", b)
+	}
+}
+
+func TestLeaseDatabaseShared(t *testing.T) {
+	reset(t)
+	dir := t.TempDir()
+	db := "db:" + filepath.Join(dir, "leases.json")
+	if _, err := setup4(db, "00:00:00:00:00:01,10.0.0.20/24,10.0.0.1"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	if _, err := setup6(db, "00:00:00:00:00:01,2001:db8::20/64"); err != nil {
+		t.Fatalf("setup6() with the same database err = %v", err)
+	}
+	if _, err := setup6("db:" + filepath.Join(dir, "other.json")); err == nil {
+		t.Errorf("setup6() with a different database err = nil, want error")
+	}
+
+	discover(t, "00:00:00:00:00:01")
+	mac, _ := net.ParseMAC("00:00:00:00:00:01")
+	req, err := dhcpv6.NewSolicit(mac, dhcpv6.WithRequestedOptions(dhcpv6.OptionCode(plbootz.OPTION_V6_SZTP_REDIRECT)))
+	if err != nil {
+		t.Fatalf("NewSolicit() err = %v", err)
+	}
+	resp, err := dhcpv6.NewAdvertiseFromSolicit(req)
+	if err != nil {
+		t.Fatalf("NewAdvertiseFromSolicit() err = %v", err)
+	}
+	handler6(req, resp)
+
+	got := Leases()
+	if len(got) != 2 || got[0].IP != "10.0.0.20" || got[1].IP != "2001:db8::20" {
+		t.Fatalf("Leases() = %+v, want the ipv4 and ipv6 leases", got)
+	}
+	if got[0].BootzRequested || !got[1].BootzRequested {
+		t.Errorf("Leases() bootz requested = %v, %v, want false, true", got[0].BootzRequested, got[1].BootzRequested)
+	}
+	if got[1].ClientID == "" {
+		t.Errorf("ipv6 lease has no client id")
+	}
+}
+
+func TestReload(t *testing.T) {
+	reset(t)
+	if _, err := setup4("00:00:00:00:00:01,10.0.0.20/24,10.0.0.1", "pool:10.0.0.0/24,10.0.0.1,10m,"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	pooled := discover(t, "00:00:00:00:00:02")
+
+	// Reloading replaces the records, and keeps the pool addresses of the clients.
+	if _, err := setup4("00:00:00:00:00:03,10.0.0.30/24,10.0.0.1", "pool:10.0.0.0/24,10.0.0.1,10m,"); err != nil {
+		t.Fatalf("setup4() reload err = %v", err)
+	}
+	if got := discover(t, "00:00:00:00:00:03").YourIPAddr.String(); got != "10.0.0.30" {
+		t.Errorf("added record address = %v, want 10.0.0.30", got)
+	}
+	if got := discover(t, "00:00:00:00:00:01").YourIPAddr.String(); got == "10.0.0.20" {
+		t.Errorf("removed record still assigned %v", got)
+	}
+	if got := discover(t, "00:00:00:00:00:02").YourIPAddr; !got.Equal(pooled.YourIPAddr) {
+		t.Errorf("pool address after reload = %v, want %v", got, pooled.YourIPAddr)
+	}
+
+	// A failed reload keeps the previous records.
+	if _, err := setup4("00:00:00:00:00:04,10.0.0.40/24"); err == nil {
+		t.Fatalf("setup4() with an invalid record err = nil, want error")
+	}
+	if got := discover(t, "00:00:00:00:00:03").YourIPAddr.String(); got != "10.0.0.30" {
+		t.Errorf("record address after failed reload = %v, want 10.0.0.30", got)
+	}
+}
+
+func TestSharedRecord(t *testing.T) {
+	reset(t)
+	if _, err := setup4("00:00:00:00:00:01;00:00:00:00:00:02,10.0.0.20/24,10.0.0.1"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	for _, mac := range []string{"00:00:00:00:00:01", "00:00:00:00:00:02"} {
+		if got := discover(t, mac).YourIPAddr.String(); got != "10.0.0.20" {
+			t.Errorf("address of %v = %v, want 10.0.0.20", mac, got)
+		}
+	}
+	// Separate records must not share an address.
+	if _, err := setup4("00:00:00:00:00:01,10.0.0.20/24,10.0.0.1", "00:00:00:00:00:02,10.0.0.20/24,10.0.0.1"); err == nil {
+		t.Errorf("setup4() with two records of the same address err = nil, want error")
+	}
+}
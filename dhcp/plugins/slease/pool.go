@@ -0,0 +1,311 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slease
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
+)
+
+const (
+	// poolPrefix marks the plugin arguments which define a pool rather than a static record.
+	poolPrefix = "pool:"
+	// maxProbes bounds the number of addresses tried when the preferred address of a client is taken.
+	maxProbes = 4096
+)
+
+// ipRange is an inclusive range of addresses.
+type ipRange struct {
+	first, last netip.Addr
+}
+
+func (r ipRange) contains(a netip.Addr) bool {
+	return r.first.Compare(a) <= 0 && a.Compare(r.last) <= 0
+}
+
+// pool is a range of addresses allocated dynamically to the machines without a static record.
+type pool struct {
+	prefix    netip.Prefix
+	first     netip.Addr
+	size      *big.Int
+	gateway   net.IP
+	leaseTime time.Duration
+	excluded  []ipRange
+}
+
+// lease returns the lease time of the pool, or the fallback, the lease time of the scope or the server, if the pool
+// does not set one.
+func (p *pool) lease(fallback time.Duration) time.Duration {
+	if p.leaseTime > 0 {
+		return p.leaseTime
+	}
+	return fallback
+}
+
+// parsePool parses a pool plugin argument.
+// format: pool:subnet/len,gateway,leasetime,exclusion;exclusion... where an exclusion is an address or a first-last range.
+func parsePool(arg string) (*pool, error) {
+	spec, ok := strings.CutPrefix(arg, poolPrefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid pool %v", arg)
+	}
+	parts := strings.Split(spec, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid pool %v", arg)
+	}
+	prefix, err := netip.ParsePrefix(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool subnet %v", parts[0])
+	}
+	p := &pool{prefix: prefix.Masked()}
+	if parts[1] != "" {
+		if p.gateway = net.ParseIP(parts[1]); p.gateway == nil {
+			return nil, fmt.Errorf("invalid pool gateway %v", parts[1])
+		}
+	}
+	if parts[2] != "" {
+		if p.leaseTime, err = time.ParseDuration(parts[2]); err != nil || p.leaseTime <= 0 {
+			return nil, fmt.Errorf("invalid pool lease time %v", parts[2])
+		}
+	}
+	if parts[3] != "" {
+		for _, e := range strings.Split(parts[3], ";") {
+			r, err := parseRange(e)
+			if err != nil {
+				return nil, err
+			}
+			p.excluded = append(p.excluded, r)
+		}
+	}
+
+	// The first and last addresses of an IPv4 subnet are the network and broadcast addresses.
+	// The first address of an IPv6 subnet is the subnet-router anycast address.
+	first := p.prefix.Addr()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(first.BitLen()-p.prefix.Bits()))
+	switch {
+	case first.Is4() && size.Cmp(big.NewInt(4)) >= 0:
+		first = first.Next()
+		size.Sub(size, big.NewInt(2))
+	case first.Is6() && size.Cmp(big.NewInt(2)) >= 0:
+		first = first.Next()
+		size.Sub(size, big.NewInt(1))
+	}
+	p.first, p.size = first, size
+	if gw, ok := netip.AddrFromSlice(p.gateway); ok {
+		gw = gw.Unmap()
+		if p.prefix.Contains(gw) {
+			p.exclude(gw)
+		}
+	}
+	return p, nil
+}
+
+func parseRange(s string) (ipRange, error) {
+	firstStr, lastStr, isRange := strings.Cut(s, "-")
+	first, err := netip.ParseAddr(firstStr)
+	if err != nil {
+		return ipRange{}, fmt.Errorf("invalid pool exclusion %v", s)
+	}
+	last := first
+	if isRange {
+		if last, err = netip.ParseAddr(lastStr); err != nil || last.Less(first) || last.Is4() != first.Is4() {
+			return ipRange{}, fmt.Errorf("invalid pool exclusion %v", s)
+		}
+	}
+	return ipRange{first: first, last: last}, nil
+}
+
+// exclude removes the address from the pool.
+func (p *pool) exclude(a netip.Addr) {
+	p.excluded = append(p.excluded, ipRange{first: a, last: a})
+}
+
+func (p *pool) isExcluded(a netip.Addr) bool {
+	for _, r := range p.excluded {
+		if r.contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns whether the address belongs to the pool and is not excluded.
+func (p *pool) contains(a netip.Addr) bool {
+	if !p.prefix.Contains(a) || a.Less(p.first) || p.isExcluded(a) {
+		return false
+	}
+	offset := new(big.Int).Sub(addrToInt(a), addrToInt(p.first))
+	return offset.Cmp(p.size) < 0
+}
+
+// allocate returns the address of the client. The preferred address is derived from a hash of the client key so that a
+// client gets the same address across restarts; if it is taken, the following addresses are tried.
+func (p *pool) allocate(key string, taken func(netip.Addr) bool) (netip.Addr, bool) {
+	if p.size.Sign() <= 0 {
+		return netip.Addr{}, false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	offset := new(big.Int).Mod(new(big.Int).SetUint64(h.Sum64()), p.size)
+	probes := int64(maxProbes)
+	if p.size.IsInt64() && p.size.Int64() < probes {
+		probes = p.size.Int64()
+	}
+	base := addrToInt(p.first)
+	for i := int64(0); i < probes; i++ {
+		a := intToAddr(new(big.Int).Add(base, offset), p.first.Is4())
+		if !p.isExcluded(a) && !taken(a) {
+			return a, true
+		}
+		offset.Add(offset, big.NewInt(1))
+		if offset.Cmp(p.size) >= 0 {
+			offset.SetInt64(0)
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func addrToInt(a netip.Addr) *big.Int {
+	return new(big.Int).SetBytes(a.AsSlice())
+}
+
+func intToAddr(i *big.Int, is4 bool) netip.Addr {
+	if is4 {
+		var b [4]byte
+		i.FillBytes(b[:])
+		return netip.AddrFrom4(b)
+	}
+	var b [16]byte
+	i.FillBytes(b[:])
+	return netip.AddrFrom16(b)
+}
+
+// poolLease is an address allocated from a pool.
+type poolLease struct {
+	key    string
+	addr   netip.Addr
+	pool   *pool
+	expiry time.Time
+}
+
+// allocator allocates addresses from pools while avoiding the static records.
+type allocator struct {
+	pools    []*pool
+	static   map[netip.Addr]string
+	byAddr   map[netip.Addr]*poolLease
+	byClient map[string]*poolLease
+	now      func() time.Time
+}
+
+func newAllocator() *allocator {
+	return &allocator{
+		static:   map[netip.Addr]string{},
+		byAddr:   map[netip.Addr]*poolLease{},
+		byClient: map[string]*poolLease{},
+		now:      time.Now,
+	}
+}
+
+// addStatic reserves the address of a static record, whose key lists the keys of its machines. It is an error for two
+// static records to use the same address.
+func (al *allocator) addStatic(key string, ip net.IP) error {
+	a, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return fmt.Errorf("invalid ip address %v for %v", ip, key)
+	}
+	a = a.Unmap()
+	if other, ok := al.static[a]; ok && other != key {
+		return fmt.Errorf("ip address %v assigned to both %v and %v", a, other, key)
+	}
+	al.static[a] = key
+	return nil
+}
+
+// addPool adds a pool. It is an error for pools to overlap.
+func (al *allocator) addPool(p *pool) error {
+	for _, other := range al.pools {
+		if other.prefix.Overlaps(p.prefix) {
+			return fmt.Errorf("pool %v overlaps pool %v", p.prefix, other.prefix)
+		}
+	}
+	al.pools = append(al.pools, p)
+	return nil
+}
+
+// checkConflicts excludes the addresses of static records from the pools, and logs them.
+func (al *allocator) checkConflicts() {
+	for a, key := range al.static {
+		for _, p := range al.pools {
+			if p.contains(a) {
+				log.Warningf("Static record %v uses address %v of pool %v, excluding it from the pool", key, a, p.prefix)
+				p.exclude(a)
+			}
+		}
+	}
+}
+
+// allocate returns the lease of the client, allocating an address from the first pool with a free one if necessary.
+// Relayed clients, whose relay address link is valid, get addresses from the pools containing the relay address; the
+// other clients from the pools which are not the subnet of a relay scope.
+// The leases last the lease time of their pool, or leaseTime if the pool does not set one.
+func (al *allocator) allocate(key string, link netip.Addr, leaseTime time.Duration) (*poolLease, bool) {
+	now := al.now()
+	eligible := func(p *pool) bool {
+		if link.IsValid() {
+			return p.prefix.Contains(link)
+		}
+		return !scope.Remote(p.prefix)
+	}
+	if l, ok := al.byClient[key]; ok && l.pool.contains(l.addr) && eligible(l.pool) {
+		l.expiry = now.Add(l.pool.lease(leaseTime))
+		return l, true
+	}
+	taken := func(a netip.Addr) bool {
+		if _, ok := al.static[a]; ok {
+			return true
+		}
+		l, ok := al.byAddr[a]
+		return ok && l.key != key && now.Before(l.expiry)
+	}
+	for _, p := range al.pools {
+		if !eligible(p) {
+			continue
+		}
+		a, ok := p.allocate(key, taken)
+		if !ok {
+			continue
+		}
+		if old, ok := al.byAddr[a]; ok {
+			// The previous lease of the address has expired.
+			delete(al.byClient, old.key)
+		}
+		if old, ok := al.byClient[key]; ok && old.addr != a {
+			// The client moved to another subnet.
+			delete(al.byAddr, old.addr)
+		}
+		l := &poolLease{key: key, addr: a, pool: p, expiry: now.Add(p.lease(leaseTime))}
+		al.byAddr[a] = l
+		al.byClient[key] = l
+		return l, true
+	}
+	return nil, false
+}
@@ -0,0 +1,319 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slease
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
+)
+
+// reset clears the plugin state shared between tests.
+func reset(t *testing.T) {
+	t.Helper()
+	ipv4Records = map[string]*ipv4Entry{}
+	ipv6Records = map[string]net.IP{}
+	ipv4RelayRecords, ipv6RelayRecords = nil, nil
+	ipv4Pools = newAllocator()
+	ipv6Pools = newAllocator()
+	leases = &leaseStore{leases: map[string]*Lease{}, now: time.Now}
+}
+
+func TestParsePool(t *testing.T) {
+	tests := []struct {
+		arg     string
+		wantErr bool
+	}{
+		{arg: "pool:10.0.0.0/24,10.0.0.1,30m,10.0.0.2-10.0.0.10;10.0.0.99"},
+		{arg: "pool:10.0.0.0/24,,,"},
+		{arg: "pool:2001:db8::/64,,1h,2001:db8::1-2001:db8::ff"},
+		{arg: "pool:10.0.0.0/24,10.0.0.1,30m", wantErr: true},
+		{arg: "pool:10.0.0.0,10.0.0.1,30m,", wantErr: true},
+		{arg: "pool:10.0.0.0/24,gateway,30m,", wantErr: true},
+		{arg: "pool:10.0.0.0/24,10.0.0.1,-1s,", wantErr: true},
+		{arg: "pool:10.0.0.0/24,10.0.0.1,30m,10.0.0.10-10.0.0.2", wantErr: true},
+		{arg: "pool:10.0.0.0/24,10.0.0.1,30m,10.0.0.2-2001:db8::1", wantErr: true},
+		{arg: "10.0.0.0/24,10.0.0.1,30m,", wantErr: true},
+	}
+	for _, test := range tests {
+		if _, err := parsePool(test.arg); (err != nil) != test.wantErr {
+			t.Errorf("parsePool(%q) err = %v, want error %v", test.arg, err, test.wantErr)
+		}
+	}
+}
+
+func mustPool(t *testing.T, arg string) *pool {
+	t.Helper()
+	p, err := parsePool(arg)
+	if err != nil {
+		t.Fatalf("parsePool(%q) err = %v", arg, err)
+	}
+	return p
+}
+
+func TestAllocate(t *testing.T) {
+	al := newAllocator()
+	p := mustPool(t, "pool:10.0.0.0/29,10.0.0.1,1h,10.0.0.2")
+	if err := al.addPool(p); err != nil {
+		t.Fatalf("addPool() err = %v", err)
+	}
+	if err := al.addStatic("static", net.ParseIP("10.0.0.3")); err != nil {
+		t.Fatalf("addStatic() err = %v", err)
+	}
+	al.checkConflicts()
+
+	// 10.0.0.1 is the gateway, 10.0.0.2 is excluded and 10.0.0.3 is static, leaving 10.0.0.4-10.0.0.6.
+	want := map[netip.Addr]bool{
+		netip.MustParseAddr("10.0.0.4"): true,
+		netip.MustParseAddr("10.0.0.5"): true,
+		netip.MustParseAddr("10.0.0.6"): true,
+	}
+	got := map[string]netip.Addr{}
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("client%d", i)
+		l, ok := al.allocate(key, netip.Addr{}, time.Hour)
+		if !ok {
+			t.Fatalf("allocate(%q) failed", key)
+		}
+		if !want[l.addr] {
+			t.Errorf("allocate(%q) got unexpected address %v", key, l.addr)
+		}
+		delete(want, l.addr)
+		got[key] = l.addr
+	}
+	if _, ok := al.allocate("client3", netip.Addr{}, time.Hour); ok {
+		t.Errorf("allocate() from an exhausted pool succeeded")
+	}
+	// Clients keep their address.
+	for key, addr := range got {
+		if l, ok := al.allocate(key, netip.Addr{}, time.Hour); !ok || l.addr != addr {
+			t.Errorf("allocate(%q) again got %v, want %v", key, l.addr, addr)
+		}
+	}
+	// Expired leases are reallocated.
+	al.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	l, ok := al.allocate("client3", netip.Addr{}, time.Hour)
+	if !ok {
+		t.Fatalf("allocate() after lease expiry failed")
+	}
+	for key, addr := range got {
+		if addr == l.addr {
+			if _, ok := al.byClient[key]; ok {
+				t.Errorf("lease of %q was not removed when its address was reallocated", key)
+			}
+		}
+	}
+}
+
+func TestAllocateDeterministic(t *testing.T) {
+	newPools := func() *allocator {
+		al := newAllocator()
+		if err := al.addPool(mustPool(t, "pool:10.1.0.0/16,10.1.0.1,1h,")); err != nil {
+			t.Fatalf("addPool() err = %v", err)
+		}
+		if err := al.addPool(mustPool(t, "pool:2001:db8::/64,,1h,")); err != nil {
+			t.Fatalf("addPool() err = %v", err)
+		}
+		return al
+	}
+	// Allocation does not depend on the order of the requests, so a restarted server hands out the same addresses.
+	first, second := newPools(), newPools()
+	keys := []string{"00:11:22:33:44:55", "serial-1", "serial-2"}
+	want := map[string]netip.Addr{}
+	for _, k := range keys {
+		l, ok := first.allocate(k, netip.Addr{}, time.Hour)
+		if !ok {
+			t.Fatalf("allocate(%q) failed", k)
+		}
+		want[k] = l.addr
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		l, ok := second.allocate(keys[i], netip.Addr{}, time.Hour)
+		if !ok || l.addr != want[keys[i]] {
+			t.Errorf("allocate(%q) after restart got %v, want %v", keys[i], l.addr, want[keys[i]])
+		}
+	}
+}
+
+func TestAllocatorConflicts(t *testing.T) {
+	al := newAllocator()
+	if err := al.addPool(mustPool(t, "pool:10.0.0.0/24,,,")); err != nil {
+		t.Fatalf("addPool() err = %v", err)
+	}
+	if err := al.addPool(mustPool(t, "pool:10.0.0.128/25,,,")); err == nil {
+		t.Errorf("addPool() with overlapping pool err = nil, want error")
+	}
+	if err := al.addStatic("a", net.ParseIP("10.0.1.1")); err != nil {
+		t.Fatalf("addStatic() err = %v", err)
+	}
+	if err := al.addStatic("b", net.ParseIP("10.0.1.1")); err == nil {
+		t.Errorf("addStatic() with duplicate address err = nil, want error")
+	}
+}
+
+func TestHandler4Pool(t *testing.T) {
+	reset(t)
+	if _, err := setup4("00:00:00:00:00:01,10.0.0.20/24,10.0.0.1", "pool:10.0.0.0/24,10.0.0.1,10m,10.0.0.2-10.0.0.19"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	mac, _ := net.ParseMAC("00:00:00:00:00:02")
+	req, err := dhcpv4.NewDiscovery(mac)
+	if err != nil {
+		t.Fatalf("NewDiscovery() err = %v", err)
+	}
+	resp, err := dhcpv4.NewReplyFromRequest(req)
+	if err != nil {
+		t.Fatalf("NewReplyFromRequest() err = %v", err)
+	}
+	resp, _ = handler4(req, resp)
+	ip, ok := netip.AddrFromSlice(resp.YourIPAddr.To4())
+	if !ok || !netip.MustParsePrefix("10.0.0.0/24").Contains(ip) || ip.Less(netip.MustParseAddr("10.0.0.21")) || ip == netip.MustParseAddr("10.0.0.255") {
+		t.Errorf("handler4() assigned %v, want an address of the pool", resp.YourIPAddr)
+	}
+	if got := resp.IPAddressLeaseTime(0); got != 10*time.Minute {
+		t.Errorf("handler4() lease time = %v, want %v", got, 10*time.Minute)
+	}
+	if got := resp.Router(); len(got) != 1 || !got[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("handler4() router = %v, want 10.0.0.1", got)
+	}
+	if got := AssignedIP("00:00:00:00:00:02"); got != resp.YourIPAddr.String() {
+		t.Errorf("AssignedIP() = %v, want %v", got, resp.YourIPAddr)
+	}
+}
+
+func TestHandler6Pool(t *testing.T) {
+	reset(t)
+	if _, err := setup6("pool:2001:db8::/120,,10m,"); err != nil {
+		t.Fatalf("setup6() err = %v", err)
+	}
+	mac, _ := net.ParseMAC("00:00:00:00:00:02")
+	req, err := dhcpv6.NewSolicit(mac)
+	if err != nil {
+		t.Fatalf("NewSolicit() err = %v", err)
+	}
+	resp, err := dhcpv6.NewAdvertiseFromSolicit(req)
+	if err != nil {
+		t.Fatalf("NewAdvertiseFromSolicit() err = %v", err)
+	}
+	got, _ := handler6(req, resp)
+	iana := got.(*dhcpv6.Message).Options.OneIANA()
+	if iana == nil {
+		t.Fatalf("handler6() did not assign an address")
+	}
+	addr := iana.Options.OneAddress()
+	if !netip.MustParsePrefix("2001:db8::/120").Contains(netip.MustParseAddr(addr.IPv6Addr.String())) {
+		t.Errorf("handler6() assigned %v, want an address of the pool", addr.IPv6Addr)
+	}
+	if addr.ValidLifetime != 10*time.Minute {
+		t.Errorf("handler6() valid lifetime = %v, want %v", addr.ValidLifetime, 10*time.Minute)
+	}
+}
+
+func TestPoolLeaseTime(t *testing.T) {
+	reset(t)
+	scopeHandler, err := scope.Plugin.Setup4("subnet=10.1.0.0%2F24&router=10.1.0.1&lease-time=5m", "subnet=10.2.0.0%2F24&router=10.2.0.1&lease-time=5m")
+	if err != nil {
+		t.Fatalf("scope Setup4() err = %v", err)
+	}
+	defer scope.Plugin.Setup4()
+	if _, err := setup4("pool:10.1.0.0/24,10.1.0.1,,", "pool:10.2.0.0/24,10.2.0.1,10m,"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	tests := []struct {
+		desc   string
+		mac    string
+		giaddr net.IP
+		want   time.Duration
+	}{
+		{desc: "lease time of the scope", mac: "00:00:00:00:00:01", giaddr: net.ParseIP("10.1.0.1"), want: 5 * time.Minute},
+		{desc: "lease time of the pool", mac: "00:00:00:00:00:02", giaddr: net.ParseIP("10.2.0.1"), want: 10 * time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mac, _ := net.ParseMAC(test.mac)
+			req, err := dhcpv4.NewDiscovery(mac, dhcpv4.WithGatewayIP(test.giaddr))
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			// The scope plugin runs before the slease plugin.
+			resp, _ = scopeHandler(req, resp)
+			resp, _ = handler4(req, resp)
+			if got := resp.IPAddressLeaseTime(0); got != test.want {
+				t.Errorf("handler4() lease time = %v, want %v", got, test.want)
+			}
+			l, ok := leases.get(false, test.mac)
+			if !ok || l.Expiry.Sub(l.LastSeen) != test.want {
+				t.Errorf("lease = %+v, want a lease of %v", l, test.want)
+			}
+		})
+	}
+}
+
+func TestHandler4RelayedPool(t *testing.T) {
+	reset(t)
+	if _, err := scope.Plugin.Setup4("subnet=10.1.0.0%2F24&router=10.1.0.1"); err != nil {
+		t.Fatalf("scope Setup4() err = %v", err)
+	}
+	defer scope.Plugin.Setup4()
+	if _, err := setup4("pool:10.0.0.0/24,10.0.0.1,,", "pool:10.1.0.0/24,10.1.0.1,,"); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	tests := []struct {
+		desc   string
+		mac    string
+		giaddr net.IP
+		want   netip.Prefix
+	}{
+		{desc: "direct", mac: "00:00:00:00:00:01", giaddr: net.IPv4zero, want: netip.MustParsePrefix("10.0.0.0/24")},
+		{desc: "relayed from scope", mac: "00:00:00:00:00:02", giaddr: net.ParseIP("10.1.0.1"), want: netip.MustParsePrefix("10.1.0.0/24")},
+		{desc: "relayed without pool", mac: "00:00:00:00:00:03", giaddr: net.ParseIP("10.2.0.1")},
+		{desc: "moved to scope", mac: "00:00:00:00:00:01", giaddr: net.ParseIP("10.1.0.1"), want: netip.MustParsePrefix("10.1.0.0/24")},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			mac, _ := net.ParseMAC(test.mac)
+			req, err := dhcpv4.NewDiscovery(mac, dhcpv4.WithGatewayIP(test.giaddr))
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			resp, _ = handler4(req, resp)
+			ip, _ := netip.AddrFromSlice(resp.YourIPAddr.To4())
+			if !test.want.IsValid() {
+				if !resp.YourIPAddr.IsUnspecified() {
+					t.Errorf("handler4() assigned %v, want no address", resp.YourIPAddr)
+				}
+				return
+			}
+			if !test.want.Contains(ip) {
+				t.Errorf("handler4() assigned %v, want an address of %v", resp.YourIPAddr, test.want)
+			}
+		})
+	}
+}
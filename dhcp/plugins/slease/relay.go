@@ -0,0 +1,99 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slease
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
+)
+
+// RelayPrefix marks the static records which identify the machine by the port it is connected to, as reported by the
+// relay agent, rather than by its MAC address or serial. The relay agent IDs are URL query encoded, e.g.
+// relay:circuit-id=eth1%2F1&remote-id=tor1.
+const RelayPrefix = "relay:"
+
+// The keys of the relay agent IDs of a static record.
+const (
+	KeyCircuitID = "circuit-id"
+	KeyRemoteID  = "remote-id"
+)
+
+// relayMatch selects the static record of the machines connected to a port.
+type relayMatch struct {
+	key       string
+	circuitID string
+	remoteID  string
+}
+
+// parseRelayKey parses the key of a static record identifying the machine by its port.
+func parseRelayKey(key string) (*relayMatch, error) {
+	spec, ok := strings.CutPrefix(key, RelayPrefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid relay record key %v", key)
+	}
+	v, err := url.ParseQuery(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay record key %v: %v", key, err)
+	}
+	m := &relayMatch{key: key, circuitID: v.Get(KeyCircuitID), remoteID: v.Get(KeyRemoteID)}
+	for k := range v {
+		if k != KeyCircuitID && k != KeyRemoteID {
+			return nil, fmt.Errorf("invalid relay record key %v: unknown id %v", key, k)
+		}
+	}
+	if m.circuitID == "" && m.remoteID == "" {
+		return nil, fmt.Errorf("relay record key %v has no circuit or remote id", key)
+	}
+	return m, nil
+}
+
+func (m *relayMatch) matches(ids scope.AgentIDs) bool {
+	if m.circuitID != "" && !scope.MatchAgentID(ids.CircuitID, m.circuitID) {
+		return false
+	}
+	return m.remoteID == "" || scope.MatchAgentID(ids.RemoteID, m.remoteID)
+}
+
+// specificity orders the matches so that the records setting both IDs are tried first.
+func (m *relayMatch) specificity() int {
+	n := 0
+	if m.circuitID != "" {
+		n++
+	}
+	if m.remoteID != "" {
+		n++
+	}
+	return n
+}
+
+// matchRelay returns the key of the most specific static record matching the relay agent IDs.
+func matchRelay(matches []*relayMatch, ids scope.AgentIDs) (string, bool) {
+	if ids.CircuitID == nil && ids.RemoteID == nil {
+		return "", false
+	}
+	var best *relayMatch
+	for _, m := range matches {
+		if m.matches(ids) && (best == nil || m.specificity() > best.specificity()) {
+			best = m
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.key, true
+}
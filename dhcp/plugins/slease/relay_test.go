@@ -0,0 +1,127 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slease
+
+import (
+	"net"
+	"testing"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+)
+
+func TestParseRelayKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "relay:circuit-id=eth1%2F1"},
+		{key: "relay:remote-id=001c73000001"},
+		{key: "relay:circuit-id=eth1&remote-id=tor1"},
+		{key: "relay:", wantErr: true},
+		{key: "relay:port=eth1", wantErr: true},
+		{key: "relay:circuit-id=%zz", wantErr: true},
+		{key: "circuit-id=eth1", wantErr: true},
+	}
+	for _, test := range tests {
+		if _, err := parseRelayKey(test.key); (err != nil) != test.wantErr {
+			t.Errorf("parseRelayKey(%q) err = %v, want error %v", test.key, err, test.wantErr)
+		}
+	}
+}
+
+func TestHandler4Relay(t *testing.T) {
+	reset(t)
+	if _, err := setup4(
+		"relay:circuit-id=eth1%2F1,10.0.0.11/24,10.0.0.1",
+		"relay:circuit-id=eth1%2F1&remote-id=tor2,10.0.0.12/24,10.0.0.1",
+		"relay:remote-id=001c73000003,10.0.0.13/24,10.0.0.1",
+		"00:00:00:00:00:01,10.0.0.20/24,10.0.0.1",
+	); err != nil {
+		t.Fatalf("setup4() err = %v", err)
+	}
+	tests := []struct {
+		desc      string
+		mac       string
+		circuitID []byte
+		remoteID  []byte
+		want      string
+	}{
+		{desc: "circuit id", mac: "02:00:00:00:00:01", circuitID: []byte("eth1/1"), remoteID: []byte("tor1"), want: "10.0.0.11"},
+		{desc: "circuit id case-insensitive", mac: "02:00:00:00:00:01", circuitID: []byte("ETH1/1"), want: "10.0.0.11"},
+		{desc: "circuit and remote id", mac: "02:00:00:00:00:01", circuitID: []byte("eth1/1"), remoteID: []byte("tor2"), want: "10.0.0.12"},
+		{desc: "hex remote id", mac: "02:00:00:00:00:01", circuitID: []byte("eth9"), remoteID: []byte{0x00, 0x1c, 0x73, 0x00, 0x00, 0x03}, want: "10.0.0.13"},
+		{desc: "mac record first", mac: "00:00:00:00:00:01", circuitID: []byte("eth1/1"), want: "10.0.0.20"},
+		{desc: "no match", mac: "02:00:00:00:00:01", circuitID: []byte("eth2"), want: "0.0.0.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var sub []dhcpv4.Option
+			if test.circuitID != nil {
+				sub = append(sub, dhcpv4.OptGeneric(dhcpv4.AgentCircuitIDSubOption, test.circuitID))
+			}
+			if test.remoteID != nil {
+				sub = append(sub, dhcpv4.OptGeneric(dhcpv4.AgentRemoteIDSubOption, test.remoteID))
+			}
+			mac, _ := net.ParseMAC(test.mac)
+			req, err := dhcpv4.NewDiscovery(mac, dhcpv4.WithOption(dhcpv4.OptRelayAgentInfo(sub...)))
+			if err != nil {
+				t.Fatalf("NewDiscovery() err = %v", err)
+			}
+			resp, err := dhcpv4.NewReplyFromRequest(req)
+			if err != nil {
+				t.Fatalf("NewReplyFromRequest() err = %v", err)
+			}
+			resp, _ = handler4(req, resp)
+			if got := resp.YourIPAddr.String(); got != test.want {
+				t.Errorf("handler4() assigned %v, want %v", got, test.want)
+			}
+		})
+	}
+	// The lease of a machine identified by its port is found by its MAC address.
+	if got := AssignedIP("02:00:00:00:00:01"); got == "" {
+		t.Errorf("AssignedIP() of a machine identified by its port = %q, want an address", got)
+	}
+}
+
+func TestHandler6Relay(t *testing.T) {
+	reset(t)
+	if _, err := setup6("relay:circuit-id=eth1%2F1&remote-id=tor1,2001:db8::11/64"); err != nil {
+		t.Fatalf("setup6() err = %v", err)
+	}
+	mac, _ := net.ParseMAC("02:00:00:00:00:01")
+	msg, err := dhcpv6.NewSolicit(mac)
+	if err != nil {
+		t.Fatalf("NewSolicit() err = %v", err)
+	}
+	relay, err := dhcpv6.EncapsulateRelay(msg, dhcpv6.MessageTypeRelayForward, net.ParseIP("2001:db8::1"), net.ParseIP("fe80::1"))
+	if err != nil {
+		t.Fatalf("EncapsulateRelay() err = %v", err)
+	}
+	relay.AddOption(dhcpv6.OptInterfaceID([]byte("eth1/1")))
+	relay.AddOption(&dhcpv6.OptRemoteID{EnterpriseNumber: 30065, RemoteID: []byte("tor1")})
+	resp, err := dhcpv6.NewAdvertiseFromSolicit(msg)
+	if err != nil {
+		t.Fatalf("NewAdvertiseFromSolicit() err = %v", err)
+	}
+	got, _ := handler6(relay, resp)
+	iana := got.(*dhcpv6.Message).Options.OneIANA()
+	if iana == nil {
+		t.Fatalf("handler6() did not assign an address")
+	}
+	if addr := iana.Options.OneAddress().IPv6Addr; !addr.Equal(net.ParseIP("2001:db8::11")) {
+		t.Errorf("handler6() assigned %v, want 2001:db8::11", addr)
+	}
+}
@@ -18,20 +18,31 @@ package slease
 import (
 	"fmt"
 	"net"
+	"net/netip"
 	"strings"
 	"sync"
 	"time"
-	"unicode"
 
 	"github.com/coredhcp/coredhcp/handler"
 	"github.com/coredhcp/coredhcp/logger"
 	"github.com/coredhcp/coredhcp/plugins"
 	"github.com/insomniacslk/dhcp/dhcpv4"
 	"github.com/insomniacslk/dhcp/dhcpv6"
+
+	"github.com/openconfig/bootz/dhcp/plugins/scope"
+
+	plbootz "github.com/openconfig/bootz/dhcp/plugins/bootz"
 )
 
 var log = logger.GetLogger("plugins/slease")
 
+// defaultLeaseTime is the lease time of the static records if the lease_time plugin does not set one.
+const defaultLeaseTime = 3600 * time.Second
+
+// KeySeparator separates the keys of the machines sharing a static record, such as the management ports of a control
+// card, e.g. 00:1c:73:00:00:01;00:1c:73:00:00:02,10.0.0.11/24,10.0.0.1.
+const KeySeparator = ";"
+
 var Plugin = plugins.Plugin{
 	Name:   "slease",
 	Setup4: setup4,
@@ -47,30 +58,112 @@ type ipv4Entry struct {
 var ipv4Records = map[string]*ipv4Entry{}
 var ipv6Records = map[string]net.IP{}
 var muRw sync.RWMutex
-var ipv4Assigned = map[string]net.IP{}
-var ipv6Assigned = map[string]net.IP{}
+var ipv4Pools = newAllocator()
+var ipv6Pools = newAllocator()
+var ipv4RelayRecords []*relayMatch
+var ipv6RelayRecords []*relayMatch
 
 func setup4(args ...string) (handler.Handler4, error) {
+	// The records and pools are replaced when the server is reloaded, the leases are kept.
+	records, pools := map[string]*ipv4Entry{}, newAllocator()
+	var relayRecords []*relayMatch
 	for _, r := range args {
+		if path, ok := strings.CutPrefix(r, dbPrefix); ok {
+			if err := leases.open(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if strings.HasPrefix(r, poolPrefix) {
+			p, err := parsePool(r)
+			if err != nil {
+				return nil, err
+			}
+			if !p.prefix.Addr().Is4() {
+				return nil, fmt.Errorf("pool %v is not an ipv4 subnet", p.prefix)
+			}
+			if err := pools.addPool(p); err != nil {
+				return nil, err
+			}
+			log.Debugf("Added ipv4 pool: %v, %v, %v", p.prefix, p.gateway, p.leaseTime)
+			continue
+		}
 		if k, r, err := parseRecord4(strings.ToLower(r)); err == nil {
-			ipv4Records[k] = r
+			if err := pools.addStatic(k, r.ip); err != nil {
+				return nil, err
+			}
+			for _, k := range strings.Split(k, KeySeparator) {
+				if strings.HasPrefix(k, RelayPrefix) {
+					m, err := parseRelayKey(k)
+					if err != nil {
+						return nil, err
+					}
+					relayRecords = append(relayRecords, m)
+				}
+				records[k] = r
+			}
 			log.Debugf("Added ipv4 record: %v, %v, %v, %v", k, r.ip, r.netmask, r.gateway)
 		} else {
 			return nil, err
 		}
 	}
+	pools.checkConflicts()
+	muRw.Lock()
+	defer muRw.Unlock()
+	leases.restore(pools, false)
+	ipv4Records, ipv4RelayRecords, ipv4Pools = records, relayRecords, pools
 	return handler4, nil
 }
 
 func setup6(args ...string) (handler.Handler6, error) {
+	// The records and pools are replaced when the server is reloaded, the leases are kept.
+	records, pools := map[string]net.IP{}, newAllocator()
+	var relayRecords []*relayMatch
 	for _, r := range args {
+		if path, ok := strings.CutPrefix(r, dbPrefix); ok {
+			if err := leases.open(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if strings.HasPrefix(r, poolPrefix) {
+			p, err := parsePool(r)
+			if err != nil {
+				return nil, err
+			}
+			if !p.prefix.Addr().Is6() {
+				return nil, fmt.Errorf("pool %v is not an ipv6 subnet", p.prefix)
+			}
+			if err := pools.addPool(p); err != nil {
+				return nil, err
+			}
+			log.Debugf("Added ipv6 pool: %v, %v", p.prefix, p.leaseTime)
+			continue
+		}
 		if k, r, err := parseRecord6(strings.ToLower(r)); err == nil {
-			ipv6Records[k] = r
+			if err := pools.addStatic(k, r); err != nil {
+				return nil, err
+			}
+			for _, k := range strings.Split(k, KeySeparator) {
+				if strings.HasPrefix(k, RelayPrefix) {
+					m, err := parseRelayKey(k)
+					if err != nil {
+						return nil, err
+					}
+					relayRecords = append(relayRecords, m)
+				}
+				records[k] = r
+			}
 			log.Debugf("Added ipv6 record: %v, %v", k, r.String())
 		} else {
 			return nil, err
 		}
 	}
+	pools.checkConflicts()
+	muRw.Lock()
+	defer muRw.Unlock()
+	leases.restore(pools, true)
+	ipv6Records, ipv6RelayRecords, ipv6Pools = records, relayRecords, pools
 	return handler6, nil
 }
 
@@ -78,22 +171,27 @@ func setup6(args ...string) (handler.Handler6, error) {
 func CleanLog() {
 	muRw.Lock()
 	defer muRw.Unlock()
-	ipv4Assigned = map[string]net.IP{}
-	ipv6Assigned = map[string]net.IP{}
+	leases.clear()
+	for _, al := range []*allocator{ipv4Pools, ipv6Pools} {
+		al.byAddr = map[netip.Addr]*poolLease{}
+		al.byClient = map[string]*poolLease{}
+	}
 }
 
 // AssignedIP returns the assigned ip related to hwAddr (mac or serial)
 func AssignedIP(hwAddr string) string {
 	hwAddr = strings.ToLower(hwAddr)
-	muRw.RLock()
-	defer muRw.RUnlock()
-	ipv4, ok := ipv4Assigned[hwAddr]
-	if ok {
-		return ipv4.String()
+	muRw.Lock()
+	defer muRw.Unlock()
+	if l, ok := leases.get(false, hwAddr); ok {
+		return l.IP
+	}
+	if l, ok := leases.get(true, hwAddr); ok {
+		return l.IP
 	}
-	ipv6, ok := ipv6Assigned[hwAddr]
-	if ok {
-		return ipv6.String()
+	// Machines identified by their port have a lease keyed by the relay agent IDs.
+	if l, ok := leases.find(hwAddr); ok {
+		return l.IP
 	}
 	return ""
 }
@@ -101,19 +199,59 @@ func AssignedIP(hwAddr string) string {
 func handler4(req, resp *dhcpv4.DHCPv4) (*dhcpv4.DHCPv4, bool) {
 	muRw.Lock()
 	defer muRw.Unlock()
+	cid := plbootz.ClientID(req.GetOneOption(dhcpv4.OptionClientIdentifier))
 	if e, ok := ipv4Records[req.ClientHWAddr.String()]; ok {
 		resp4(e, resp)
-		ipv4Assigned[req.ClientHWAddr.String()] = resp.ServerIPAddr
-	} else if req.Options.Has(dhcpv4.OptionClientIdentifier) {
-		cid := req.GetOneOption(dhcpv4.OptionClientIdentifier)
-		if e, ok := ipv4Records[toString(cid)]; ok {
-			resp4(e, resp)
-			ipv4Assigned[strings.ToLower(toString(cid))] = resp.ServerIPAddr
+		record4(req, resp, req.ClientHWAddr.String())
+	} else if e, ok := ipv4Records[cid]; ok && cid != "" {
+		resp4(e, resp)
+		record4(req, resp, strings.ToLower(cid))
+	} else if key, ok := matchRelay(ipv4RelayRecords, scope.AgentIDs4(req)); ok {
+		// The machine is identified by the port it is connected to.
+		resp4(ipv4Records[key], resp)
+		record4(req, resp, key)
+	} else {
+		// Machines without a static record get an address from the pools. The client identifier is preferred over the
+		// MAC address as the allocation key.
+		key := req.ClientHWAddr.String()
+		if cid != "" {
+			key = strings.ToLower(cid)
+		}
+		// The lease time of the pool takes precedence over the one set by the scope or the lease_time plugin.
+		link, _ := scope.RelayAddr4(req)
+		if l, ok := ipv4Pools.allocate(key, link, resp.IPAddressLeaseTime(defaultLeaseTime)); ok {
+			respPool4(l, resp)
+			record4(req, resp, key)
 		}
 	}
 	return resp, false
 }
 
+// record4 records the lease of the address assigned in the response.
+func record4(req, resp *dhcpv4.DHCPv4, key string) {
+	now := leases.now()
+	leases.update(&Lease{
+		Key:            key,
+		ClientID:       plbootz.ClientID(req.GetOneOption(dhcpv4.OptionClientIdentifier)),
+		MAC:            req.ClientHWAddr.String(),
+		IP:             resp.YourIPAddr.String(),
+		Expiry:         now.Add(resp.IPAddressLeaseTime(defaultLeaseTime)),
+		LastSeen:       now,
+		BootzRequested: req.IsOptionRequested(dhcpv4.GenericOptionCode(plbootz.OPTION_V4_SZTP_REDIRECT)),
+	})
+}
+
+func respPool4(l *poolLease, resp *dhcpv4.DHCPv4) {
+	resp.YourIPAddr = l.addr.AsSlice()
+	resp.Options.Update(dhcpv4.OptSubnetMask(net.CIDRMask(l.pool.prefix.Bits(), 32)))
+	if l.pool.gateway != nil {
+		resp.Options.Update(dhcpv4.OptRouter(l.pool.gateway))
+	}
+	if l.pool.leaseTime > 0 {
+		resp.Options.Update(dhcpv4.OptIPAddressLeaseTime(l.pool.leaseTime))
+	}
+}
+
 func resp4(e *ipv4Entry, resp *dhcpv4.DHCPv4) {
 	resp.YourIPAddr = e.ip
 	resp.Options.Update(dhcpv4.OptSubnetMask(e.netmask))
@@ -133,45 +271,85 @@ func handler6(req, resp dhcpv6.DHCPv6) (dhcpv6.DHCPv6, bool) {
 		return resp, false
 	}
 
-	if mac, err := dhcpv6.ExtractMAC(req); err == nil {
-		if ip, ok := ipv6Records[mac.String()]; ok {
-			resp.AddOption(createIpv6LeaseOption(m, ip))
-			ipv6Assigned[mac.String()] = ip
+	leaseTime := defaultLeaseTime
+	if s := scope.Lookup6(req); s != nil && s.LeaseTime > 0 {
+		leaseTime = s.LeaseTime
+	}
+
+	// The allocation key of machines without a static record is the same as the static record key.
+	var key, mac string
+	if hw, err := dhcpv6.ExtractMAC(req); err == nil {
+		key, mac = hw.String(), hw.String()
+		if ip, ok := ipv6Records[key]; ok {
+			resp.AddOption(createIpv6LeaseOption(m, ip, leaseTime))
+			record6(m, key, mac, ip, leaseTime)
+			return resp, false
 		}
 	} else {
 		duid := m.Options.ClientID()
 		if en, ok := duid.(*dhcpv6.DUIDEN); ok {
 			ei := en.EnterpriseIdentifier[:len(en.EnterpriseIdentifier)]
-			if ip, ok := ipv6Records[toString(ei)]; ok {
-				resp.AddOption(createIpv6LeaseOption(m, ip))
-				ipv6Assigned[strings.ToLower(toString(ei))] = ip
+			key = strings.ToLower(plbootz.ClientID(ei))
+			if ip, ok := ipv6Records[plbootz.ClientID(ei)]; ok {
+				resp.AddOption(createIpv6LeaseOption(m, ip, leaseTime))
+				record6(m, key, mac, ip, leaseTime)
+				return resp, false
 			}
+		} else if duid != nil {
+			key = fmt.Sprintf("%x", duid.ToBytes())
 		}
 	}
+	if rk, ok := matchRelay(ipv6RelayRecords, scope.AgentIDs6(req)); ok {
+		// The machine is identified by the port it is connected to.
+		ip := ipv6Records[rk]
+		resp.AddOption(createIpv6LeaseOption(m, ip, leaseTime))
+		record6(m, rk, mac, ip, leaseTime)
+		return resp, false
+	}
+	if key == "" {
+		return resp, false
+	}
+	link, _ := scope.RelayAddr6(req)
+	if l, ok := ipv6Pools.allocate(key, link, leaseTime); ok {
+		resp.AddOption(createIpv6LeaseOption(m, l.addr.AsSlice(), l.pool.lease(leaseTime)))
+		record6(m, key, mac, l.addr.AsSlice(), l.pool.lease(leaseTime))
+	}
 	return resp, false
 }
 
-func createIpv6LeaseOption(m *dhcpv6.Message, ip net.IP) *dhcpv6.OptIANA {
+// record6 records the lease of the assigned address.
+func record6(m *dhcpv6.Message, key, mac string, ip net.IP, leaseTime time.Duration) {
+	var clientID string
+	if duid := m.Options.ClientID(); duid != nil {
+		clientID = fmt.Sprintf("%x", duid.ToBytes())
+	}
+	now := leases.now()
+	leases.update(&Lease{
+		Key:            key,
+		ClientID:       clientID,
+		MAC:            mac,
+		IP:             ip.String(),
+		Expiry:         now.Add(leaseTime),
+		LastSeen:       now,
+		BootzRequested: m.IsOptionRequested(dhcpv6.OptionCode(plbootz.OPTION_V6_SZTP_REDIRECT)),
+	})
+}
+
+func createIpv6LeaseOption(m *dhcpv6.Message, ip net.IP, leaseTime time.Duration) *dhcpv6.OptIANA {
 	return &dhcpv6.OptIANA{
 		IaId: m.Options.OneIANA().IaId,
 		Options: dhcpv6.IdentityOptions{Options: []dhcpv6.Option{
 			&dhcpv6.OptIAAddress{
 				IPv6Addr:          ip,
-				PreferredLifetime: 3600 * time.Second,
-				ValidLifetime:     3600 * time.Second,
+				PreferredLifetime: leaseTime,
+				ValidLifetime:     leaseTime,
 			},
 		}},
 	}
 }
 
-func toString(b []byte) string {
-	return strings.TrimFunc(string(b), func(r rune) bool {
-		return !unicode.IsGraphic(r)
-	})
-}
-
 func parseRecord4(r string) (string, *ipv4Entry, error) {
-	//format: mac|serial,ipv4/mask,gw
+	//format: mac|serial|relay:ids[;...],ipv4/mask,gw
 	parts := strings.Split(r, ",")
 	if len(parts) != 3 {
 		return "", nil, fmt.Errorf("invalid entry %v", r)
@@ -193,7 +371,7 @@ func parseRecord4(r string) (string, *ipv4Entry, error) {
 }
 
 func parseRecord6(r string) (string, net.IP, error) {
-	//format: mac|serial,ipv6
+	//format: mac|serial|relay:ids[;...],ipv6
 	parts := strings.Split(r, ",")
 	if len(parts) != 2 {
 		return "", nil, fmt.Errorf("invalid entry %v", r)
@@ -0,0 +1,280 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v7.35.0
+// source: github.com/openconfig/bootz/dhcp/proto/dhcpadmin.proto
+
+package dhcpadmin
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListLeasesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        string                 `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLeasesRequest) Reset() {
+	*x = ListLeasesRequest{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLeasesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLeasesRequest) ProtoMessage() {}
+
+func (x *ListLeasesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLeasesRequest.ProtoReflect.Descriptor instead.
+func (*ListLeasesRequest) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListLeasesRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+type ListLeasesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Leases        []*Lease               `protobuf:"bytes,1,rep,name=leases,proto3" json:"leases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLeasesResponse) Reset() {
+	*x = ListLeasesResponse{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLeasesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLeasesResponse) ProtoMessage() {}
+
+func (x *ListLeasesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLeasesResponse.ProtoReflect.Descriptor instead.
+func (*ListLeasesResponse) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListLeasesResponse) GetLeases() []*Lease {
+	if x != nil {
+		return x.Leases
+	}
+	return nil
+}
+
+type Lease struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Key            string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ClientId       string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Mac            string                 `protobuf:"bytes,3,opt,name=mac,proto3" json:"mac,omitempty"`
+	Ip             string                 `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`
+	Expiry         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	LastSeen       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	BootzRequested bool                   `protobuf:"varint,7,opt,name=bootz_requested,json=bootzRequested,proto3" json:"bootz_requested,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Lease) Reset() {
+	*x = Lease{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Lease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Lease) ProtoMessage() {}
+
+func (x *Lease) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Lease.ProtoReflect.Descriptor instead.
+func (*Lease) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Lease) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Lease) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *Lease) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *Lease) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Lease) GetExpiry() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Expiry
+	}
+	return nil
+}
+
+func (x *Lease) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Lease) GetBootzRequested() bool {
+	if x != nil {
+		return x.BootzRequested
+	}
+	return false
+}
+
+var File_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto protoreflect.FileDescriptor
+
+const file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDesc = "" +
+	"\n" +
+	"6github.com/openconfig/bootz/dhcp/proto/dhcpadmin.proto\x12\tdhcpadmin\x1a\x1fgoogle/protobuf/timestamp.proto\"+\n" +
+	"\x11ListLeasesRequest\x12\x16\n" +
+	"\x06filter\x18\x01 \x01(\tR\x06filter\">\n" +
+	"\x12ListLeasesResponse\x12(\n" +
+	"\x06leases\x18\x01 \x03(\v2\x10.dhcpadmin.LeaseR\x06leases\"\xee\x01\n" +
+	"\x05Lease\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x10\n" +
+	"\x03mac\x18\x03 \x01(\tR\x03mac\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x122\n" +
+	"\x06expiry\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x06expiry\x127\n" +
+	"\tlast_seen\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12'\n" +
+	"\x0fbootz_requested\x18\a \x01(\bR\x0ebootzRequested2X\n" +
+	"\tDHCPAdmin\x12K\n" +
+	"\n" +
+	"ListLeases\x12\x1c.dhcpadmin.ListLeasesRequest\x1a\x1d.dhcpadmin.ListLeasesResponse\"\x00B2Z0github.com/openconfig/bootz/dhcp/proto/dhcpadminb\x06proto3"
+
+var (
+	file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescOnce sync.Once
+	file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescData []byte
+)
+
+func file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescGZIP() []byte {
+	file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescOnce.Do(func() {
+		file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDesc), len(file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDesc)))
+	})
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDescData
+}
+
+var file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_goTypes = []any{
+	(*ListLeasesRequest)(nil),     // 0: dhcpadmin.ListLeasesRequest
+	(*ListLeasesResponse)(nil),    // 1: dhcpadmin.ListLeasesResponse
+	(*Lease)(nil),                 // 2: dhcpadmin.Lease
+	(*timestamppb.Timestamp)(nil), // 3: google.protobuf.Timestamp
+}
+var file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_depIdxs = []int32{
+	2, // 0: dhcpadmin.ListLeasesResponse.leases:type_name -> dhcpadmin.Lease
+	3, // 1: dhcpadmin.Lease.expiry:type_name -> google.protobuf.Timestamp
+	3, // 2: dhcpadmin.Lease.last_seen:type_name -> google.protobuf.Timestamp
+	0, // 3: dhcpadmin.DHCPAdmin.ListLeases:input_type -> dhcpadmin.ListLeasesRequest
+	1, // 4: dhcpadmin.DHCPAdmin.ListLeases:output_type -> dhcpadmin.ListLeasesResponse
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_init() }
+func file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_init() {
+	if File_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDesc), len(file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_goTypes,
+		DependencyIndexes: file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_depIdxs,
+		MessageInfos:      file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_msgTypes,
+	}.Build()
+	File_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto = out.File
+	file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_goTypes = nil
+	file_github_com_openconfig_bootz_dhcp_proto_dhcpadmin_proto_depIdxs = nil
+}
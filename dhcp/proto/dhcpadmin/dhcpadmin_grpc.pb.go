@@ -0,0 +1,119 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.1
+// - protoc             v7.34.1
+// source: github.com/openconfig/bootz/dhcp/proto/dhcpadmin.proto
+
+package dhcpadmin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DHCPAdmin_ListLeases_FullMethodName = "/dhcpadmin.DHCPAdmin/ListLeases"
+)
+
+// DHCPAdminClient is the client API for DHCPAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DHCPAdminClient interface {
+	ListLeases(ctx context.Context, in *ListLeasesRequest, opts ...grpc.CallOption) (*ListLeasesResponse, error)
+}
+
+type dHCPAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDHCPAdminClient(cc grpc.ClientConnInterface) DHCPAdminClient {
+	return &dHCPAdminClient{cc}
+}
+
+func (c *dHCPAdminClient) ListLeases(ctx context.Context, in *ListLeasesRequest, opts ...grpc.CallOption) (*ListLeasesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLeasesResponse)
+	err := c.cc.Invoke(ctx, DHCPAdmin_ListLeases_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DHCPAdminServer is the server API for DHCPAdmin service.
+// All implementations should embed UnimplementedDHCPAdminServer
+// for forward compatibility.
+type DHCPAdminServer interface {
+	ListLeases(context.Context, *ListLeasesRequest) (*ListLeasesResponse, error)
+}
+
+// UnimplementedDHCPAdminServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDHCPAdminServer struct{}
+
+func (UnimplementedDHCPAdminServer) ListLeases(context.Context, *ListLeasesRequest) (*ListLeasesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListLeases not implemented")
+}
+func (UnimplementedDHCPAdminServer) testEmbeddedByValue() {}
+
+// UnsafeDHCPAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DHCPAdminServer will
+// result in compilation errors.
+type UnsafeDHCPAdminServer interface {
+	mustEmbedUnimplementedDHCPAdminServer()
+}
+
+func RegisterDHCPAdminServer(s grpc.ServiceRegistrar, srv DHCPAdminServer) {
+	// If the following call panics, it indicates UnimplementedDHCPAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DHCPAdmin_ServiceDesc, srv)
+}
+
+func _DHCPAdmin_ListLeases_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLeasesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DHCPAdminServer).ListLeases(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DHCPAdmin_ListLeases_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DHCPAdminServer).ListLeases(ctx, req.(*ListLeasesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DHCPAdmin_ServiceDesc is the grpc.ServiceDesc for DHCPAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DHCPAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dhcpadmin.DHCPAdmin",
+	HandlerType: (*DHCPAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListLeases",
+			Handler:    _DHCPAdmin_ListLeases_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "github.com/openconfig/bootz/dhcp/proto/dhcpadmin.proto",
+}
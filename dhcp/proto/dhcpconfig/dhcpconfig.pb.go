@@ -21,14 +21,71 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Config_Mode int32
+
+const (
+	Config_MODE_DUAL_STACK Config_Mode = 0
+	Config_MODE_IPV4_ONLY  Config_Mode = 1
+	Config_MODE_IPV6_ONLY  Config_Mode = 2
+)
+
+// Enum value maps for Config_Mode.
+var (
+	Config_Mode_name = map[int32]string{
+		0: "MODE_DUAL_STACK",
+		1: "MODE_IPV4_ONLY",
+		2: "MODE_IPV6_ONLY",
+	}
+	Config_Mode_value = map[string]int32{
+		"MODE_DUAL_STACK": 0,
+		"MODE_IPV4_ONLY":  1,
+		"MODE_IPV6_ONLY":  2,
+	}
+)
+
+func (x Config_Mode) Enum() *Config_Mode {
+	p := new(Config_Mode)
+	*p = x
+	return p
+}
+
+func (x Config_Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Config_Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_enumTypes[0].Descriptor()
+}
+
+func (Config_Mode) Type() protoreflect.EnumType {
+	return &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_enumTypes[0]
+}
+
+func (x Config_Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Config_Mode.Descriptor instead.
+func (Config_Mode) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{0, 0}
+}
+
 type Config struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Interface     string                 `protobuf:"bytes,1,opt,name=interface,proto3" json:"interface,omitempty"`
-	Dns           []string               `protobuf:"bytes,2,rep,name=dns,proto3" json:"dns,omitempty"`
-	BootzUrls     []string               `protobuf:"bytes,3,rep,name=bootz_urls,json=bootzUrls,proto3" json:"bootz_urls,omitempty"`
-	Records       []*Record              `protobuf:"bytes,4,rep,name=records,proto3" json:"records,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Interface        string                 `protobuf:"bytes,1,opt,name=interface,proto3" json:"interface,omitempty"`
+	Dns              []string               `protobuf:"bytes,2,rep,name=dns,proto3" json:"dns,omitempty"`
+	BootzUrls        []string               `protobuf:"bytes,3,rep,name=bootz_urls,json=bootzUrls,proto3" json:"bootz_urls,omitempty"`
+	Records          []*Record              `protobuf:"bytes,4,rep,name=records,proto3" json:"records,omitempty"`
+	Pools            []*Pool                `protobuf:"bytes,5,rep,name=pools,proto3" json:"pools,omitempty"`
+	LeaseDb          string                 `protobuf:"bytes,6,opt,name=lease_db,json=leaseDb,proto3" json:"lease_db,omitempty"`
+	AdminAddress     string                 `protobuf:"bytes,7,opt,name=admin_address,json=adminAddress,proto3" json:"admin_address,omitempty"`
+	BootzRedirects   []*BootzRedirect       `protobuf:"bytes,8,rep,name=bootz_redirects,json=bootzRedirects,proto3" json:"bootz_redirects,omitempty"`
+	Scopes           []*Scope               `protobuf:"bytes,9,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	Mode             Config_Mode            `protobuf:"varint,10,opt,name=mode,proto3,enum=dhcpconfig.Config_Mode" json:"mode,omitempty"`
+	LegacyBootServer string                 `protobuf:"bytes,11,opt,name=legacy_boot_server,json=legacyBootServer,proto3" json:"legacy_boot_server,omitempty"`
+	AdminTls         *AdminTLS              `protobuf:"bytes,12,opt,name=admin_tls,json=adminTls,proto3" json:"admin_tls,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *Config) Reset() {
@@ -89,18 +146,138 @@ func (x *Config) GetRecords() []*Record {
 	return nil
 }
 
-type Record struct {
+func (x *Config) GetPools() []*Pool {
+	if x != nil {
+		return x.Pools
+	}
+	return nil
+}
+
+func (x *Config) GetLeaseDb() string {
+	if x != nil {
+		return x.LeaseDb
+	}
+	return ""
+}
+
+func (x *Config) GetAdminAddress() string {
+	if x != nil {
+		return x.AdminAddress
+	}
+	return ""
+}
+
+func (x *Config) GetBootzRedirects() []*BootzRedirect {
+	if x != nil {
+		return x.BootzRedirects
+	}
+	return nil
+}
+
+func (x *Config) GetScopes() []*Scope {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *Config) GetMode() Config_Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Config_MODE_DUAL_STACK
+}
+
+func (x *Config) GetLegacyBootServer() string {
+	if x != nil {
+		return x.LegacyBootServer
+	}
+	return ""
+}
+
+func (x *Config) GetAdminTls() *AdminTLS {
+	if x != nil {
+		return x.AdminTls
+	}
+	return nil
+}
+
+type AdminTLS struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Machine       string                 `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
-	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
-	Gateway       string                 `protobuf:"bytes,3,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	CertFile      string                 `protobuf:"bytes,1,opt,name=cert_file,json=certFile,proto3" json:"cert_file,omitempty"`
+	KeyFile       string                 `protobuf:"bytes,2,opt,name=key_file,json=keyFile,proto3" json:"key_file,omitempty"`
+	ClientCaFile  string                 `protobuf:"bytes,3,opt,name=client_ca_file,json=clientCaFile,proto3" json:"client_ca_file,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *AdminTLS) Reset() {
+	*x = AdminTLS{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdminTLS) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdminTLS) ProtoMessage() {}
+
+func (x *AdminTLS) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdminTLS.ProtoReflect.Descriptor instead.
+func (*AdminTLS) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AdminTLS) GetCertFile() string {
+	if x != nil {
+		return x.CertFile
+	}
+	return ""
+}
+
+func (x *AdminTLS) GetKeyFile() string {
+	if x != nil {
+		return x.KeyFile
+	}
+	return ""
+}
+
+func (x *AdminTLS) GetClientCaFile() string {
+	if x != nil {
+		return x.ClientCaFile
+	}
+	return ""
+}
+
+type Record struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Machine           string                 `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
+	Ip                string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Gateway           string                 `protobuf:"bytes,3,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	CircuitId         string                 `protobuf:"bytes,4,opt,name=circuit_id,json=circuitId,proto3" json:"circuit_id,omitempty"`
+	RemoteId          string                 `protobuf:"bytes,5,opt,name=remote_id,json=remoteId,proto3" json:"remote_id,omitempty"`
+	BootFile          string                 `protobuf:"bytes,6,opt,name=boot_file,json=bootFile,proto3" json:"boot_file,omitempty"`
+	AlternateMachines []string               `protobuf:"bytes,7,rep,name=alternate_machines,json=alternateMachines,proto3" json:"alternate_machines,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
 func (x *Record) Reset() {
 	*x = Record{}
-	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[1]
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -112,7 +289,7 @@ func (x *Record) String() string {
 func (*Record) ProtoMessage() {}
 
 func (x *Record) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[1]
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -125,7 +302,7 @@ func (x *Record) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Record.ProtoReflect.Descriptor instead.
 func (*Record) Descriptor() ([]byte, []int) {
-	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{1}
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Record) GetMachine() string {
@@ -149,22 +326,333 @@ func (x *Record) GetGateway() string {
 	return ""
 }
 
+func (x *Record) GetCircuitId() string {
+	if x != nil {
+		return x.CircuitId
+	}
+	return ""
+}
+
+func (x *Record) GetRemoteId() string {
+	if x != nil {
+		return x.RemoteId
+	}
+	return ""
+}
+
+func (x *Record) GetBootFile() string {
+	if x != nil {
+		return x.BootFile
+	}
+	return ""
+}
+
+func (x *Record) GetAlternateMachines() []string {
+	if x != nil {
+		return x.AlternateMachines
+	}
+	return nil
+}
+
+type Pool struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Subnet           string                 `protobuf:"bytes,1,opt,name=subnet,proto3" json:"subnet,omitempty"`
+	Exclusions       []string               `protobuf:"bytes,2,rep,name=exclusions,proto3" json:"exclusions,omitempty"`
+	Gateway          string                 `protobuf:"bytes,3,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	LeaseTimeSeconds uint32                 `protobuf:"varint,4,opt,name=lease_time_seconds,json=leaseTimeSeconds,proto3" json:"lease_time_seconds,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Pool) Reset() {
+	*x = Pool{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pool) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pool) ProtoMessage() {}
+
+func (x *Pool) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pool.ProtoReflect.Descriptor instead.
+func (*Pool) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Pool) GetSubnet() string {
+	if x != nil {
+		return x.Subnet
+	}
+	return ""
+}
+
+func (x *Pool) GetExclusions() []string {
+	if x != nil {
+		return x.Exclusions
+	}
+	return nil
+}
+
+func (x *Pool) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
+func (x *Pool) GetLeaseTimeSeconds() uint32 {
+	if x != nil {
+		return x.LeaseTimeSeconds
+	}
+	return 0
+}
+
+type BootzRedirect struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VendorClass   string                 `protobuf:"bytes,1,opt,name=vendor_class,json=vendorClass,proto3" json:"vendor_class,omitempty"`
+	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	MacPrefix     string                 `protobuf:"bytes,3,opt,name=mac_prefix,json=macPrefix,proto3" json:"mac_prefix,omitempty"`
+	CircuitId     string                 `protobuf:"bytes,4,opt,name=circuit_id,json=circuitId,proto3" json:"circuit_id,omitempty"`
+	BootzUrls     []string               `protobuf:"bytes,5,rep,name=bootz_urls,json=bootzUrls,proto3" json:"bootz_urls,omitempty"`
+	RemoteId      string                 `protobuf:"bytes,6,opt,name=remote_id,json=remoteId,proto3" json:"remote_id,omitempty"`
+	BootFile      string                 `protobuf:"bytes,7,opt,name=boot_file,json=bootFile,proto3" json:"boot_file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BootzRedirect) Reset() {
+	*x = BootzRedirect{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BootzRedirect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BootzRedirect) ProtoMessage() {}
+
+func (x *BootzRedirect) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BootzRedirect.ProtoReflect.Descriptor instead.
+func (*BootzRedirect) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BootzRedirect) GetVendorClass() string {
+	if x != nil {
+		return x.VendorClass
+	}
+	return ""
+}
+
+func (x *BootzRedirect) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *BootzRedirect) GetMacPrefix() string {
+	if x != nil {
+		return x.MacPrefix
+	}
+	return ""
+}
+
+func (x *BootzRedirect) GetCircuitId() string {
+	if x != nil {
+		return x.CircuitId
+	}
+	return ""
+}
+
+func (x *BootzRedirect) GetBootzUrls() []string {
+	if x != nil {
+		return x.BootzUrls
+	}
+	return nil
+}
+
+func (x *BootzRedirect) GetRemoteId() string {
+	if x != nil {
+		return x.RemoteId
+	}
+	return ""
+}
+
+func (x *BootzRedirect) GetBootFile() string {
+	if x != nil {
+		return x.BootFile
+	}
+	return ""
+}
+
+type Scope struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Subnet           string                 `protobuf:"bytes,1,opt,name=subnet,proto3" json:"subnet,omitempty"`
+	Router           string                 `protobuf:"bytes,2,opt,name=router,proto3" json:"router,omitempty"`
+	Dns              []string               `protobuf:"bytes,3,rep,name=dns,proto3" json:"dns,omitempty"`
+	LeaseTimeSeconds uint32                 `protobuf:"varint,4,opt,name=lease_time_seconds,json=leaseTimeSeconds,proto3" json:"lease_time_seconds,omitempty"`
+	BootzUrls        []string               `protobuf:"bytes,5,rep,name=bootz_urls,json=bootzUrls,proto3" json:"bootz_urls,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Scope) Reset() {
+	*x = Scope{}
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Scope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Scope) ProtoMessage() {}
+
+func (x *Scope) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Scope.ProtoReflect.Descriptor instead.
+func (*Scope) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Scope) GetSubnet() string {
+	if x != nil {
+		return x.Subnet
+	}
+	return ""
+}
+
+func (x *Scope) GetRouter() string {
+	if x != nil {
+		return x.Router
+	}
+	return ""
+}
+
+func (x *Scope) GetDns() []string {
+	if x != nil {
+		return x.Dns
+	}
+	return nil
+}
+
+func (x *Scope) GetLeaseTimeSeconds() uint32 {
+	if x != nil {
+		return x.LeaseTimeSeconds
+	}
+	return 0
+}
+
+func (x *Scope) GetBootzUrls() []string {
+	if x != nil {
+		return x.BootzUrls
+	}
+	return nil
+}
+
 var File_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto protoreflect.FileDescriptor
 
 const file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDesc = "" +
 	"\n" +
 	"7github.com/openconfig/bootz/dhcp/proto/dhcpconfig.proto\x12\n" +
-	"dhcpconfig\"\x85\x01\n" +
+	"dhcpconfig\"\xaf\x04\n" +
 	"\x06Config\x12\x1c\n" +
 	"\tinterface\x18\x01 \x01(\tR\tinterface\x12\x10\n" +
 	"\x03dns\x18\x02 \x03(\tR\x03dns\x12\x1d\n" +
 	"\n" +
 	"bootz_urls\x18\x03 \x03(\tR\tbootzUrls\x12,\n" +
-	"\arecords\x18\x04 \x03(\v2\x12.dhcpconfig.RecordR\arecords\"L\n" +
+	"\arecords\x18\x04 \x03(\v2\x12.dhcpconfig.RecordR\arecords\x12&\n" +
+	"\x05pools\x18\x05 \x03(\v2\x10.dhcpconfig.PoolR\x05pools\x12\x19\n" +
+	"\blease_db\x18\x06 \x01(\tR\aleaseDb\x12#\n" +
+	"\radmin_address\x18\a \x01(\tR\fadminAddress\x12B\n" +
+	"\x0fbootz_redirects\x18\b \x03(\v2\x19.dhcpconfig.BootzRedirectR\x0ebootzRedirects\x12)\n" +
+	"\x06scopes\x18\t \x03(\v2\x11.dhcpconfig.ScopeR\x06scopes\x12+\n" +
+	"\x04mode\x18\n" +
+	" \x01(\x0e2\x17.dhcpconfig.Config.ModeR\x04mode\x12,\n" +
+	"\x12legacy_boot_server\x18\v \x01(\tR\x10legacyBootServer\x121\n" +
+	"\tadmin_tls\x18\f \x01(\v2\x14.dhcpconfig.AdminTLSR\badminTls\"C\n" +
+	"\x04Mode\x12\x13\n" +
+	"\x0fMODE_DUAL_STACK\x10\x00\x12\x12\n" +
+	"\x0eMODE_IPV4_ONLY\x10\x01\x12\x12\n" +
+	"\x0eMODE_IPV6_ONLY\x10\x02\"h\n" +
+	"\bAdminTLS\x12\x1b\n" +
+	"\tcert_file\x18\x01 \x01(\tR\bcertFile\x12\x19\n" +
+	"\bkey_file\x18\x02 \x01(\tR\akeyFile\x12$\n" +
+	"\x0eclient_ca_file\x18\x03 \x01(\tR\fclientCaFile\"\xd4\x01\n" +
 	"\x06Record\x12\x18\n" +
 	"\amachine\x18\x01 \x01(\tR\amachine\x12\x0e\n" +
 	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x18\n" +
-	"\agateway\x18\x03 \x01(\tR\agatewayB3Z1github.com/openconfig/bootz/dhcp/proto/dhcpconfigb\x06proto3"
+	"\agateway\x18\x03 \x01(\tR\agateway\x12\x1d\n" +
+	"\n" +
+	"circuit_id\x18\x04 \x01(\tR\tcircuitId\x12\x1b\n" +
+	"\tremote_id\x18\x05 \x01(\tR\bremoteId\x12\x1b\n" +
+	"\tboot_file\x18\x06 \x01(\tR\bbootFile\x12-\n" +
+	"\x12alternate_machines\x18\a \x03(\tR\x11alternateMachines\"\x86\x01\n" +
+	"\x04Pool\x12\x16\n" +
+	"\x06subnet\x18\x01 \x01(\tR\x06subnet\x12\x1e\n" +
+	"\n" +
+	"exclusions\x18\x02 \x03(\tR\n" +
+	"exclusions\x12\x18\n" +
+	"\agateway\x18\x03 \x01(\tR\agateway\x12,\n" +
+	"\x12lease_time_seconds\x18\x04 \x01(\rR\x10leaseTimeSeconds\"\xe6\x01\n" +
+	"\rBootzRedirect\x12!\n" +
+	"\fvendor_class\x18\x01 \x01(\tR\vvendorClass\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x1d\n" +
+	"\n" +
+	"mac_prefix\x18\x03 \x01(\tR\tmacPrefix\x12\x1d\n" +
+	"\n" +
+	"circuit_id\x18\x04 \x01(\tR\tcircuitId\x12\x1d\n" +
+	"\n" +
+	"bootz_urls\x18\x05 \x03(\tR\tbootzUrls\x12\x1b\n" +
+	"\tremote_id\x18\x06 \x01(\tR\bremoteId\x12\x1b\n" +
+	"\tboot_file\x18\a \x01(\tR\bbootFile\"\x96\x01\n" +
+	"\x05Scope\x12\x16\n" +
+	"\x06subnet\x18\x01 \x01(\tR\x06subnet\x12\x16\n" +
+	"\x06router\x18\x02 \x01(\tR\x06router\x12\x10\n" +
+	"\x03dns\x18\x03 \x03(\tR\x03dns\x12,\n" +
+	"\x12lease_time_seconds\x18\x04 \x01(\rR\x10leaseTimeSeconds\x12\x1d\n" +
+	"\n" +
+	"bootz_urls\x18\x05 \x03(\tR\tbootzUrlsB3Z1github.com/openconfig/bootz/dhcp/proto/dhcpconfigb\x06proto3"
 
 var (
 	file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescOnce sync.Once
@@ -178,18 +666,29 @@ func file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescGZIP()
 	return file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDescData
 }
 
-var file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_goTypes = []any{
-	(*Config)(nil), // 0: dhcpconfig.Config
-	(*Record)(nil), // 1: dhcpconfig.Record
+	(Config_Mode)(0),      // 0: dhcpconfig.Config.Mode
+	(*Config)(nil),        // 1: dhcpconfig.Config
+	(*AdminTLS)(nil),      // 2: dhcpconfig.AdminTLS
+	(*Record)(nil),        // 3: dhcpconfig.Record
+	(*Pool)(nil),          // 4: dhcpconfig.Pool
+	(*BootzRedirect)(nil), // 5: dhcpconfig.BootzRedirect
+	(*Scope)(nil),         // 6: dhcpconfig.Scope
 }
 var file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_depIdxs = []int32{
-	1, // 0: dhcpconfig.Config.records:type_name -> dhcpconfig.Record
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	3, // 0: dhcpconfig.Config.records:type_name -> dhcpconfig.Record
+	4, // 1: dhcpconfig.Config.pools:type_name -> dhcpconfig.Pool
+	5, // 2: dhcpconfig.Config.bootz_redirects:type_name -> dhcpconfig.BootzRedirect
+	6, // 3: dhcpconfig.Config.scopes:type_name -> dhcpconfig.Scope
+	0, // 4: dhcpconfig.Config.mode:type_name -> dhcpconfig.Config.Mode
+	2, // 5: dhcpconfig.Config.admin_tls:type_name -> dhcpconfig.AdminTLS
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_init() }
@@ -202,13 +701,14 @@ func file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDesc), len(file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_goTypes,
 		DependencyIndexes: file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_depIdxs,
+		EnumInfos:         file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_enumTypes,
 		MessageInfos:      file_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto_msgTypes,
 	}.Build()
 	File_github_com_openconfig_bootz_dhcp_proto_dhcpconfig_proto = out.File
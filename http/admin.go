@@ -0,0 +1,91 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxUploadSize is the maximum size of uploaded files if AdminOpts.MaxUploadSize is not set.
+const DefaultMaxUploadSize = 8 << 30
+
+// AdminOpts configures the admin listener of the HTTP server, which is separate from the listener the devices
+// download images from. It serves the metrics exported with expvar at "/debug/vars". The admin requests must present
+// Token as a bearer token.
+type AdminOpts struct {
+	Address string
+	Token   string
+	// Upload, if set, handles the requests to "/upload/", with the prefix stripped.
+	Upload http.Handler
+	// MaxUploadSize is the maximum size in bytes of the uploaded files, DefaultMaxUploadSize if 0.
+	MaxUploadSize int64
+}
+
+// newAdminServer returns the admin server of the configuration, which serves HTTPS if the image server does.
+func newAdminServer(conf *Opts) (*http.Server, error) {
+	admin := conf.Admin
+	if admin.Address == "" {
+		return nil, fmt.Errorf("admin address not specified")
+	}
+	if admin.Token == "" {
+		return nil, fmt.Errorf("admin token not specified")
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	if admin.Upload != nil {
+		maxSize := admin.MaxUploadSize
+		if maxSize == 0 {
+			maxSize = DefaultMaxUploadSize
+		}
+		mux.Handle("/upload/", http.StripPrefix("/upload", maxBytes(maxSize, admin.Upload)))
+	}
+	srv := &http.Server{Addr: admin.Address, Handler: authenticate(admin.Token, mux)}
+	if conf.HTTPS {
+		if conf.TLSConfig == nil {
+			return nil, fmt.Errorf("https requires a TLS config")
+		}
+		srv.TLSConfig = conf.TLSConfig.Clone()
+	}
+	return srv, nil
+}
+
+// authenticate refuses the requests which do not present the bearer token.
+func authenticate(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// maxBytes limits the size of the request bodies.
+func maxBytes(n int64, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > n {
+			http.Error(w, fmt.Sprintf("request body larger than %d bytes", n), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -16,8 +16,10 @@
 package http
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"sync"
 
@@ -27,12 +29,35 @@ import (
 type Opts struct {
 	Address string
 	Folder  string
+	// Admin, if set, serves the admin requests, such as the uploads and the metrics, on a separate listener.
+	Admin *AdminOpts
+	// OnDownload, if set, is called after each image download.
+	OnDownload func(*Download)
+	// HTTPS makes the server serve HTTPS with TLSConfig. The Bootz server sets TLSConfig, with a certificate issued
+	// from its trust anchor, if it is not set.
+	HTTPS     bool
+	TLSConfig *tls.Config
+	// Limits, if set, limits the concurrent image downloads and their bandwidth.
+	Limits *Limits
+	// URLVerifier, if set, requires the image URLs to be signed for a serial number, which downloads are attributed to.
+	URLVerifier URLVerifier
+	// RequireClientCert requires HTTPS clients to present a certificate, such as their IDevID, verified against the
+	// client CAs of TLSConfig.
+	RequireClientCert bool
 }
 
 func (*Opts) IsBootzServerOpts() {}
 
+// URLVerifier verifies signed image URLs.
+type URLVerifier interface {
+	// Verify verifies the signature of the URL, given by its escaped path and its query, and returns the serial number
+	// it is signed for.
+	Verify(path string, query url.Values) (string, error)
+}
+
 type Server struct {
 	server *http.Server
+	admin  *http.Server
 }
 
 var instance *Server = nil
@@ -54,23 +79,75 @@ func Start(conf *Opts) error {
 		return fmt.Errorf("folder is not accessible: %v", err)
 	}
 
-	fs := http.FileServer(http.Dir(conf.Folder))
-	mux := http.NewServeMux()
-	mux.Handle("/", fs)
-	srv := &http.Server{Addr: conf.Address, Handler: mux}
-	instance = &Server{server: srv}
-
-	go func() {
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Exitf("Error starting http server: %v", err)
+	srv, err := newServer(conf)
+	if err != nil {
+		return err
+	}
+	var admin *http.Server
+	if conf.Admin != nil {
+		if admin, err = newAdminServer(conf); err != nil {
+			return err
 		}
-	}()
+	}
+	scheme := "http"
+	if conf.HTTPS {
+		scheme = "https"
+	}
+	instance = &Server{server: srv, admin: admin}
 
-	log.Infof("Serving http at address %q for folder %q", conf.Address, conf.Folder)
+	go serve(srv, conf.HTTPS)
+	log.Infof("Serving %v at address %q for folder %q", scheme, conf.Address, conf.Folder)
+	if admin != nil {
+		go serve(admin, conf.HTTPS)
+		log.Infof("Serving %v admin requests at address %q", scheme, conf.Admin.Address)
+	}
 
 	return nil
 }
 
+func serve(srv *http.Server, https bool) {
+	var err error
+	if https {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		log.Exitf("Error starting http server: %v", err)
+	}
+}
+
+// newServer returns the HTTP server of the configuration, which serves HTTPS if srv.TLSConfig is set.
+func newServer(conf *Opts) (*http.Server, error) {
+	mux, err := newMux(conf)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: conf.Address, Handler: mux}
+	if conf.HTTPS {
+		if conf.TLSConfig == nil {
+			return nil, fmt.Errorf("https requires a TLS config")
+		}
+		srv.TLSConfig = conf.TLSConfig.Clone()
+		if conf.RequireClientCert {
+			srv.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if conf.RequireClientCert {
+		return nil, fmt.Errorf("client certificates can only be required over https")
+	}
+	return srv, nil
+}
+
+func newMux(conf *Opts) (*http.ServeMux, error) {
+	images, err := newImageHandler(conf)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", images)
+	return mux, nil
+}
+
 // Stop stops the http server.
 func Stop() {
 	lock.Lock()
@@ -78,6 +155,9 @@ func Stop() {
 
 	if instance != nil {
 		instance.server.Close()
+		if instance.admin != nil {
+			instance.admin.Close()
+		}
 	}
 	instance = nil
 }
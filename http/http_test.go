@@ -0,0 +1,254 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
+	bootztls "github.com/openconfig/bootz/common/tls"
+)
+
+func TestHTTPS(t *testing.T) {
+	ca, caKey, err := ownercertificate.NewRSACertificate("Bootz Trust Anchor", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idevidCA, idevidCAKey, err := ownercertificate.NewRSACertificate("Vendor IDevID CA", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idevid, idevidKey, err := ownercertificate.NewRSACertificate("Vendor IDevID", "1234", idevidCA, idevidCAKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(idevidCA)
+	tlsConfig, err := bootztls.TLSConfiguration(&bootztls.Opts{
+		CAPrivateKey:      caKey,
+		CACert:            ca,
+		IPAddress:         net.ParseIP("127.0.0.1"),
+		ClientCAs:         clientCAs,
+		ServerCertSubject: &pkix.Name{CommonName: "Image Server"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "os.bin"), []byte(image), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	clientCert := tls.Certificate{Certificate: [][]byte{idevid.Raw}, PrivateKey: idevidKey}
+
+	tests := []struct {
+		desc       string
+		opts       *Opts
+		clientCert bool
+		wantErr    bool
+		wantGetErr bool
+	}{{
+		desc: "HTTPS",
+		opts: &Opts{Folder: folder, HTTPS: true, TLSConfig: tlsConfig},
+	}, {
+		desc:       "Client certificate required",
+		opts:       &Opts{Folder: folder, HTTPS: true, TLSConfig: tlsConfig, RequireClientCert: true},
+		clientCert: true,
+	}, {
+		desc:       "Missing client certificate",
+		opts:       &Opts{Folder: folder, HTTPS: true, TLSConfig: tlsConfig, RequireClientCert: true},
+		wantGetErr: true,
+	}, {
+		desc:    "Missing TLS config",
+		opts:    &Opts{Folder: folder, HTTPS: true},
+		wantErr: true,
+	}, {
+		desc:    "Client certificate over HTTP",
+		opts:    &Opts{Folder: folder, RequireClientCert: true},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			srv, err := newServer(test.opts)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newServer() err = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			ts := httptest.NewUnstartedServer(srv.Handler)
+			ts.TLS = srv.TLSConfig
+			ts.StartTLS()
+			defer ts.Close()
+
+			clientTLS := &tls.Config{RootCAs: roots}
+			if test.clientCert {
+				clientTLS.Certificates = []tls.Certificate{clientCert}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+			resp, err := client.Get(ts.URL + "/os.bin")
+			if (err != nil) != test.wantGetErr {
+				t.Fatalf("Get() err = %v, want error %v", err, test.wantGetErr)
+			}
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil || string(body) != image {
+				t.Errorf("Get() body = %q, %v, want %q", body, err, image)
+			}
+		})
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	upload := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	tests := []struct {
+		desc       string
+		admin      *AdminOpts
+		token      string
+		body       string
+		wantErr    bool
+		wantStatus int
+	}{{
+		desc:       "Upload",
+		admin:      &AdminOpts{Address: ":0", Token: "secret", Upload: upload},
+		token:      "secret",
+		body:       image,
+		wantStatus: http.StatusCreated,
+	}, {
+		desc:       "Missing token",
+		admin:      &AdminOpts{Address: ":0", Token: "secret", Upload: upload},
+		body:       image,
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		desc:       "Wrong token",
+		admin:      &AdminOpts{Address: ":0", Token: "secret", Upload: upload},
+		token:      "guess",
+		body:       image,
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		desc:       "Upload too large",
+		admin:      &AdminOpts{Address: ":0", Token: "secret", Upload: upload, MaxUploadSize: 4},
+		token:      "secret",
+		body:       image,
+		wantStatus: http.StatusRequestEntityTooLarge,
+	}, {
+		desc:       "Uploads disabled",
+		admin:      &AdminOpts{Address: ":0", Token: "secret"},
+		token:      "secret",
+		body:       image,
+		wantStatus: http.StatusNotFound,
+	}, {
+		desc:    "Missing token configuration",
+		admin:   &AdminOpts{Address: ":0", Upload: upload},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			srv, err := newAdminServer(&Opts{Folder: t.TempDir(), Admin: test.admin})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("newAdminServer() err = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			r := httptest.NewRequest(http.MethodPut, "/upload/os/1.0/os.bin", strings.NewReader(test.body))
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Errorf("PUT status = %v, want %v", w.Code, test.wantStatus)
+			}
+		})
+	}
+
+	// The image server does not serve the uploads.
+	srv, err := newServer(&Opts{Folder: t.TempDir(), Admin: &AdminOpts{Address: ":0", Token: "secret", Upload: upload}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPut, "/upload/os/1.0/os.bin", strings.NewReader(image))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, r)
+	if w.Code == http.StatusCreated {
+		t.Errorf("PUT to the image server status = %v, want an error", w.Code)
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	conf := &Opts{Folder: t.TempDir(), Admin: &AdminOpts{Address: ":0", Token: "secret"}}
+	admin, err := newAdminServer(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := newServer(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		desc       string
+		handler    http.Handler
+		token      string
+		wantStatus int
+	}{{
+		desc:       "Admin listener",
+		handler:    admin.Handler,
+		token:      "secret",
+		wantStatus: http.StatusOK,
+	}, {
+		desc:       "Missing token",
+		handler:    admin.Handler,
+		wantStatus: http.StatusUnauthorized,
+	}, {
+		desc:       "Image server",
+		handler:    images.Handler,
+		wantStatus: http.StatusNotFound,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			test.handler.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Errorf("GET /debug/vars status = %v, want %v", w.Code, test.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+// Download describes a completed image download.
+type Download struct {
+	Path       string
+	RemoteAddr string
+	// Serial is the serial number the URL is signed for, empty if URLs are not signed.
+	Serial string
+	// Range is the Range header of the request, empty for a full download.
+	Range    string
+	Status   int
+	Bytes    int64
+	Duration time.Duration
+}
+
+// digest is the digests of a file, valid as long as its size and modification time do not change.
+type digest struct {
+	size    int64
+	modTime time.Time
+	// done is closed once the digests are computed, or err is set.
+	done   chan struct{}
+	sha256 []byte
+	sha512 []byte
+	err    error
+}
+
+// imageHandler serves the image files of a folder with support for range requests and integrity headers.
+type imageHandler struct {
+	root       http.FileSystem
+	dirs       http.Handler
+	verifier   URLVerifier
+	limiter    *limiter
+	onDownload func(*Download)
+
+	mu      sync.Mutex
+	digests map[string]*digest
+}
+
+func newImageHandler(conf *Opts) (*imageHandler, error) {
+	root := http.Dir(conf.Folder)
+	h := &imageHandler{
+		root:       root,
+		dirs:       http.FileServer(root),
+		verifier:   conf.URLVerifier,
+		onDownload: conf.OnDownload,
+		digests:    map[string]*digest{},
+	}
+	if conf.Limits != nil {
+		var err error
+		if h.limiter, err = newLimiter(conf.Limits); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// ServeHTTP serves the image file of the request. The response has a strong ETag, the SHA-256 digest of the file,
+// which conditional and range requests are evaluated against so that interrupted downloads can be resumed with
+// If-Range. The Digest and Repr-Digest headers carry the SHA-256 and SHA-512 digests of the file, and
+// Content-Digest too for full responses. Downloads are subject to the download limits.
+func (h *imageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var serial string
+	if h.verifier != nil {
+		var err error
+		if serial, err = h.verifier.Verify(r.URL.EscapedPath(), r.URL.Query()); err != nil {
+			log.Warningf("Refused download of %v by %v: %v", r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+	name := path.Clean("/" + r.URL.Path)
+	f, err := h.root.Open(name)
+	if err != nil {
+		h.notFile(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		h.notFile(w, r)
+		return
+	}
+	// The digests are computed without holding a download slot.
+	d, err := h.digest(r.Context(), name, f, info)
+	if err != nil {
+		log.Warningf("Unable to compute the digest of %v: %v", name, err)
+		http.Error(w, "unable to read file", http.StatusInternalServerError)
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "unable to read file", http.StatusInternalServerError)
+		return
+	}
+	if h.limiter != nil && r.Method == http.MethodGet {
+		lw, release, ok := h.limiter.acquire(w, r)
+		if !ok {
+			log.Warningf("Refused download of %v by %v: download limits reached", name, r.RemoteAddr)
+			return
+		}
+		defer release()
+		w = lw
+	}
+
+	sha256B64, sha512B64 := base64.StdEncoding.EncodeToString(d.sha256), base64.StdEncoding.EncodeToString(d.sha512)
+	hdr := w.Header()
+	hdr.Set("ETag", `"`+hex.EncodeToString(d.sha256)+`"`)
+	hdr.Set("Digest", fmt.Sprintf("SHA-256=%v,SHA-512=%v", sha256B64, sha512B64))
+	hdr.Set("Repr-Digest", fmt.Sprintf("sha-256=:%v:, sha-512=:%v:", sha256B64, sha512B64))
+	hdr.Set("Content-Type", "application/octet-stream")
+
+	// The content is the full file only for 200 responses.
+	cw := &countingWriter{ResponseWriter: w, contentDigest: hdr.Get("Repr-Digest")}
+	start := time.Now()
+	http.ServeContent(cw, r, info.Name(), info.ModTime(), f)
+	if r.Method != http.MethodGet {
+		return
+	}
+	dl := &Download{
+		Path:       name,
+		RemoteAddr: r.RemoteAddr,
+		Serial:     serial,
+		Range:      r.Header.Get("Range"),
+		Status:     cw.status,
+		Bytes:      cw.bytes,
+		Duration:   time.Since(start),
+	}
+	log.Infof("Served %v to %v (serial %q): status %v, range %q, %d of %d bytes in %v", dl.Path, dl.RemoteAddr, dl.Serial, dl.Status, dl.Range, dl.Bytes, info.Size(), dl.Duration)
+	if h.onDownload != nil {
+		h.onDownload(dl)
+	}
+}
+
+// notFile serves a request for a path which is not a file. Directories are only listed if URLs are not signed, so
+// that the images cannot be enumerated.
+func (h *imageHandler) notFile(w http.ResponseWriter, r *http.Request) {
+	if h.verifier != nil {
+		http.NotFound(w, r)
+		return
+	}
+	// Let the file server list the directory or report the error.
+	h.dirs.ServeHTTP(w, r)
+}
+
+// digest returns the digests of the file, which are computed once for each version of the file: concurrent requests
+// of a file wait for the digests computed for the first one.
+func (h *imageHandler) digest(ctx context.Context, name string, f io.Reader, info os.FileInfo) (*digest, error) {
+	h.mu.Lock()
+	d, ok := h.digests[name]
+	if ok && d.size == info.Size() && d.modTime.Equal(info.ModTime()) {
+		h.mu.Unlock()
+		select {
+		case <-d.done:
+			return d, d.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	d = &digest{size: info.Size(), modTime: info.ModTime(), done: make(chan struct{})}
+	h.digests[name] = d
+	h.mu.Unlock()
+
+	h256, h512 := sha256.New(), sha512.New()
+	if _, d.err = io.Copy(io.MultiWriter(h256, h512), f); d.err == nil {
+		d.sha256, d.sha512 = h256.Sum(nil), h512.Sum(nil)
+	} else {
+		// The next request tries again.
+		h.mu.Lock()
+		if h.digests[name] == d {
+			delete(h.digests, name)
+		}
+		h.mu.Unlock()
+	}
+	close(d.done)
+	return d, d.err
+}
+
+// countingWriter records the status and the number of body bytes of a response, and sets the Content-Digest header
+// of 200 responses.
+type countingWriter struct {
+	http.ResponseWriter
+	contentDigest string
+	status        int
+	bytes         int64
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status == http.StatusOK {
+		w.Header().Set("Content-Digest", w.contentDigest)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom passes the content to the ReadFrom of the response writer, if any, which sends files with sendfile.
+func (w *countingWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.bytes += n
+	return n, err
+}
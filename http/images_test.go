@@ -0,0 +1,307 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const image = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func newTestHandler(t *testing.T, opts *Opts) http.Handler {
+	t.Helper()
+	opts.Folder = t.TempDir()
+	if err := os.WriteFile(filepath.Join(opts.Folder, "os.bin"), []byte(image), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mux, err := newMux(opts)
+	if err != nil {
+		t.Fatalf("newMux() err = %v, want nil", err)
+	}
+	return mux
+}
+
+// get serves a GET request of the path synchronously, so that the download records are complete when it returns.
+func get(t *testing.T, h http.Handler, path string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	resp := w.Result()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestImageHeaders(t *testing.T) {
+	h := newTestHandler(t, &Opts{})
+	h256, h512 := sha256.Sum256([]byte(image)), sha512.Sum512([]byte(image))
+	b256, b512 := base64.StdEncoding.EncodeToString(h256[:]), base64.StdEncoding.EncodeToString(h512[:])
+
+	resp, body := get(t, h, "/os.bin", nil)
+	if resp.StatusCode != http.StatusOK || body != image {
+		t.Fatalf("GET = %v %q, want 200 %q", resp.StatusCode, body, image)
+	}
+	want := map[string]string{
+		"Etag":           `"` + hex.EncodeToString(h256[:]) + `"`,
+		"Accept-Ranges":  "bytes",
+		"Digest":         "SHA-256=" + b256 + ",SHA-512=" + b512,
+		"Repr-Digest":    "sha-256=:" + b256 + ":, sha-512=:" + b512 + ":",
+		"Content-Digest": "sha-256=:" + b256 + ":, sha-512=:" + b512 + ":",
+	}
+	got := map[string]string{}
+	for k := range want {
+		got[k] = resp.Header.Get(k)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GET headers diff (-want, +got):\n%s", diff)
+	}
+
+	resp, _ = get(t, h, "/os.bin", map[string]string{"If-None-Match": want["Etag"]})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with matching If-None-Match status = %v, want %v", resp.StatusCode, http.StatusNotModified)
+	}
+}
+
+func TestImageRanges(t *testing.T) {
+	var downloads []*Download
+	h := newTestHandler(t, &Opts{OnDownload: func(d *Download) { downloads = append(downloads, d) }})
+	first, _ := get(t, h, "/os.bin", map[string]string{"Range": "bytes=0-9"})
+	etag := first.Header.Get("ETag")
+
+	tests := []struct {
+		desc       string
+		header     map[string]string
+		wantStatus int
+		wantBody   string
+	}{{
+		desc:       "Partial download",
+		header:     map[string]string{"Range": "bytes=0-9"},
+		wantStatus: http.StatusPartialContent,
+		wantBody:   image[:10],
+	}, {
+		desc:       "Resumed download",
+		header:     map[string]string{"Range": "bytes=10-", "If-Range": etag},
+		wantStatus: http.StatusPartialContent,
+		wantBody:   image[10:],
+	}, {
+		desc:       "Resumed download of a changed image",
+		header:     map[string]string{"Range": "bytes=10-", "If-Range": `"0000"`},
+		wantStatus: http.StatusOK,
+		wantBody:   image,
+	}, {
+		desc:       "Unsatisfiable range",
+		header:     map[string]string{"Range": "bytes=100-"},
+		wantStatus: http.StatusRequestedRangeNotSatisfiable,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			downloads = nil
+			resp, body := get(t, h, "/os.bin", test.header)
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("GET status = %v, want %v", resp.StatusCode, test.wantStatus)
+			}
+			if test.wantBody != "" && body != test.wantBody {
+				t.Errorf("GET body = %q, want %q", body, test.wantBody)
+			}
+			if got, want := resp.Header.Get("Content-Digest") != "", test.wantStatus == http.StatusOK; got != want {
+				t.Errorf("GET has Content-Digest = %v, want %v", got, want)
+			}
+			if len(downloads) != 1 {
+				t.Fatalf("got %d download records, want 1", len(downloads))
+			}
+			d := downloads[0]
+			if d.Path != "/os.bin" || d.Status != test.wantStatus || d.Range != test.header["Range"] {
+				t.Errorf("download record = %+v, want path /os.bin, status %v and range %q", d, test.wantStatus, test.header["Range"])
+			}
+			if test.wantBody != "" && d.Bytes != int64(len(test.wantBody)) {
+				t.Errorf("download record bytes = %v, want %v", d.Bytes, len(test.wantBody))
+			}
+		})
+	}
+}
+
+func TestImageChanged(t *testing.T) {
+	opts := &Opts{}
+	h := newTestHandler(t, opts)
+	file := filepath.Join(opts.Folder, "os.bin")
+	before, _ := get(t, h, "/os.bin", nil)
+	if err := os.WriteFile(file, []byte(strings.ToUpper(image)+"!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	after, body := get(t, h, "/os.bin", map[string]string{"Range": "bytes=10-", "If-Range": before.Header.Get("ETag")})
+	if after.StatusCode != http.StatusOK || body != strings.ToUpper(image)+"!" {
+		t.Errorf("resumed GET of a changed image = %v %q, want the full new image", after.StatusCode, body)
+	}
+	if after.Header.Get("ETag") == before.Header.Get("ETag") {
+		t.Errorf("ETag of a changed image did not change")
+	}
+}
+
+// fakeVerifier accepts the URLs whose token is "valid", signed for the serial number of the query.
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(path string, query url.Values) (string, error) {
+	if query.Get("token") != "valid" {
+		return "", fmt.Errorf("invalid token for %v", path)
+	}
+	return query.Get("serial"), nil
+}
+
+func TestSignedURLs(t *testing.T) {
+	var downloads []*Download
+	h := newTestHandler(t, &Opts{
+		URLVerifier: fakeVerifier{},
+		OnDownload:  func(d *Download) { downloads = append(downloads, d) },
+	})
+
+	tests := []struct {
+		desc       string
+		path       string
+		wantStatus int
+		wantSerial string
+	}{{
+		desc:       "Signed",
+		path:       "/os.bin?serial=SN123&token=valid",
+		wantStatus: http.StatusOK,
+		wantSerial: "SN123",
+	}, {
+		desc:       "Unsigned",
+		path:       "/os.bin",
+		wantStatus: http.StatusForbidden,
+	}, {
+		desc:       "Invalid signature",
+		path:       "/os.bin?serial=SN123&token=invalid",
+		wantStatus: http.StatusForbidden,
+	}, {
+		desc:       "Directory listing",
+		path:       "/?serial=SN123&token=valid",
+		wantStatus: http.StatusNotFound,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			downloads = nil
+			resp, _ := get(t, h, test.path, nil)
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("GET status = %v, want %v", resp.StatusCode, test.wantStatus)
+			}
+			if test.wantSerial == "" {
+				if len(downloads) != 0 {
+					t.Errorf("got download records %v, want none", downloads)
+				}
+				return
+			}
+			if len(downloads) != 1 || downloads[0].Serial != test.wantSerial || downloads[0].Bytes != int64(len(image)) {
+				t.Errorf("download records = %v, want one of %d bytes for serial %q", downloads, len(image), test.wantSerial)
+			}
+		})
+	}
+}
+
+func TestDigestComputedOnce(t *testing.T) {
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "os.bin"), []byte(image), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(folder, "os.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := newImageHandler(&Opts{Folder: folder})
+	if err != nil {
+		t.Fatalf("newImageHandler() err = %v, want nil", err)
+	}
+
+	// The second request waits for the digests the first one is computing, without reading the file.
+	pr, pw := io.Pipe()
+	first := make(chan *digest)
+	go func() {
+		d, _ := h.digest(context.Background(), "/os.bin", pr, info)
+		first <- d
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		h.mu.Lock()
+		_, started := h.digests["/os.bin"]
+		h.mu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("digest of the first request not started")
+		}
+	}
+	second := make(chan *digest)
+	go func() {
+		d, err := h.digest(context.Background(), "/os.bin", iotest.ErrReader(fmt.Errorf("file read again")), info)
+		if err != nil {
+			t.Errorf("digest() of the second request err = %v, want nil", err)
+		}
+		second <- d
+	}()
+	io.WriteString(pw, image)
+	pw.Close()
+	d1, d2 := <-first, <-second
+	want := sha256.Sum256([]byte(image))
+	if d1 != d2 || string(d1.sha256) != string(want[:]) {
+		t.Errorf("digests of the requests = %+v and %+v, want the same digests of the image", d1, d2)
+	}
+}
+
+// readFromRecorder records the calls to ReadFrom, through which a connection sends files with sendfile.
+type readFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom int
+}
+
+func (w *readFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	w.readFrom++
+	return io.Copy(w.ResponseRecorder, r)
+}
+
+func TestImageReadFrom(t *testing.T) {
+	for _, limits := range []*Limits{nil, {Bandwidth: 1 << 20}} {
+		mux := newTestHandler(t, &Opts{Limits: limits})
+		w := &readFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/os.bin", nil))
+		if w.Code != http.StatusOK || w.Body.String() != image {
+			t.Errorf("GET with limits %+v = %v %q, want 200 %q", limits, w.Code, w.Body.String(), image)
+		}
+		if w.readFrom == 0 {
+			t.Errorf("GET with limits %+v did not send the file with ReadFrom", limits)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultQueueTimeout = 30 * time.Second
+	defaultRetryAfter   = 10 * time.Second
+	// burstBytes is the token bucket size of the bandwidth limits, and the size of the throttled writes.
+	burstBytes = 32 << 10
+)
+
+// Metrics, exported with expvar.
+var (
+	// ActiveDownloads is the number of downloads in progress.
+	ActiveDownloads = expvar.NewInt("bootz_http_active_downloads")
+	// QueuedDownloads is the number of downloads waiting for a download slot.
+	QueuedDownloads = expvar.NewInt("bootz_http_queued_downloads")
+	// RejectedDownloads counts the downloads refused with 503 Service Unavailable.
+	RejectedDownloads = expvar.NewInt("bootz_http_rejected_downloads")
+)
+
+// Limits limits the concurrent downloads and their bandwidth. Zero values mean no limit.
+type Limits struct {
+	// MaxDownloads is the maximum number of concurrent downloads.
+	MaxDownloads int
+	// MaxClientDownloads is the maximum number of concurrent downloads of a client IP address. Additional downloads
+	// of the client are refused.
+	MaxClientDownloads int
+	// MaxQueued is the maximum number of downloads waiting up to QueueTimeout for one of the MaxDownloads slots.
+	// Additional downloads are refused.
+	MaxQueued int
+	// QueueTimeout is how long a download waits for a slot before it is refused. Defaults to 30 seconds.
+	QueueTimeout time.Duration
+	// RetryAfter is the Retry-After delay of refused downloads. Defaults to 10 seconds.
+	RetryAfter time.Duration
+	// Bandwidth is the total bandwidth of the downloads, in bytes per second.
+	Bandwidth int64
+	// ClientBandwidth is the bandwidth of the downloads of a client IP address, in bytes per second.
+	ClientBandwidth int64
+}
+
+// downloadClient is the state of a client with downloads in progress.
+type downloadClient struct {
+	active    int
+	bandwidth *rate.Limiter
+}
+
+// limiter enforces the download limits.
+type limiter struct {
+	limits    Limits
+	slots     chan struct{}
+	bandwidth *rate.Limiter
+
+	mu      sync.Mutex
+	queued  int
+	clients map[string]*downloadClient
+}
+
+func newLimiter(limits *Limits) (*limiter, error) {
+	if limits.MaxDownloads < 0 || limits.MaxClientDownloads < 0 || limits.MaxQueued < 0 || limits.Bandwidth < 0 || limits.ClientBandwidth < 0 {
+		return nil, fmt.Errorf("download limits must not be negative")
+	}
+	l := &limiter{limits: *limits, clients: map[string]*downloadClient{}}
+	if l.limits.QueueTimeout <= 0 {
+		l.limits.QueueTimeout = defaultQueueTimeout
+	}
+	if l.limits.RetryAfter <= 0 {
+		l.limits.RetryAfter = defaultRetryAfter
+	}
+	if l.limits.MaxDownloads > 0 {
+		l.slots = make(chan struct{}, l.limits.MaxDownloads)
+	}
+	if l.limits.Bandwidth > 0 {
+		l.bandwidth = rate.NewLimiter(rate.Limit(l.limits.Bandwidth), burstBytes)
+	}
+	return l, nil
+}
+
+// acquire admits a download of the client at the remote address, waiting for a free download slot if needed. It
+// returns the writer the response must be written to and the function releasing the download, or false if the
+// download was refused with 503 Service Unavailable.
+func (l *limiter) acquire(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func(), bool) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	c, ok := l.reserveClient(ip)
+	if !ok {
+		l.refuse(w, "too many downloads from %v", ip)
+		return nil, nil, false
+	}
+	if !l.reserveSlot(r.Context()) {
+		l.releaseClient(ip)
+		l.refuse(w, "too many downloads")
+		return nil, nil, false
+	}
+	ActiveDownloads.Add(1)
+	release := func() {
+		ActiveDownloads.Add(-1)
+		if l.slots != nil {
+			<-l.slots
+		}
+		l.releaseClient(ip)
+	}
+	var limiters []*rate.Limiter
+	for _, b := range []*rate.Limiter{l.bandwidth, c.bandwidth} {
+		if b != nil {
+			limiters = append(limiters, b)
+		}
+	}
+	if len(limiters) == 0 {
+		return w, release, true
+	}
+	return &throttledWriter{ResponseWriter: w, ctx: r.Context(), limiters: limiters}, release, true
+}
+
+// refuse refuses a download with 503 Service Unavailable.
+func (l *limiter) refuse(w http.ResponseWriter, format string, args ...any) {
+	RejectedDownloads.Add(1)
+	w.Header().Set("Retry-After", strconv.Itoa(int(l.limits.RetryAfter.Round(time.Second)/time.Second)))
+	http.Error(w, fmt.Sprintf(format, args...), http.StatusServiceUnavailable)
+}
+
+// reserveClient counts a download of the client, unless it has reached its limit.
+func (l *limiter) reserveClient(ip string) (*downloadClient, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &downloadClient{}
+		if l.limits.ClientBandwidth > 0 {
+			c.bandwidth = rate.NewLimiter(rate.Limit(l.limits.ClientBandwidth), burstBytes)
+		}
+		l.clients[ip] = c
+	}
+	if l.limits.MaxClientDownloads > 0 && c.active >= l.limits.MaxClientDownloads {
+		return nil, false
+	}
+	c.active++
+	return c, true
+}
+
+func (l *limiter) releaseClient(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.clients[ip]
+	if !ok {
+		return
+	}
+	if c.active--; c.active <= 0 {
+		delete(l.clients, ip)
+	}
+}
+
+// reserveSlot takes a download slot, queueing for one if none is free.
+func (l *limiter) reserveSlot(ctx context.Context) bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	l.mu.Lock()
+	if l.limits.MaxQueued > 0 && l.queued >= l.limits.MaxQueued {
+		l.mu.Unlock()
+		return false
+	}
+	l.queued++
+	l.mu.Unlock()
+	QueuedDownloads.Add(1)
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+		QueuedDownloads.Add(-1)
+	}()
+
+	timer := time.NewTimer(l.limits.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// throttledWriter writes the response at the rate of the bandwidth limiters.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	limiters []*rate.Limiter
+}
+
+func (w *throttledWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := min(len(b), burstBytes)
+		for _, l := range w.limiters {
+			if err := l.WaitN(w.ctx, n); err != nil {
+				return written, err
+			}
+		}
+		m, err := w.ResponseWriter.Write(b[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// ReadFrom sends the content in bursts at the rate of the limiters. Each burst is passed to the ReadFrom of the
+// response writer as a limited reader of the source, so that the bursts of a file, such as the one of
+// http.ServeContent, are still sent with sendfile.
+func (w *throttledWriter) ReadFrom(r io.Reader) (int64, error) {
+	lr, ok := r.(*io.LimitedReader)
+	if !ok {
+		lr = &io.LimitedReader{R: r, N: math.MaxInt64}
+	}
+	var written int64
+	for lr.N > 0 {
+		burst := min(lr.N, burstBytes)
+		for _, l := range w.limiters {
+			if err := l.WaitN(w.ctx, int(burst)); err != nil {
+				return written, err
+			}
+		}
+		n, err := io.Copy(w.ResponseWriter, &io.LimitedReader{R: lr.R, N: burst})
+		lr.N -= n
+		written += n
+		if err != nil || n < burst {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// acquire acquires a download for the client address, and returns the release function or the refusal response.
+func acquire(t *testing.T, l *limiter, remoteAddr string) (func(), *httptest.ResponseRecorder) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/os.bin", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	_, release, ok := l.acquire(w, req)
+	if !ok {
+		return nil, w
+	}
+	return release, nil
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l, err := newLimiter(&Limits{MaxDownloads: 2, MaxClientDownloads: 1, QueueTimeout: time.Millisecond, RetryAfter: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("newLimiter() err = %v, want nil", err)
+	}
+	releaseA, _ := acquire(t, l, "10.0.0.1:1000")
+	if releaseA == nil {
+		t.Fatalf("acquire() of the first download was refused")
+	}
+	if _, refused := acquire(t, l, "10.0.0.1:1001"); refused == nil {
+		t.Fatalf("acquire() of a second download of the client was admitted")
+	} else if refused.Code != http.StatusServiceUnavailable || refused.Header().Get("Retry-After") != "5" {
+		t.Errorf("refused download = %v with Retry-After %q, want %v with Retry-After 5", refused.Code, refused.Header().Get("Retry-After"), http.StatusServiceUnavailable)
+	}
+	releaseB, _ := acquire(t, l, "10.0.0.2:1000")
+	if releaseB == nil {
+		t.Fatalf("acquire() of a download of another client was refused")
+	}
+	if release, _ := acquire(t, l, "10.0.0.3:1000"); release != nil {
+		t.Fatalf("acquire() beyond MaxDownloads was admitted")
+	}
+	releaseA()
+	releaseC, _ := acquire(t, l, "10.0.0.3:1000")
+	if releaseC == nil {
+		t.Fatalf("acquire() after a release was refused")
+	}
+	releaseB()
+	releaseC()
+	if len(l.clients) != 0 {
+		t.Errorf("limiter has %d clients after all releases, want 0", len(l.clients))
+	}
+}
+
+func TestLimiterQueue(t *testing.T) {
+	l, err := newLimiter(&Limits{MaxDownloads: 1, MaxQueued: 1, QueueTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("newLimiter() err = %v, want nil", err)
+	}
+	queued := QueuedDownloads.Value()
+	releaseA, _ := acquire(t, l, "10.0.0.1:1000")
+	if releaseA == nil {
+		t.Fatalf("acquire() of the first download was refused")
+	}
+	admitted := make(chan func())
+	go func() {
+		release, _ := acquire(t, l, "10.0.0.2:1000")
+		admitted <- release
+	}()
+	for deadline := time.Now().Add(5 * time.Second); QueuedDownloads.Value() != queued+1; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("QueuedDownloads = %v, want %v", QueuedDownloads.Value(), queued+1)
+		}
+	}
+	if release, _ := acquire(t, l, "10.0.0.3:1000"); release != nil {
+		t.Fatalf("acquire() with a full queue was admitted")
+	}
+	releaseA()
+	releaseB := <-admitted
+	if releaseB == nil {
+		t.Fatalf("acquire() of the queued download was refused")
+	}
+	releaseB()
+	if got := QueuedDownloads.Value(); got != queued {
+		t.Errorf("QueuedDownloads = %v after the queue drained, want %v", got, queued)
+	}
+
+	l, err = newLimiter(&Limits{MaxDownloads: 1, MaxQueued: 1, QueueTimeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("newLimiter() err = %v, want nil", err)
+	}
+	releaseA, _ = acquire(t, l, "10.0.0.1:1000")
+	defer releaseA()
+	if release, _ := acquire(t, l, "10.0.0.2:1000"); release != nil {
+		t.Errorf("acquire() after the queue timeout was admitted")
+	}
+}
+
+func TestLimiterUnboundedQueue(t *testing.T) {
+	// MaxQueued defaults to no limit: the downloads beyond the slots wait for one.
+	l, err := newLimiter(&Limits{MaxDownloads: 1, QueueTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("newLimiter() err = %v, want nil", err)
+	}
+	releaseA, _ := acquire(t, l, "10.0.0.1:1000")
+	if releaseA == nil {
+		t.Fatalf("acquire() of the first download was refused")
+	}
+	admitted := make(chan func(), 2)
+	for _, addr := range []string{"10.0.0.2:1000", "10.0.0.3:1000"} {
+		go func() {
+			release, _ := acquire(t, l, addr)
+			admitted <- release
+		}()
+	}
+	releaseA()
+	for range 2 {
+		release := <-admitted
+		if release == nil {
+			t.Fatalf("acquire() of a queued download was refused")
+		}
+		release()
+	}
+}
+
+func TestBandwidth(t *testing.T) {
+	folder := t.TempDir()
+	content := bytes.Repeat([]byte("x"), 3*burstBytes)
+	if err := os.WriteFile(filepath.Join(folder, "os.bin"), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mux, err := newMux(&Opts{Folder: folder, Limits: &Limits{Bandwidth: 4 * burstBytes}})
+	if err != nil {
+		t.Fatalf("newMux() err = %v, want nil", err)
+	}
+	start := time.Now()
+	resp, body := get(t, mux, "/os.bin", nil)
+	// The first burst is sent at once, the two others at 4 bursts per second.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("download took %v, want at least 400ms", elapsed)
+	}
+	if resp.StatusCode != http.StatusOK || len(body) != len(content) {
+		t.Errorf("GET = %v with %d bytes, want %v with %d bytes", resp.StatusCode, len(body), http.StatusOK, len(content))
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	if _, err := newLimiter(&Limits{MaxDownloads: -1}); err == nil {
+		t.Errorf("newLimiter() with a negative limit err = nil, want error")
+	}
+}
@@ -23,7 +23,8 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
-	"strings"
+	"sync"
+	"time"
 
 	ownershipvoucher "github.com/openconfig/bootz/common/ownership_voucher"
 
@@ -38,7 +39,19 @@ type InMemoryArtifactManager struct {
 	ownerCert       *x509.Certificate
 	ownerKey        crypto.PrivateKey
 	vendorCAPool    *x509.CertPool
-	controlCards    map[string]*cpb.ControlCard
+	// now returns the current time used to validate ownership vouchers.
+	now func() time.Time
+
+	mu           sync.RWMutex
+	controlCards map[string]*cpb.ControlCard
+}
+
+// controlCard returns the control card of the inventory with the serial number.
+func (m *InMemoryArtifactManager) controlCard(serial string) (*cpb.ControlCard, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cc, ok := m.controlCards[serial]
+	return cc, ok
 }
 
 // BootzServerTrustAnchorKeyPair returns the Bootz server trust anchor. This is the keypair that will generate the server's TLS certificate.
@@ -56,30 +69,42 @@ func (m *InMemoryArtifactManager) OwnershipVoucher(ctx context.Context, serial s
 	// We don't use the "vendor" argument because it is empty when the request is a ReportStatusRequest.
 	// For simplicity, we assume the serial numbers are unique within our inventory.
 	// For production usecase, you should maintain a list containing only the chassis that are being bootstrappped and match to that list to prevent serial number collision.
-	if v, ok := m.controlCards[serial]; ok {
-		ov, err := base64.StdEncoding.DecodeString(v.GetOwnershipVoucher())
-		if err != nil {
-			return nil, fmt.Errorf("base64 decoding failed: %v", err)
-		}
-		// We do not have the full trust chain to verify the ownership vouchers issued by the vendors.
-		unmarshaled, err := ownershipvoucher.Unmarshal(ov, nil)
-		if err != nil {
-			return nil, fmt.Errorf("unmarshalling failed: %v", err)
-		}
-		if !strings.EqualFold(unmarshaled.OV.SerialNumber, serial) {
-			return nil, fmt.Errorf("serial number does not match, got %v, want %v", unmarshaled.OV.SerialNumber, serial)
-		}
-		return ov, nil
+	if v, ok := m.controlCard(serial); ok {
+		// The voucher was validated at load time, but validate it again in case it has expired since.
+		return m.validateOwnershipVoucher(v)
 	}
 	return nil, fmt.Errorf("not found for serial number: %v", serial)
 }
 
+// validateOwnershipVoucher decodes the ownership voucher of the control card and validates it against the vendor CA pool.
+func (m *InMemoryArtifactManager) validateOwnershipVoucher(cc *cpb.ControlCard) ([]byte, error) {
+	ov, err := base64.StdEncoding.DecodeString(cc.GetOwnershipVoucher())
+	if err != nil {
+		return nil, fmt.Errorf("base64 decoding failed: %v", err)
+	}
+	if _, err := ownershipvoucher.Validate(ov, &ownershipvoucher.ValidateOpts{
+		VendorCAPool:    m.vendorCAPool,
+		SerialNumber:    cc.GetSerialNumber(),
+		Now:             m.now,
+		CheckRevocation: skipRevocationChecks,
+	}); err != nil {
+		return nil, fmt.Errorf("ownership voucher validation failed: %w", err)
+	}
+	return ov, nil
+}
+
+// skipRevocationChecks accepts the pinned domain cert of vouchers requiring revocation checks, which are the duty of
+// the device using the voucher rather than of the server handing it out (RFC 8366).
+func skipRevocationChecks(*x509.Certificate) error {
+	return nil
+}
+
 // PublicKey retrieves the EK or PPK public key of the chassis for use in the BootstrapStream challenge.
 func (m *InMemoryArtifactManager) PublicKey(ctx context.Context, serial string, vendor string) (crypto.PublicKey, epb.Key, error) {
 	// We don't use the "vendor" argument because it is empty when the request is a ReportStatusRequest.
 	// For simplicity, we assume the serial numbers are unique within our inventory.
 	// For production usecase, you should maintain a list containing only the chassis that are being bootstrappped and match to that list to prevent serial number collision.
-	if v, ok := m.controlCards[serial]; ok {
+	if v, ok := m.controlCard(serial); ok {
 		pubBytes, err := base64.StdEncoding.DecodeString(v.GetPublicKey())
 		if err != nil {
 			return nil, epb.Key_KEY_UNSPECIFIED, fmt.Errorf("failed to decode public key: %v", err)
@@ -124,7 +149,7 @@ func ParseCertKeyPair(pair *cpb.CertKeyPair) (*x509.Certificate, crypto.PrivateK
 // New returns a new in-memory artifact manager.
 func New(config *cpb.Config) (*InMemoryArtifactManager, error) {
 	var err error
-	am := &InMemoryArtifactManager{}
+	am := &InMemoryArtifactManager{now: time.Now}
 	am.trustAnchorCert, am.trustAnchorKey, err = ParseCertKeyPair(config.GetTrustAnchor())
 	if err != nil {
 		return nil, fmt.Errorf("trust anchor error: %v", err)
@@ -145,11 +170,29 @@ func New(config *cpb.Config) (*InMemoryArtifactManager, error) {
 		}
 		am.vendorCAPool.AddCert(cert)
 	}
-	am.controlCards = make(map[string]*cpb.ControlCard)
+	if err := am.UpdateControlCards(config); err != nil {
+		return nil, err
+	}
+	return am, nil
+}
+
+// UpdateControlCards replaces the control cards with the ones of the config. The ownership vouchers of the new control
+// cards are validated first, and the control cards are left unchanged if one is invalid.
+func (m *InMemoryArtifactManager) UpdateControlCards(config *cpb.Config) error {
+	controlCards := make(map[string]*cpb.ControlCard)
 	for _, c := range config.GetChassis() {
 		for _, cc := range c.GetControlCards() {
-			am.controlCards[cc.GetSerialNumber()] = cc
+			// Control cards without an ownership voucher can still bootstrap in insecure mode.
+			if cc.GetOwnershipVoucher() != "" {
+				if _, err := m.validateOwnershipVoucher(cc); err != nil {
+					return fmt.Errorf("control card %v: %v", cc.GetSerialNumber(), err)
+				}
+			}
+			controlCards[cc.GetSerialNumber()] = cc
 		}
 	}
-	return am, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.controlCards = controlCards
+	return nil
 }
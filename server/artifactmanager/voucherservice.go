@@ -0,0 +1,299 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifactmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	ownershipvoucher "github.com/openconfig/bootz/common/ownership_voucher"
+
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+const (
+	defaultRefreshBefore   = 24 * time.Hour
+	defaultRefreshInterval = time.Hour
+	defaultFetchTimeout    = 30 * time.Second
+	// maxVoucherSize bounds the size of a voucher returned by the voucher service.
+	maxVoucherSize = 1 << 20
+	// voucherMediaTypes are the voucher formats accepted from the voucher service.
+	voucherMediaTypes = "application/voucher-cms+json, application/voucher-cms+xml, application/voucher-cose+cbor"
+)
+
+// cachedVoucher is an ownership voucher fetched from the voucher service.
+type cachedVoucher struct {
+	raw    []byte
+	vendor string
+	// expiresOn is the zero time if the voucher never expires.
+	expiresOn time.Time
+}
+
+// VoucherServiceArtifactManager is an InMemoryArtifactManager which fetches the ownership vouchers that are not in the
+// inventory from a vendor voucher service. Fetched vouchers are validated, cached on disk until they expire and
+// refreshed before they do.
+type VoucherServiceArtifactManager struct {
+	*InMemoryArtifactManager
+	client          *http.Client
+	url             string
+	cacheDir        string
+	refreshBefore   time.Duration
+	refreshInterval time.Duration
+
+	mu       sync.Mutex
+	vouchers map[string]*cachedVoucher
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// OwnershipVoucher returns the ownership voucher for the given serial number and vendor.
+// Vouchers in the inventory take precedence over the ones from the voucher service.
+func (m *VoucherServiceArtifactManager) OwnershipVoucher(ctx context.Context, serial string, vendor string) ([]byte, error) {
+	if cc, ok := m.controlCard(serial); ok && cc.GetOwnershipVoucher() != "" {
+		return m.validateOwnershipVoucher(cc)
+	}
+	m.mu.Lock()
+	cached, ok := m.vouchers[serial]
+	m.mu.Unlock()
+	if !ok {
+		// The voucher may have been cached by a previous run.
+		if raw, err := os.ReadFile(m.cachePath(serial)); err == nil {
+			cached = &cachedVoucher{raw: raw, vendor: vendor}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			log.Warningf("Unable to read cached ownership voucher for serial number %v: %v", serial, err)
+		}
+	}
+	if cached != nil {
+		// Vendor is empty for ReportStatusRequests, so keep the one the voucher was first requested with.
+		if vendor == "" {
+			vendor = cached.vendor
+		}
+		expiresOn, err := m.validateFetched(cached.raw, serial)
+		if err == nil {
+			m.store(serial, &cachedVoucher{raw: cached.raw, vendor: vendor, expiresOn: expiresOn})
+			return cached.raw, nil
+		}
+		log.Infof("Cached ownership voucher for serial number %v is no longer valid, fetching a new one: %v", serial, err)
+	}
+	return m.fetch(ctx, serial, vendor)
+}
+
+// fetch retrieves the ownership voucher for the serial number from the voucher service, validates it and caches it.
+func (m *VoucherServiceArtifactManager) fetch(ctx context.Context, serial string, vendor string) ([]byte, error) {
+	u, err := url.Parse(m.url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid voucher service URL %q: %v", m.url, err)
+	}
+	// The serial number is a single path segment: JoinPath would resolve a dot segment, and PathEscape leaves it as is.
+	if serial == "" || serial == "." || serial == ".." {
+		return nil, fmt.Errorf("invalid serial number %q", serial)
+	}
+	u = u.JoinPath(url.PathEscape(serial))
+	if vendor != "" {
+		u.RawQuery = url.Values{"manufacturer": {vendor}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create voucher service request: %v", err)
+	}
+	req.Header.Set("Accept", voucherMediaTypes)
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("voucher service request for serial number %v failed: %v", serial, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("voucher service returned %v for serial number %v", resp.Status, serial)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxVoucherSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read voucher service response: %v", err)
+	}
+	if len(raw) > maxVoucherSize {
+		return nil, fmt.Errorf("voucher service response for serial number %v exceeds %d bytes", serial, maxVoucherSize)
+	}
+	expiresOn, err := m.validateFetched(raw, serial)
+	if err != nil {
+		return nil, fmt.Errorf("voucher service returned an invalid ownership voucher for serial number %v: %w", serial, err)
+	}
+	if err := m.writeCache(serial, raw); err != nil {
+		// The voucher is still usable, it will be fetched again on the next start.
+		log.Warningf("Unable to cache ownership voucher for serial number %v: %v", serial, err)
+	}
+	m.store(serial, &cachedVoucher{raw: raw, vendor: vendor, expiresOn: expiresOn})
+	log.Infof("Fetched ownership voucher for serial number %v from voucher service", serial)
+	return raw, nil
+}
+
+// validateFetched validates the voucher and returns its expiry time.
+func (m *VoucherServiceArtifactManager) validateFetched(raw []byte, serial string) (time.Time, error) {
+	ov, err := ownershipvoucher.Validate(raw, &ownershipvoucher.ValidateOpts{
+		VendorCAPool:    m.vendorCAPool,
+		SerialNumber:    serial,
+		Now:             m.now,
+		CheckRevocation: skipRevocationChecks,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ov.OV.ExpiresOn == "" {
+		return time.Time{}, nil
+	}
+	// The format was checked by Validate.
+	return time.Parse(time.RFC3339, ov.OV.ExpiresOn)
+}
+
+func (m *VoucherServiceArtifactManager) store(serial string, v *cachedVoucher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.vouchers[serial] = v
+}
+
+// cachePath returns the path of the cache file of the serial number.
+func (m *VoucherServiceArtifactManager) cachePath(serial string) string {
+	return filepath.Join(m.cacheDir, url.PathEscape(serial)+".ov")
+}
+
+// writeCache atomically writes the voucher to the cache directory.
+func (m *VoucherServiceArtifactManager) writeCache(serial string, raw []byte) error {
+	f, err := os.CreateTemp(m.cacheDir, ".ov-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), m.cachePath(serial))
+}
+
+// refresh fetches new vouchers for the cached ones which expire within the refresh window.
+func (m *VoucherServiceArtifactManager) refresh(ctx context.Context) {
+	deadline := m.now().Add(m.refreshBefore)
+	m.mu.Lock()
+	due := make(map[string]string)
+	for serial, v := range m.vouchers {
+		if !v.expiresOn.IsZero() && !v.expiresOn.After(deadline) {
+			due[serial] = v.vendor
+		}
+	}
+	m.mu.Unlock()
+	for serial, vendor := range due {
+		if _, err := m.fetch(ctx, serial, vendor); err != nil {
+			// Keep serving the cached voucher until it expires.
+			log.Warningf("Unable to refresh ownership voucher for serial number %v: %v", serial, err)
+		}
+	}
+}
+
+func (m *VoucherServiceArtifactManager) refreshLoop(ctx context.Context) {
+	defer close(m.done)
+	ticker := time.NewTicker(m.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.refresh(ctx)
+		}
+	}
+}
+
+// Close stops refreshing the cached vouchers.
+func (m *VoucherServiceArtifactManager) Close() error {
+	m.cancel()
+	<-m.done
+	return nil
+}
+
+// NewVoucherService returns a new artifact manager which fetches ownership vouchers from the voucher service in the config.
+func NewVoucherService(config *cpb.Config) (*VoucherServiceArtifactManager, error) {
+	vs := config.GetVoucherService()
+	if vs.GetUrl() == "" {
+		return nil, fmt.Errorf("voucher service URL must be set")
+	}
+	if _, err := url.Parse(vs.GetUrl()); err != nil {
+		return nil, fmt.Errorf("invalid voucher service URL %q: %v", vs.GetUrl(), err)
+	}
+	if vs.GetCacheDir() == "" {
+		return nil, fmt.Errorf("voucher service cache directory must be set")
+	}
+	if err := os.MkdirAll(vs.GetCacheDir(), 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create voucher cache directory: %v", err)
+	}
+	inMemory, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(vs.GetCaCerts()) > 0 {
+		roots := x509.NewCertPool()
+		for _, v := range vs.GetCaCerts() {
+			certBytes, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode voucher service CA certificate: %v", err)
+			}
+			cert, err := x509.ParseCertificate(certBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse voucher service CA certificate: %v", err)
+			}
+			roots.AddCert(cert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	m := &VoucherServiceArtifactManager{
+		InMemoryArtifactManager: inMemory,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   durationOrDefault(vs.GetTimeoutSeconds(), defaultFetchTimeout),
+		},
+		url:             strings.TrimSuffix(vs.GetUrl(), "/"),
+		cacheDir:        vs.GetCacheDir(),
+		refreshBefore:   durationOrDefault(vs.GetRefreshBeforeSeconds(), defaultRefreshBefore),
+		refreshInterval: durationOrDefault(vs.GetRefreshIntervalSeconds(), defaultRefreshInterval),
+		vouchers:        make(map[string]*cachedVoucher),
+		done:            make(chan struct{}),
+	}
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+	go m.refreshLoop(ctx)
+	return m, nil
+}
+
+func durationOrDefault(seconds uint32, def time.Duration) time.Duration {
+	if seconds == 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,362 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifactmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+
+	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
+	ownershipvoucher "github.com/openconfig/bootz/common/ownership_voucher"
+
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// fakeVoucherService is a local stand-in for a vendor voucher service.
+type fakeVoucherService struct {
+	t          *testing.T
+	vendorCert *x509.Certificate
+	vendorKey  crypto.PrivateKey
+	pdc        *x509.Certificate
+
+	mu sync.Mutex
+	// validity is how long issued vouchers are valid for.
+	validity time.Duration
+	// issuedSerial overrides the serial number of issued vouchers if set.
+	issuedSerial string
+	// revocationChecks is whether issued vouchers require revocation checks of the pinned domain cert.
+	revocationChecks bool
+	// status overrides the response status if set.
+	status   int
+	requests map[string]int
+	vendors  map[string]string
+}
+
+func (f *fakeVoucherService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	serial, err := url.PathUnescape(path.Base(r.URL.EscapedPath()))
+	if err != nil {
+		f.t.Errorf("invalid request path %q: %v", r.URL.EscapedPath(), err)
+		return
+	}
+	f.requests[serial]++
+	f.vendors[serial] = r.URL.Query().Get("manufacturer")
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	issued := serial
+	if f.issuedSerial != "" {
+		issued = f.issuedSerial
+	}
+	ov, err := f.issue(issued)
+	if err != nil {
+		f.t.Errorf("unable to issue voucher: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/voucher-cms+json")
+	w.Write(ov)
+}
+
+// issue returns a voucher for the serial number signed by the vendor certificate.
+func (f *fakeVoucherService) issue(serial string) ([]byte, error) {
+	now := time.Now()
+	content, err := json.Marshal(&ownershipvoucher.OwnershipVoucher{OV: ownershipvoucher.OVInner{
+		CreatedOn:                  now.Add(-time.Minute).Format(time.RFC3339),
+		ExpiresOn:                  now.Add(f.validity).Format(time.RFC3339),
+		Assertion:                  ownershipvoucher.AssertionVerified,
+		SerialNumber:               serial,
+		PinnedDomainCert:           f.pdc.Raw,
+		DomainCertRevocationChecks: f.revocationChecks,
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal voucher: %v", err)
+	}
+	signedMessage, err := pkcs7.NewSignedData(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create signed data: %v", err)
+	}
+	signedMessage.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := signedMessage.AddSigner(f.vendorCert, f.vendorKey, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("unable to add signer: %v", err)
+	}
+	return signedMessage.Finish()
+}
+
+func (f *fakeVoucherService) requestCount(serial string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[serial]
+}
+
+func (f *fakeVoucherService) vendor(serial string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.vendors[serial]
+}
+
+func certKeyPair(t *testing.T, cert *x509.Certificate, key crypto.PrivateKey) *cpb.CertKeyPair {
+	t.Helper()
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal private key: %v", err)
+	}
+	return &cpb.CertKeyPair{
+		Cert: base64.StdEncoding.EncodeToString(cert.Raw),
+		Key:  base64.StdEncoding.EncodeToString(keyBytes),
+	}
+}
+
+func setupVoucherService(t *testing.T) (*fakeVoucherService, *cpb.Config) {
+	t.Helper()
+	vendorCert, vendorKey, err := ownercertificate.NewRSACertificate("Vendor Certificate Authority", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate vendor CA: %v", err)
+	}
+	pdc, pdcKey, err := ownercertificate.NewRSACertificate("Pinned Domain Cert", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate PDC: %v", err)
+	}
+	oc, ocKey, err := ownercertificate.NewRSACertificate("Owner Certificate", "", pdc, pdcKey)
+	if err != nil {
+		t.Fatalf("unable to generate owner certificate: %v", err)
+	}
+	f := &fakeVoucherService{
+		t:          t,
+		vendorCert: vendorCert,
+		vendorKey:  vendorKey,
+		pdc:        pdc,
+		validity:   30 * 24 * time.Hour,
+		requests:   make(map[string]int),
+		vendors:    make(map[string]string),
+	}
+	ts := httptest.NewTLSServer(f)
+	t.Cleanup(ts.Close)
+	config := &cpb.Config{
+		TrustAnchor:      certKeyPair(t, pdc, pdcKey),
+		OwnerCertificate: certKeyPair(t, oc, ocKey),
+		VendorCaCerts:    []string{base64.StdEncoding.EncodeToString(vendorCert.Raw)},
+		VoucherService: &cpb.VoucherService{
+			Url:      ts.URL + "/vouchers/",
+			CacheDir: filepath.Join(t.TempDir(), "vouchers"),
+			CaCerts:  []string{base64.StdEncoding.EncodeToString(ts.Certificate().Raw)},
+		},
+	}
+	return f, config
+}
+
+func newVoucherService(t *testing.T, config *cpb.Config) *VoucherServiceArtifactManager {
+	t.Helper()
+	m, err := NewVoucherService(config)
+	if err != nil {
+		t.Fatalf("NewVoucherService() err = %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestVoucherServiceFetchAndCache(t *testing.T) {
+	f, config := setupVoucherService(t)
+	m := newVoucherService(t, config)
+	ctx := context.Background()
+
+	ov, err := m.OwnershipVoucher(ctx, "123A", "Cisco")
+	if err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	if got := f.requestCount("123A"); got != 1 {
+		t.Errorf("voucher service requests = %d, want 1", got)
+	}
+	if got := f.vendor("123A"); got != "Cisco" {
+		t.Errorf("voucher service manufacturer = %q, want %q", got, "Cisco")
+	}
+	// The second request, without a vendor as for ReportStatusRequests, must be served from the cache.
+	again, err := m.OwnershipVoucher(ctx, "123A", "")
+	if err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	if !bytes.Equal(ov, again) {
+		t.Errorf("OwnershipVoucher() returned a different voucher from the cache")
+	}
+	if got := f.requestCount("123A"); got != 1 {
+		t.Errorf("voucher service requests = %d, want 1", got)
+	}
+	cached, err := os.ReadFile(filepath.Join(config.GetVoucherService().GetCacheDir(), "123A.ov"))
+	if err != nil {
+		t.Fatalf("unable to read cached voucher: %v", err)
+	}
+	if !bytes.Equal(ov, cached) {
+		t.Errorf("cached voucher on disk does not match the fetched one")
+	}
+
+	// A new manager must serve the voucher from the disk cache.
+	restarted := newVoucherService(t, config)
+	fromDisk, err := restarted.OwnershipVoucher(ctx, "123A", "Cisco")
+	if err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	if !bytes.Equal(ov, fromDisk) {
+		t.Errorf("OwnershipVoucher() returned a different voucher from the disk cache")
+	}
+	if got := f.requestCount("123A"); got != 1 {
+		t.Errorf("voucher service requests = %d, want 1", got)
+	}
+}
+
+func TestVoucherServiceErrors(t *testing.T) {
+	tests := []struct {
+		desc         string
+		status       int
+		issuedSerial string
+	}{{
+		desc:   "Voucher service error",
+		status: http.StatusNotFound,
+	}, {
+		desc:         "Voucher for another serial number",
+		issuedSerial: "456B",
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f, config := setupVoucherService(t)
+			f.status = test.status
+			f.issuedSerial = test.issuedSerial
+			m := newVoucherService(t, config)
+			if _, err := m.OwnershipVoucher(context.Background(), "123A", "Cisco"); err == nil {
+				t.Fatalf("OwnershipVoucher() err = nil, want error")
+			}
+			if _, err := os.Stat(filepath.Join(config.GetVoucherService().GetCacheDir(), "123A.ov")); !os.IsNotExist(err) {
+				t.Errorf("invalid voucher was cached: %v", err)
+			}
+		})
+	}
+}
+
+func TestVoucherServiceSerialEscaping(t *testing.T) {
+	f, config := setupVoucherService(t)
+	m := newVoucherService(t, config)
+	if _, err := m.OwnershipVoucher(context.Background(), "../123A", "Cisco"); err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v, want nil", err)
+	}
+	if got := f.requestCount("../123A"); got != 1 {
+		t.Errorf("voucher service requests for %q = %d, want 1", "../123A", got)
+	}
+	for _, serial := range []string{"", ".", ".."} {
+		if _, err := m.OwnershipVoucher(context.Background(), serial, "Cisco"); err == nil {
+			t.Errorf("OwnershipVoucher(%q) err = nil, want error", serial)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if got := len(f.requests); got != 1 {
+		t.Errorf("voucher service received requests for %d serial numbers, want 1", got)
+	}
+}
+
+func TestVoucherServiceRefresh(t *testing.T) {
+	f, config := setupVoucherService(t)
+	f.validity = time.Hour
+	m := newVoucherService(t, config)
+	ctx := context.Background()
+
+	first, err := m.OwnershipVoucher(ctx, "123A", "Cisco")
+	if err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	// Vouchers expiring after the refresh window are kept.
+	m.refreshBefore = time.Minute
+	m.refresh(ctx)
+	if got := f.requestCount("123A"); got != 1 {
+		t.Errorf("voucher service requests = %d, want 1", got)
+	}
+	// Vouchers expiring within the refresh window are fetched again.
+	m.refreshBefore = 2 * time.Hour
+	time.Sleep(time.Second) // Make sure the new voucher has a different created-on.
+	m.refresh(ctx)
+	if got := f.requestCount("123A"); got != 2 {
+		t.Errorf("voucher service requests = %d, want 2", got)
+	}
+	if got := f.vendor("123A"); got != "Cisco" {
+		t.Errorf("voucher service manufacturer = %q, want %q", got, "Cisco")
+	}
+	second, err := m.OwnershipVoucher(ctx, "123A", "")
+	if err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	if bytes.Equal(first, second) {
+		t.Errorf("OwnershipVoucher() returned the voucher from before the refresh")
+	}
+}
+
+func TestVoucherServiceExpired(t *testing.T) {
+	f, config := setupVoucherService(t)
+	f.validity = time.Hour
+	m := newVoucherService(t, config)
+	ctx := context.Background()
+
+	if _, err := m.OwnershipVoucher(ctx, "123A", "Cisco"); err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	// Once the cached voucher expires, a new one is fetched.
+	m.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	f.mu.Lock()
+	f.validity = 3 * time.Hour
+	f.mu.Unlock()
+	if _, err := m.OwnershipVoucher(ctx, "123A", "Cisco"); err != nil {
+		t.Fatalf("OwnershipVoucher() err = %v", err)
+	}
+	if got := f.requestCount("123A"); got != 2 {
+		t.Errorf("voucher service requests = %d, want 2", got)
+	}
+}
+
+func TestRevocationChecksVoucher(t *testing.T) {
+	f, config := setupVoucherService(t)
+	f.revocationChecks = true
+
+	// The vouchers requiring revocation checks are checked by the device, not at load time.
+	if _, err := newVoucherService(t, config).OwnershipVoucher(context.Background(), "123A", "Cisco"); err != nil {
+		t.Errorf("OwnershipVoucher() err = %v, want nil", err)
+	}
+	ov, err := f.issue("123B")
+	if err != nil {
+		t.Fatalf("unable to issue voucher: %v", err)
+	}
+	config.VoucherService = nil
+	config.Chassis = []*cpb.Chassis{{ControlCards: []*cpb.ControlCard{{SerialNumber: "123B", OwnershipVoucher: base64.StdEncoding.EncodeToString(ov)}}}}
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if _, err := m.OwnershipVoucher(context.Background(), "123B", ""); err != nil {
+		t.Errorf("OwnershipVoucher() err = %v, want nil", err)
+	}
+}
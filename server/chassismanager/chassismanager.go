@@ -20,17 +20,87 @@ package chassismanager
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sync"
 
 	log "github.com/golang/glog"
+	"github.com/openconfig/bootz/common/imagehash"
 	"github.com/openconfig/bootz/common/types"
+	"google.golang.org/protobuf/proto"
 
 	bpb "github.com/openconfig/bootz/proto/bootz"
 	cpb "github.com/openconfig/bootz/server/proto/config"
 )
 
+// ImageResolver resolves a software image by name and version.
+type ImageResolver interface {
+	// Resolve returns the image with its url, os_image_hash and hash_algorithm set.
+	Resolve(name, version string) (*bpb.SoftwareImage, error)
+}
+
+// ImageVerifier reports whether the vendor signature of an image was verified.
+type ImageVerifier interface {
+	Verified(image *bpb.SoftwareImage) bool
+}
+
 // InMemoryChassisManager provides a simple in memory handler for chassis.
 type InMemoryChassisManager struct {
-	chassis map[string]*cpb.Chassis
+	mu       sync.RWMutex
+	chassis  map[string]*cpb.Chassis
+	rollouts []*rollout
+	images   ImageResolver
+	// verifier is set if the intended images must be verified, except for lab chassis.
+	verifier ImageVerifier
+	// httpsHost is the host:port of the image server if it serves HTTPS.
+	httpsHost string
+	// signer signs the image URLs of the image server at signedHost.
+	signer     URLSigner
+	signedHost string
+}
+
+// URLSigner signs image URLs for a serial number.
+type URLSigner interface {
+	Sign(url, serial string) (string, error)
+}
+
+// Option configures an InMemoryChassisManager.
+type Option func(*InMemoryChassisManager)
+
+// WithImageResolver resolves the intended images which are referenced by name and version only, without url.
+func WithImageResolver(r ImageResolver) Option {
+	return func(m *InMemoryChassisManager) {
+		m.images = r
+	}
+}
+
+// WithImageVerifier refuses to hand out the intended images the verifier did not verify, except to lab chassis.
+func WithImageVerifier(v ImageVerifier) Option {
+	return func(m *InMemoryChassisManager) {
+		m.verifier = v
+	}
+}
+
+// WithImageURLSigner signs the intended image URLs of the image server at host:port for the requested serial number.
+func WithImageURLSigner(host string, s URLSigner) Option {
+	return func(m *InMemoryChassisManager) {
+		m.signer = s
+		m.signedHost = host
+	}
+}
+
+// WithHTTPSImageHost switches the http intended image URLs of the image server at host:port to https.
+func WithHTTPSImageHost(host string) Option {
+	return func(m *InMemoryChassisManager) {
+		m.httpsHost = host
+	}
+}
+
+// lookup returns the chassis with a control card with the given serial number.
+func (m *InMemoryChassisManager) lookup(serial string) (*cpb.Chassis, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.chassis[serial]
+	return c, ok
 }
 
 // ResolveChassis fills the chassis information based on the matched inventory.
@@ -38,7 +108,7 @@ func (m *InMemoryChassisManager) ResolveChassis(ctx context.Context, chassis *ty
 	if chassis == nil {
 		return fmt.Errorf("chassis cannot be nil")
 	}
-	found, ok := m.chassis[chassis.ActiveSerial]
+	found, ok := m.lookup(chassis.ActiveSerial)
 	if !ok {
 		return fmt.Errorf("chassis with serial number %v not found", chassis.ActiveSerial)
 	}
@@ -50,16 +120,46 @@ func (m *InMemoryChassisManager) ResolveChassis(ctx context.Context, chassis *ty
 }
 
 // GenerateBootstrapData generates the bootstrap data response for the provided serial number.
-func (m *InMemoryChassisManager) GenerateBootstrapData(ctx context.Context, _ *types.Chassis, serial string) (*bpb.BootstrapDataResponse, error) {
-	found, ok := m.chassis[serial]
+// The overrides of the control card are merged over the chassis defaults.
+func (m *InMemoryChassisManager) GenerateBootstrapData(ctx context.Context, chassis *types.Chassis, serial string) (*bpb.BootstrapDataResponse, error) {
+	found, ok := m.lookup(serial)
 	if !ok {
 		return nil, fmt.Errorf("chassis with serial number %v not found", serial)
 	}
+	card := controlCard(found, serial)
+	if hasOverrides(card) {
+		var reported string
+		if chassis != nil {
+			reported = chassis.PartNumbers[serial]
+		}
+		if reported != card.GetPartNumber() {
+			return nil, fmt.Errorf("control card %v reported part number %q, but its overrides are for part number %q", serial, reported, card.GetPartNumber())
+		}
+	}
+	image, err := m.intendedImage(found, serial)
+	if err != nil {
+		return nil, err
+	}
+	if image != nil && m.verifier != nil && !found.GetLab() && !m.verifier.Verified(image) {
+		return nil, fmt.Errorf("intended image %v version %v of chassis %v is not verified", image.GetName(), image.GetVersion(), found.GetHostname())
+	}
+	bootConfig := found.GetBootConfig()
+	if card.GetBootConfig() != nil {
+		bootConfig = &bpb.BootConfig{}
+		if found.GetBootConfig() != nil {
+			proto.Merge(bootConfig, found.GetBootConfig())
+		}
+		proto.Merge(bootConfig, card.GetBootConfig())
+	}
+	bootPasswordHash := found.GetBootPasswordHash()
+	if card.GetBootPasswordHash() != "" {
+		bootPasswordHash = card.GetBootPasswordHash()
+	}
 	return &bpb.BootstrapDataResponse{
 		SerialNum:        serial,
-		IntendedImage:    found.GetIntendedImage(),
-		BootPasswordHash: found.GetBootPasswordHash(),
-		BootConfig:       found.GetBootConfig(),
+		IntendedImage:    image,
+		BootPasswordHash: bootPasswordHash,
+		BootConfig:       bootConfig,
 		Credentials:      found.GetCredentials(),
 		Pathz:            found.GetPathz(),
 		Authz:            found.GetAuthz(),
@@ -67,6 +167,93 @@ func (m *InMemoryChassisManager) GenerateBootstrapData(ctx context.Context, _ *t
 	}, nil
 }
 
+// rolloutImage returns the image of the first rollout the chassis belongs to, or its intended image.
+func (m *InMemoryChassisManager) rolloutImage(chassis *cpb.Chassis) *bpb.SoftwareImage {
+	m.mu.RLock()
+	rollouts := m.rollouts
+	m.mu.RUnlock()
+	for _, r := range rollouts {
+		if r.contains(chassis) {
+			return r.image(chassis)
+		}
+	}
+	return chassis.GetIntendedImage()
+}
+
+// controlCard returns the control card of the chassis with the serial number.
+func controlCard(chassis *cpb.Chassis, serial string) *cpb.ControlCard {
+	for _, cc := range chassis.GetControlCards() {
+		if cc.GetSerialNumber() == serial {
+			return cc
+		}
+	}
+	return nil
+}
+
+// hasOverrides returns whether the control card overrides the bootstrap data of its chassis.
+func hasOverrides(card *cpb.ControlCard) bool {
+	return card.GetIntendedImage() != nil || card.GetBootConfig() != nil || card.GetBootPasswordHash() != ""
+}
+
+// ValidateOverrides checks that the control cards which override the bootstrap data of their chassis have a part
+// number.
+func ValidateOverrides(config *cpb.Config) error {
+	for _, c := range config.GetChassis() {
+		for _, cc := range c.GetControlCards() {
+			if hasOverrides(cc) && cc.GetPartNumber() == "" {
+				return fmt.Errorf("control card %v of chassis %v has overrides but no part number", cc.GetSerialNumber(), c.GetHostname())
+			}
+		}
+	}
+	return nil
+}
+
+// intendedImage returns the intended image of the control card, or else the image of the rollout of the chassis or
+// its intended image, resolved by the image resolver if it has no url, with its hash in the canonical representation
+// and the url of the image server switched to https and signed for the serial number as configured.
+func (m *InMemoryChassisManager) intendedImage(chassis *cpb.Chassis, serial string) (*bpb.SoftwareImage, error) {
+	image := controlCard(chassis, serial).GetIntendedImage()
+	if image == nil {
+		image = m.rolloutImage(chassis)
+	}
+	if image.GetUrl() == "" && image.GetName() != "" && m.images != nil {
+		resolved, err := m.images.Resolve(image.GetName(), image.GetVersion())
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve intended image of chassis %v: %v", chassis.GetHostname(), err)
+		}
+		image = resolved
+	}
+	if image.GetOsImageHash() != "" {
+		hash, err := imagehash.Normalize(image.GetOsImageHash())
+		if err != nil {
+			return nil, fmt.Errorf("invalid os_image_hash of the intended image of chassis %v: %v", chassis.GetHostname(), err)
+		}
+		if hash != image.GetOsImageHash() {
+			image = proto.Clone(image).(*bpb.SoftwareImage)
+			image.OsImageHash = hash
+		}
+	}
+	u, err := url.Parse(image.GetUrl())
+	if err != nil || image.GetUrl() == "" {
+		return image, nil
+	}
+	rawURL := image.GetUrl()
+	if m.httpsHost != "" && u.Scheme == "http" && u.Host == m.httpsHost {
+		u.Scheme = "https"
+		rawURL = u.String()
+	}
+	if m.signer != nil && u.Host == m.signedHost {
+		if rawURL, err = m.signer.Sign(rawURL, serial); err != nil {
+			return nil, fmt.Errorf("unable to sign intended image url of chassis %v: %v", chassis.GetHostname(), err)
+		}
+	}
+	if rawURL != image.GetUrl() {
+		image = proto.Clone(image).(*bpb.SoftwareImage)
+		image.Url = rawURL
+	}
+	return image, nil
+}
+
 // UpdateStatus updates the status for each control card on the chassis.
 func (m *InMemoryChassisManager) UpdateStatus(ctx context.Context, req *bpb.ReportStatusRequest) error {
 	if len(req.GetStates()) == 0 {
@@ -77,11 +264,46 @@ func (m *InMemoryChassisManager) UpdateStatus(ctx context.Context, req *bpb.Repo
 	for _, v := range req.GetStates() {
 		log.Infof("Control card %v changed status to %v", v.GetSerialNumber(), v.GetStatus())
 	}
+	// The status of the chassis counts toward the failure rate of the rollouts which handed out their target image.
+	m.mu.RLock()
+	rollouts := m.rollouts
+	m.mu.RUnlock()
+	reported := map[string]bool{}
+	for _, v := range req.GetStates() {
+		c, ok := m.lookup(v.GetSerialNumber())
+		if !ok || reported[chassisKey(c)] {
+			continue
+		}
+		reported[chassisKey(c)] = true
+		for _, r := range rollouts {
+			r.report(chassisKey(c), req.GetStatus())
+		}
+	}
 	return nil
 }
 
-// New returns a new in-memory chassis manager.
-func New(config *cpb.Config) *InMemoryChassisManager {
+// Update replaces the inventory and the rollouts with the ones of the config. The progress of the rollouts whose name
+// and target image are unchanged is kept.
+func (m *InMemoryChassisManager) Update(config *cpb.Config) {
+	chassis := index(config)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chassis = chassis
+	m.rollouts = updateRollouts(config, m.rollouts)
+}
+
+// updateRollouts returns the rollouts of the config, or none if they are invalid.
+func updateRollouts(config *cpb.Config, previous []*rollout) []*rollout {
+	rollouts, err := newRollouts(config, previous)
+	if err != nil {
+		log.Errorf("Ignoring the rollouts of the config: %v", err)
+		return nil
+	}
+	return rollouts
+}
+
+// index returns the chassis of the config indexed by control card serial number.
+func index(config *cpb.Config) map[string]*cpb.Chassis {
 	// For fast lookup, we build a map indexed by the control card serial number, which means modular chassis with dual control cards are indexed twice.
 	chassis := make(map[string]*cpb.Chassis)
 	for _, c := range config.GetChassis() {
@@ -89,5 +311,14 @@ func New(config *cpb.Config) *InMemoryChassisManager {
 			chassis[cc.GetSerialNumber()] = c
 		}
 	}
-	return &InMemoryChassisManager{chassis: chassis}
+	return chassis
+}
+
+// New returns a new in-memory chassis manager.
+func New(config *cpb.Config, opts ...Option) *InMemoryChassisManager {
+	m := &InMemoryChassisManager{chassis: index(config), rollouts: updateRollouts(config, nil)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
@@ -0,0 +1,293 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chassismanager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/bootz/common/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// fakeSigner signs the URLs by appending the serial number.
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(url, serial string) (string, error) {
+	return url + "?serial=" + serial, nil
+}
+
+type fakeResolver map[string]*bpb.SoftwareImage
+
+func (r fakeResolver) Resolve(name, version string) (*bpb.SoftwareImage, error) {
+	if i, ok := r[name+"/"+version]; ok {
+		return i, nil
+	}
+	return nil, fmt.Errorf("image %v version %v not found", name, version)
+}
+
+// fakeVerifier verifies the images by hash.
+type fakeVerifier map[string]bool
+
+func (v fakeVerifier) Verified(image *bpb.SoftwareImage) bool {
+	return v[image.GetOsImageHash()]
+}
+
+func TestGenerateBootstrapDataIntendedImage(t *testing.T) {
+	resolved := &bpb.SoftwareImage{
+		Name:          "os",
+		Version:       "1.0",
+		Url:           "http://10.0.0.1/sha256/abcd",
+		OsImageHash:   "ab:cd",
+		HashAlgorithm: "ietf-sztp-conveyed-info:sha-256",
+	}
+	explicit := &bpb.SoftwareImage{Name: "os", Version: "2.0", Url: "http://10.0.0.2/os.bin", OsImageHash: "ef:01"}
+	tests := []struct {
+		desc     string
+		image    *bpb.SoftwareImage
+		resolver ImageResolver
+		https    string
+		signer   string
+		verifier ImageVerifier
+		lab      bool
+		want     *bpb.SoftwareImage
+		wantErr  bool
+	}{{
+		desc:     "Resolved",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+		resolver: fakeResolver{"os/1.0": resolved},
+		want:     resolved,
+	}, {
+		desc:     "Explicit URL",
+		image:    explicit,
+		resolver: fakeResolver{},
+		want:     explicit,
+	}, {
+		desc:     "Hash in the canonical representation",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "2.0", Url: "http://10.0.0.2/os.bin", OsImageHash: "EF01"},
+		resolver: fakeResolver{},
+		want:     explicit,
+	}, {
+		desc:     "Invalid hash",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "2.0", Url: "http://10.0.0.2/os.bin", OsImageHash: "ef0"},
+		resolver: fakeResolver{},
+		wantErr:  true,
+	}, {
+		desc:  "No resolver",
+		image: &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+		want:  &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+	}, {
+		desc:     "No intended image",
+		resolver: fakeResolver{},
+	}, {
+		desc:     "HTTPS image server",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+		resolver: fakeResolver{"os/1.0": resolved},
+		https:    "10.0.0.1",
+		want: &bpb.SoftwareImage{
+			Name:          "os",
+			Version:       "1.0",
+			Url:           "https://10.0.0.1/sha256/abcd",
+			OsImageHash:   "ab:cd",
+			HashAlgorithm: "ietf-sztp-conveyed-info:sha-256",
+		},
+	}, {
+		desc:  "HTTPS for another image server",
+		image: explicit,
+		https: "10.0.0.1",
+		want:  explicit,
+	}, {
+		desc:     "Signed HTTPS URL",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+		resolver: fakeResolver{"os/1.0": resolved},
+		https:    "10.0.0.1",
+		signer:   "10.0.0.1",
+		want: &bpb.SoftwareImage{
+			Name:          "os",
+			Version:       "1.0",
+			Url:           "https://10.0.0.1/sha256/abcd?serial=123",
+			OsImageHash:   "ab:cd",
+			HashAlgorithm: "ietf-sztp-conveyed-info:sha-256",
+		},
+	}, {
+		desc:   "Unsigned URL of another image server",
+		image:  explicit,
+		signer: "10.0.0.1",
+		want:   explicit,
+	}, {
+		desc:     "Missing image",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "3.0"},
+		resolver: fakeResolver{"os/1.0": resolved},
+		wantErr:  true,
+	}, {
+		desc:     "Verified image",
+		image:    &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+		resolver: fakeResolver{"os/1.0": resolved},
+		verifier: fakeVerifier{"ab:cd": true},
+		want:     resolved,
+	}, {
+		desc:     "Unverified image",
+		image:    explicit,
+		verifier: fakeVerifier{"ab:cd": true},
+		wantErr:  true,
+	}, {
+		desc:     "Unverified image of a lab chassis",
+		image:    explicit,
+		verifier: fakeVerifier{"ab:cd": true},
+		lab:      true,
+		want:     explicit,
+	}, {
+		desc:     "No intended image to verify",
+		verifier: fakeVerifier{},
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config := &cpb.Config{Chassis: []*cpb.Chassis{{
+				Hostname:      "test",
+				IntendedImage: test.image,
+				Lab:           test.lab,
+				ControlCards:  []*cpb.ControlCard{{SerialNumber: "123"}},
+			}}}
+			var opts []Option
+			if test.resolver != nil {
+				opts = append(opts, WithImageResolver(test.resolver))
+			}
+			if test.https != "" {
+				opts = append(opts, WithHTTPSImageHost(test.https))
+			}
+			if test.signer != "" {
+				opts = append(opts, WithImageURLSigner(test.signer, fakeSigner{}))
+			}
+			if test.verifier != nil {
+				opts = append(opts, WithImageVerifier(test.verifier))
+			}
+			got, err := New(config, opts...).GenerateBootstrapData(context.Background(), nil, "123")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("GenerateBootstrapData() err = %v, want error %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got.GetIntendedImage(), protocmp.Transform()); diff != "" {
+				t.Errorf("GenerateBootstrapData() intended image diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateBootstrapDataOverrides(t *testing.T) {
+	chassisImage := &bpb.SoftwareImage{Name: "os", Version: "1.0", Url: "http://10.0.0.1/os-1.0.bin", OsImageHash: "ab"}
+	cardImage := &bpb.SoftwareImage{Name: "os-gen2", Version: "1.0", Url: "http://10.0.0.1/os-gen2-1.0.bin", OsImageHash: "cd"}
+	rolloutImage := &bpb.SoftwareImage{Name: "os", Version: "2.0", Url: "http://10.0.0.1/os-2.0.bin", OsImageHash: "ef"}
+	config := &cpb.Config{
+		Chassis: []*cpb.Chassis{{
+			Hostname:         "modular",
+			IntendedImage:    chassisImage,
+			BootPasswordHash: "chassis-hash",
+			BootConfig:       &bpb.BootConfig{VendorConfig: []byte("chassis vendor config"), OcConfig: []byte("chassis oc config")},
+			ControlCards: []*cpb.ControlCard{{
+				SerialNumber: "A",
+				PartNumber:   "PN-GEN1",
+			}, {
+				SerialNumber:     "B",
+				PartNumber:       "PN-GEN2",
+				IntendedImage:    cardImage,
+				BootConfig:       &bpb.BootConfig{VendorConfig: []byte("gen2 vendor config")},
+				BootPasswordHash: "gen2-hash",
+			}},
+		}},
+		ImageTargets: []*cpb.ImageTarget{{Name: "os-2.0", Image: rolloutImage}},
+	}
+	descriptor := &types.Chassis{PartNumbers: map[string]string{"A": "PN-GEN1", "B": "PN-GEN2"}}
+
+	tests := []struct {
+		desc     string
+		chassis  *types.Chassis
+		serial   string
+		rollouts []*cpb.Rollout
+		want     *bpb.BootstrapDataResponse
+		wantErr  bool
+	}{{
+		desc:    "Chassis defaults",
+		chassis: descriptor,
+		serial:  "A",
+		want: &bpb.BootstrapDataResponse{
+			SerialNum:        "A",
+			IntendedImage:    chassisImage,
+			BootPasswordHash: "chassis-hash",
+			BootConfig:       &bpb.BootConfig{VendorConfig: []byte("chassis vendor config"), OcConfig: []byte("chassis oc config")},
+		},
+	}, {
+		desc:    "Overrides",
+		chassis: descriptor,
+		serial:  "B",
+		want: &bpb.BootstrapDataResponse{
+			SerialNum:        "B",
+			IntendedImage:    cardImage,
+			BootPasswordHash: "gen2-hash",
+			BootConfig:       &bpb.BootConfig{VendorConfig: []byte("gen2 vendor config"), OcConfig: []byte("chassis oc config")},
+		},
+	}, {
+		desc:     "Override of the rollout image",
+		chassis:  descriptor,
+		serial:   "B",
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "os-2.0", Groups: []*cpb.RolloutGroup{{Percentage: 100}}}},
+		want: &bpb.BootstrapDataResponse{
+			SerialNum:        "B",
+			IntendedImage:    cardImage,
+			BootPasswordHash: "gen2-hash",
+			BootConfig:       &bpb.BootConfig{VendorConfig: []byte("gen2 vendor config"), OcConfig: []byte("chassis oc config")},
+		},
+	}, {
+		desc:     "Rollout image without override",
+		chassis:  descriptor,
+		serial:   "A",
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "os-2.0", Groups: []*cpb.RolloutGroup{{Percentage: 100}}}},
+		want: &bpb.BootstrapDataResponse{
+			SerialNum:        "A",
+			IntendedImage:    rolloutImage,
+			BootPasswordHash: "chassis-hash",
+			BootConfig:       &bpb.BootConfig{VendorConfig: []byte("chassis vendor config"), OcConfig: []byte("chassis oc config")},
+		},
+	}, {
+		desc:    "Other part number",
+		chassis: &types.Chassis{PartNumbers: map[string]string{"A": "PN-GEN1", "B": "PN-GEN1"}},
+		serial:  "B",
+		wantErr: true,
+	}, {
+		desc:    "No part number reported",
+		serial:  "B",
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c := proto.Clone(config).(*cpb.Config)
+			c.Rollouts = test.rollouts
+			got, err := New(c).GenerateBootstrapData(context.Background(), test.chassis, test.serial)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("GenerateBootstrapData() err = %v, want error %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("GenerateBootstrapData() diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+	if got := string(config.GetChassis()[0].GetBootConfig().GetVendorConfig()); got != "chassis vendor config" {
+		t.Errorf("GenerateBootstrapData() modified the boot config of the chassis")
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chassismanager
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sync"
+
+	log "github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// rollout is a staged rollout of an image target to groups of chassis.
+type rollout struct {
+	name   string
+	target *bpb.SoftwareImage
+	// previous is the image the chassis are reverted to, nil for their intended image.
+	previous    *bpb.SoftwareImage
+	groups      []*rolloutGroup
+	maxFailures float64
+	minReports  int
+	state       *rolloutState
+}
+
+// rolloutGroup is a group of chassis of a rollout.
+type rolloutGroup struct {
+	parts      map[string]bool
+	hostname   *regexp.Regexp
+	chassis    map[string]bool
+	percentage uint32
+}
+
+// rolloutState is the progress of a rollout.
+type rolloutState struct {
+	mu sync.Mutex
+	// assigned are the chassis handed out the target image, by chassis key.
+	assigned map[string]bool
+	// failed is whether the last status reported by each assigned chassis is a failure.
+	failed map[string]bool
+	halted bool
+}
+
+// chassisKey identifies a chassis by the serial number of its first control card.
+func chassisKey(c *cpb.Chassis) string {
+	if len(c.GetControlCards()) == 0 {
+		return ""
+	}
+	return c.GetControlCards()[0].GetSerialNumber()
+}
+
+// bucket returns the bucket, between 0 and 99, of the chassis in the rollout.
+func bucket(rollout, key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(rollout + "/" + key))
+	return h.Sum32() % 100
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	s := map[string]bool{}
+	for _, v := range values {
+		s[v] = true
+	}
+	return s
+}
+
+// matches returns whether the chassis belongs to the group of the named rollout.
+func (g *rolloutGroup) matches(rollout string, c *cpb.Chassis) bool {
+	if g.parts != nil {
+		found := false
+		for _, cc := range c.GetControlCards() {
+			found = found || g.parts[cc.GetPartNumber()]
+		}
+		if !found {
+			return false
+		}
+	}
+	if g.hostname != nil && !g.hostname.MatchString(c.GetHostname()) {
+		return false
+	}
+	if g.chassis != nil {
+		found := g.chassis[c.GetHostname()]
+		for _, cc := range c.GetControlCards() {
+			found = found || g.chassis[cc.GetSerialNumber()]
+		}
+		if !found {
+			return false
+		}
+	}
+	return g.percentage == 0 || bucket(rollout, chassisKey(c)) < g.percentage
+}
+
+// contains returns whether the chassis belongs to a group of the rollout.
+func (r *rollout) contains(c *cpb.Chassis) bool {
+	for _, g := range r.groups {
+		if g.matches(r.name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// image returns the image the chassis of the rollout is handed out: the target image, or the previous image if the
+// rollout halted and the chassis did not report a success.
+func (r *rollout) image(c *cpb.Chassis) *bpb.SoftwareImage {
+	key := chassisKey(c)
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if failed, reported := r.state.failed[key]; r.state.halted && (failed || !reported) {
+		if r.previous != nil {
+			return r.previous
+		}
+		return c.GetIntendedImage()
+	}
+	r.state.assigned[key] = true
+	return r.target
+}
+
+// report records the bootstrap status reported by a chassis handed out the target image, and halts the rollout if
+// the failure rate exceeds its threshold.
+func (r *rollout) report(key string, status bpb.ReportStatusRequest_BootstrapStatus) {
+	if status != bpb.ReportStatusRequest_BOOTSTRAP_STATUS_SUCCESS && status != bpb.ReportStatusRequest_BOOTSTRAP_STATUS_FAILURE {
+		return
+	}
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	if !r.state.assigned[key] {
+		return
+	}
+	r.state.failed[key] = status == bpb.ReportStatusRequest_BOOTSTRAP_STATUS_FAILURE
+	if r.state.halted || r.maxFailures == 0 || len(r.state.failed) < r.minReports {
+		return
+	}
+	failures := 0
+	for _, f := range r.state.failed {
+		if f {
+			failures++
+		}
+	}
+	if rate := float64(failures) / float64(len(r.state.failed)); rate > r.maxFailures {
+		r.state.halted = true
+		log.Warningf("Halting rollout %v: %d of %d chassis reported a failure, more than the %v failure rate", r.name, failures, len(r.state.failed), r.maxFailures)
+	}
+}
+
+// newRollouts returns the rollouts of the config. The progress of the previous rollouts with the same name and target
+// image is kept.
+func newRollouts(config *cpb.Config, previous []*rollout) ([]*rollout, error) {
+	targets := map[string]*bpb.SoftwareImage{}
+	for _, t := range config.GetImageTargets() {
+		if t.GetName() == "" {
+			return nil, fmt.Errorf("image target without name")
+		}
+		if _, ok := targets[t.GetName()]; ok {
+			return nil, fmt.Errorf("duplicate image target %q", t.GetName())
+		}
+		if t.GetImage() == nil {
+			return nil, fmt.Errorf("image target %q has no image", t.GetName())
+		}
+		targets[t.GetName()] = t.GetImage()
+	}
+	states := map[string]*rollout{}
+	for _, r := range previous {
+		states[r.name] = r
+	}
+
+	var rollouts []*rollout
+	names := map[string]bool{}
+	for _, rc := range config.GetRollouts() {
+		if rc.GetName() == "" {
+			return nil, fmt.Errorf("rollout without name")
+		}
+		if names[rc.GetName()] {
+			return nil, fmt.Errorf("duplicate rollout %q", rc.GetName())
+		}
+		names[rc.GetName()] = true
+		r := &rollout{name: rc.GetName(), maxFailures: rc.GetMaxFailureRate(), minReports: max(int(rc.GetMinReports()), 1)}
+		var ok bool
+		if r.target, ok = targets[rc.GetTarget()]; !ok {
+			return nil, fmt.Errorf("unknown target image %q of rollout %q", rc.GetTarget(), rc.GetName())
+		}
+		if rc.GetPrevious() != "" {
+			if r.previous, ok = targets[rc.GetPrevious()]; !ok {
+				return nil, fmt.Errorf("unknown previous image %q of rollout %q", rc.GetPrevious(), rc.GetName())
+			}
+		}
+		if r.maxFailures < 0 || r.maxFailures > 1 {
+			return nil, fmt.Errorf("max failure rate of rollout %q must be between 0 and 1, got %v", rc.GetName(), r.maxFailures)
+		}
+		for _, gc := range rc.GetGroups() {
+			g := &rolloutGroup{parts: toSet(gc.GetPartNumbers()), chassis: toSet(gc.GetChassis()), percentage: gc.GetPercentage()}
+			// A group without criteria would match every chassis: that is spelled with a percentage of 100.
+			if len(g.parts) == 0 && len(g.chassis) == 0 && g.percentage == 0 && gc.GetHostnamePattern() == "" {
+				return nil, fmt.Errorf("group %q of rollout %q sets no criteria", gc.GetName(), rc.GetName())
+			}
+			if gc.GetHostnamePattern() != "" {
+				re, err := regexp.Compile("^(?:" + gc.GetHostnamePattern() + ")$")
+				if err != nil {
+					return nil, fmt.Errorf("invalid hostname pattern of group %q of rollout %q: %v", gc.GetName(), rc.GetName(), err)
+				}
+				g.hostname = re
+			}
+			if g.percentage > 100 {
+				return nil, fmt.Errorf("percentage of group %q of rollout %q must be at most 100, got %v", gc.GetName(), rc.GetName(), g.percentage)
+			}
+			r.groups = append(r.groups, g)
+		}
+		if p, ok := states[r.name]; ok && proto.Equal(p.target, r.target) {
+			r.state = p.state
+		} else {
+			r.state = &rolloutState{assigned: map[string]bool{}, failed: map[string]bool{}}
+		}
+		rollouts = append(rollouts, r)
+	}
+	return rollouts, nil
+}
+
+// ValidateRollouts checks the image targets and the rollouts of the config.
+func ValidateRollouts(config *cpb.Config) error {
+	_, err := newRollouts(config, nil)
+	return err
+}
@@ -0,0 +1,297 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chassismanager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+var (
+	currentImage = &bpb.SoftwareImage{Name: "os", Version: "1.0", Url: "http://10.0.0.1/os-1.0.bin", OsImageHash: "ab"}
+	newImage     = &bpb.SoftwareImage{Name: "os", Version: "2.0", Url: "http://10.0.0.1/os-2.0.bin", OsImageHash: "cd"}
+	safeImage    = &bpb.SoftwareImage{Name: "os", Version: "1.1", Url: "http://10.0.0.1/os-1.1.bin", OsImageHash: "ef"}
+	imageTargets = []*cpb.ImageTarget{{Name: "new", Image: newImage}, {Name: "safe", Image: safeImage}}
+)
+
+func newChassis(hostname, serial, partNumber string) *cpb.Chassis {
+	return &cpb.Chassis{
+		Hostname:      hostname,
+		IntendedImage: currentImage,
+		ControlCards:  []*cpb.ControlCard{{SerialNumber: serial, PartNumber: partNumber}},
+	}
+}
+
+// intendedVersion returns the version of the intended image handed out to the control card.
+func intendedVersion(t *testing.T, m *InMemoryChassisManager, serial string) string {
+	t.Helper()
+	resp, err := m.GenerateBootstrapData(context.Background(), nil, serial)
+	if err != nil {
+		t.Fatalf("GenerateBootstrapData(%v) err = %v, want nil", serial, err)
+	}
+	return resp.GetIntendedImage().GetVersion()
+}
+
+func report(t *testing.T, m *InMemoryChassisManager, serial string, status bpb.ReportStatusRequest_BootstrapStatus) {
+	t.Helper()
+	req := &bpb.ReportStatusRequest{Status: status, States: []*bpb.ControlCardState{{SerialNumber: serial}}}
+	if err := m.UpdateStatus(context.Background(), req); err != nil {
+		t.Fatalf("UpdateStatus() err = %v, want nil", err)
+	}
+}
+
+func TestRolloutGroups(t *testing.T) {
+	chassis := newChassis("lab-edge-1", "123", "PN-A")
+	tests := []struct {
+		desc   string
+		groups []*cpb.RolloutGroup
+		want   string
+	}{{
+		desc: "No groups",
+		want: "1.0",
+	}, {
+		desc:   "Part number",
+		groups: []*cpb.RolloutGroup{{PartNumbers: []string{"PN-B", "PN-A"}}},
+		want:   "2.0",
+	}, {
+		desc:   "Other part number",
+		groups: []*cpb.RolloutGroup{{PartNumbers: []string{"PN-B"}}},
+		want:   "1.0",
+	}, {
+		desc:   "Hostname pattern",
+		groups: []*cpb.RolloutGroup{{HostnamePattern: "lab-.*"}},
+		want:   "2.0",
+	}, {
+		desc:   "Partial hostname match",
+		groups: []*cpb.RolloutGroup{{HostnamePattern: "edge"}},
+		want:   "1.0",
+	}, {
+		desc:   "Explicit hostname",
+		groups: []*cpb.RolloutGroup{{Chassis: []string{"lab-edge-1"}}},
+		want:   "2.0",
+	}, {
+		desc:   "Explicit serial number",
+		groups: []*cpb.RolloutGroup{{Chassis: []string{"123"}}},
+		want:   "2.0",
+	}, {
+		desc:   "All criteria must match",
+		groups: []*cpb.RolloutGroup{{PartNumbers: []string{"PN-A"}, HostnamePattern: "prod-.*"}},
+		want:   "1.0",
+	}, {
+		desc:   "Any group matches",
+		groups: []*cpb.RolloutGroup{{HostnamePattern: "prod-.*"}, {PartNumbers: []string{"PN-A"}}},
+		want:   "2.0",
+	}, {
+		desc:   "Full percentage",
+		groups: []*cpb.RolloutGroup{{Percentage: 100}},
+		want:   "2.0",
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			m := New(&cpb.Config{
+				Chassis:      []*cpb.Chassis{chassis},
+				ImageTargets: imageTargets,
+				Rollouts:     []*cpb.Rollout{{Name: "os-2.0", Target: "new", Groups: test.groups}},
+			})
+			if got := intendedVersion(t, m, "123"); got != test.want {
+				t.Errorf("GenerateBootstrapData() intended image version = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// The first rollout the chassis belongs to applies.
+	m := New(&cpb.Config{
+		Chassis:      []*cpb.Chassis{chassis},
+		ImageTargets: imageTargets,
+		Rollouts: []*cpb.Rollout{
+			{Name: "os-1.1", Target: "safe", Groups: []*cpb.RolloutGroup{{Chassis: []string{"123"}}}},
+			{Name: "os-2.0", Target: "new", Groups: []*cpb.RolloutGroup{{Percentage: 100}}},
+		},
+	})
+	if got := intendedVersion(t, m, "123"); got != "1.1" {
+		t.Errorf("GenerateBootstrapData() intended image version = %v, want 1.1", got)
+	}
+}
+
+func TestRolloutPercentage(t *testing.T) {
+	config := &cpb.Config{ImageTargets: imageTargets}
+	for i := 0; i < 1000; i++ {
+		config.Chassis = append(config.Chassis, newChassis(fmt.Sprintf("host-%d", i), fmt.Sprintf("SN%d", i), ""))
+	}
+	selected := func(percentage uint32) map[string]bool {
+		c := proto.Clone(config).(*cpb.Config)
+		c.Rollouts = []*cpb.Rollout{{Name: "os-2.0", Target: "new", Groups: []*cpb.RolloutGroup{{Percentage: percentage}}}}
+		m := New(c)
+		s := map[string]bool{}
+		for _, ch := range c.GetChassis() {
+			if serial := ch.GetControlCards()[0].GetSerialNumber(); intendedVersion(t, m, serial) == "2.0" {
+				s[serial] = true
+			}
+		}
+		return s
+	}
+	canary, stage := selected(5), selected(50)
+	if len(canary) < 20 || len(canary) > 80 {
+		t.Errorf("5%% of the chassis selected %d of 1000 chassis", len(canary))
+	}
+	if len(stage) < 400 || len(stage) > 600 {
+		t.Errorf("50%% of the chassis selected %d of 1000 chassis", len(stage))
+	}
+	for serial := range canary {
+		if !stage[serial] {
+			t.Errorf("chassis %v selected at 5%% is not selected at 50%%", serial)
+		}
+	}
+}
+
+func TestRolloutHalt(t *testing.T) {
+	config := &cpb.Config{
+		Chassis: []*cpb.Chassis{
+			newChassis("a", "A", ""),
+			newChassis("b", "B", ""),
+			newChassis("c", "C", ""),
+			newChassis("d", "D", ""),
+			newChassis("other", "O", ""),
+		},
+		ImageTargets: imageTargets,
+		Rollouts: []*cpb.Rollout{{
+			Name:           "os-2.0",
+			Target:         "new",
+			Previous:       "safe",
+			Groups:         []*cpb.RolloutGroup{{Chassis: []string{"a", "b", "c", "d"}}},
+			MaxFailureRate: 0.4,
+			MinReports:     2,
+		}},
+	}
+	m := New(config)
+	for _, serial := range []string{"A", "B", "C"} {
+		if got := intendedVersion(t, m, serial); got != "2.0" {
+			t.Fatalf("GenerateBootstrapData(%v) intended image version = %v, want 2.0", serial, got)
+		}
+	}
+	// The chassis which were not handed out the target image do not count.
+	report(t, m, "O", bpb.ReportStatusRequest_BOOTSTRAP_STATUS_FAILURE)
+	report(t, m, "D", bpb.ReportStatusRequest_BOOTSTRAP_STATUS_FAILURE)
+	// A single report is below the minimum number of reports.
+	report(t, m, "B", bpb.ReportStatusRequest_BOOTSTRAP_STATUS_FAILURE)
+	if got := intendedVersion(t, m, "D"); got != "2.0" {
+		t.Fatalf("GenerateBootstrapData(D) intended image version = %v before the halt, want 2.0", got)
+	}
+	report(t, m, "A", bpb.ReportStatusRequest_BOOTSTRAP_STATUS_SUCCESS)
+	report(t, m, "C", bpb.ReportStatusRequest_BOOTSTRAP_STATUS_INITIATED)
+
+	// 1 failure out of 2 reports exceeds the 40% failure rate.
+	for serial, want := range map[string]string{"A": "2.0", "B": "1.1", "C": "1.1", "D": "1.1", "O": "1.0"} {
+		if got := intendedVersion(t, m, serial); got != want {
+			t.Errorf("GenerateBootstrapData(%v) intended image version = %v after the halt, want %v", serial, got, want)
+		}
+	}
+
+	// The rollout stays halted while its target is unchanged.
+	m.Update(config)
+	if got := intendedVersion(t, m, "C"); got != "1.1" {
+		t.Errorf("GenerateBootstrapData(C) intended image version = %v after a reload, want 1.1", got)
+	}
+	fixed := proto.Clone(config).(*cpb.Config)
+	fixed.ImageTargets[0].Image.Version = "2.0.1"
+	m.Update(fixed)
+	if got := intendedVersion(t, m, "C"); got != "2.0.1" {
+		t.Errorf("GenerateBootstrapData(C) intended image version = %v after a new target, want 2.0.1", got)
+	}
+}
+
+func TestRolloutRevertsToIntendedImage(t *testing.T) {
+	m := New(&cpb.Config{
+		Chassis:      []*cpb.Chassis{newChassis("a", "A", "")},
+		ImageTargets: imageTargets,
+		Rollouts: []*cpb.Rollout{{
+			Name:           "os-2.0",
+			Target:         "new",
+			Groups:         []*cpb.RolloutGroup{{Percentage: 100}},
+			MaxFailureRate: 0.5,
+		}},
+	})
+	intendedVersion(t, m, "A")
+	report(t, m, "A", bpb.ReportStatusRequest_BOOTSTRAP_STATUS_FAILURE)
+	if got := intendedVersion(t, m, "A"); got != "1.0" {
+		t.Errorf("GenerateBootstrapData() intended image version = %v after the halt, want 1.0", got)
+	}
+}
+
+func TestValidateRollouts(t *testing.T) {
+	tests := []struct {
+		desc     string
+		targets  []*cpb.ImageTarget
+		rollouts []*cpb.Rollout
+		wantErr  bool
+	}{{
+		desc:     "Valid",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new", Previous: "safe", Groups: []*cpb.RolloutGroup{{HostnamePattern: "lab-.*", Percentage: 10}}}},
+	}, {
+		desc:     "Unknown target",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-3.0", Target: "os-3.0"}},
+		wantErr:  true,
+	}, {
+		desc:     "Unknown previous image",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new", Previous: "old"}},
+		wantErr:  true,
+	}, {
+		desc:    "Duplicate image target",
+		targets: []*cpb.ImageTarget{{Name: "new", Image: newImage}, {Name: "new", Image: safeImage}},
+		wantErr: true,
+	}, {
+		desc:     "Duplicate rollout",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new"}, {Name: "os-2.0", Target: "safe"}},
+		wantErr:  true,
+	}, {
+		desc:     "Invalid hostname pattern",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new", Groups: []*cpb.RolloutGroup{{HostnamePattern: "lab-("}}}},
+		wantErr:  true,
+	}, {
+		desc:     "Group without criteria",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new", Groups: []*cpb.RolloutGroup{{Name: "all"}}}},
+		wantErr:  true,
+	}, {
+		desc:     "Percentage above 100",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new", Groups: []*cpb.RolloutGroup{{Percentage: 101}}}},
+		wantErr:  true,
+	}, {
+		desc:     "Failure rate above 1",
+		targets:  imageTargets,
+		rollouts: []*cpb.Rollout{{Name: "os-2.0", Target: "new", MaxFailureRate: 5}},
+		wantErr:  true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := ValidateRollouts(&cpb.Config{ImageTargets: test.targets, Rollouts: test.rollouts})
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateRollouts() err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
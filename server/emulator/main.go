@@ -21,11 +21,16 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/openconfig/bootz/dhcp"
 	"github.com/openconfig/bootz/http"
 	"github.com/openconfig/bootz/server"
+	"github.com/openconfig/bootz/tftp"
 	"google.golang.org/protobuf/encoding/prototext"
 
 	log "github.com/golang/glog"
@@ -39,18 +44,61 @@ var (
 	dhcpFile    = flag.String("dhcp_file", "", "DHCP config file.")
 	httpAddress = flag.String("http_address", "", "HTTP server address.")
 	httpFolder  = flag.String("http_folder", "", "HTTP serving folder.")
-)
+	httpTLS     = flag.Bool("http_tls", false, "Serve the images over HTTPS with a certificate issued from the trust anchor.")
+	httpIDevID  = flag.Bool("http_require_idevid", false, "Require the devices to present their IDevID to download images over HTTPS.")
 
-func main() {
-	flag.Parse()
+	httpMaxDownloads       = flag.Int("http_max_downloads", 0, "Maximum number of concurrent image downloads, 0 for no limit.")
+	httpMaxClientDownloads = flag.Int("http_max_client_downloads", 0, "Maximum number of concurrent image downloads of a device, 0 for no limit.")
+	httpMaxQueued          = flag.Int("http_max_queued", 0, "Maximum number of image downloads waiting for one of the --http_max_downloads slots, 0 for no limit.")
+	httpBandwidth          = flag.Int64("http_bandwidth", 0, "Total bandwidth of the image downloads in bytes per second, 0 for no limit.")
+	httpClientBandwidth    = flag.Int64("http_client_bandwidth", 0, "Bandwidth of the image downloads of a device in bytes per second, 0 for no limit.")
+
+	httpAdminAddress   = flag.String("http_admin_address", "", "Address of the HTTP admin listener, which serves the image uploads and the metrics. Disabled if empty.")
+	httpAdminTokenFile = flag.String("http_admin_token_file", "", "File of the bearer token the HTTP admin requests must present.")
+	httpMaxUploadSize  = flag.Int64("http_max_upload_size", 0, "Maximum size of the uploaded images in bytes, 0 for 8 GiB.")
 
+	tftpAddress      = flag.String("tftp_address", "", "TFTP server address.")
+	tftpFolder       = flag.String("tftp_folder", "", "TFTP serving folder. Defaults to --http_folder.")
+	tftpMaxBlockSize = flag.Int("tftp_max_block_size", 0, "Maximum negotiated TFTP block size, 0 for 65464.")
+	tftpUnsigned     = flag.Bool("tftp_allow_unsigned", false, "Start the TFTP server although image_url_signing is set, which it bypasses.")
+)
+
+// readConfig reads the Bootz config file.
+func readConfig() (*cpb.Config, error) {
 	configBytes, err := os.ReadFile(*configFile)
 	if err != nil {
-		log.Exitf("failed to read Bootz config file: %v. Specify with argument '--config_file path/to/file'", err)
+		return nil, fmt.Errorf("failed to read Bootz config file: %v. Specify with argument '--config_file path/to/file'", err)
 	}
 	config := &cpb.Config{}
 	if err := prototext.Unmarshal(configBytes, config); err != nil {
-		log.Exitf("failed to unmarshal Bootz config file: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal Bootz config file: %v", err)
+	}
+	return config, nil
+}
+
+// reloadOnHangup updates the inventory of the server from the Bootz config file whenever the process receives SIGHUP.
+func reloadOnHangup(s *server.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		config, err := readConfig()
+		if err == nil {
+			err = s.UpdateInventory(config)
+		}
+		if err != nil {
+			log.Errorf("failed to reload the inventory: %v", err)
+			continue
+		}
+		log.Infof("Reloaded the inventory from %v", *configFile)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	config, err := readConfig()
+	if err != nil {
+		log.Exit(err)
 	}
 	if config.GetServerAddress() == "" {
 		log.Exit("no server address found in Bootz config file.")
@@ -73,9 +121,44 @@ func main() {
 	}
 
 	if *httpAddress != "" && *httpFolder != "" {
+		var admin *http.AdminOpts
+		if *httpAdminAddress != "" {
+			token, err := os.ReadFile(*httpAdminTokenFile)
+			if err != nil {
+				log.Exitf("failed to read HTTP admin token file: %v. Specify with argument '--http_admin_token_file path/to/file'", err)
+			}
+			admin = &http.AdminOpts{
+				Address:       *httpAdminAddress,
+				Token:         strings.TrimSpace(string(token)),
+				MaxUploadSize: *httpMaxUploadSize,
+			}
+		}
 		opts = append(opts, &http.Opts{
-			Address: *httpAddress,
-			Folder:  *httpFolder,
+			Address:           *httpAddress,
+			Folder:            *httpFolder,
+			HTTPS:             *httpTLS,
+			RequireClientCert: *httpIDevID,
+			Limits: &http.Limits{
+				MaxDownloads:       *httpMaxDownloads,
+				MaxClientDownloads: *httpMaxClientDownloads,
+				MaxQueued:          *httpMaxQueued,
+				Bandwidth:          *httpBandwidth,
+				ClientBandwidth:    *httpClientBandwidth,
+			},
+			Admin: admin,
+		})
+	}
+
+	if *tftpAddress != "" {
+		folder := *tftpFolder
+		if folder == "" {
+			folder = *httpFolder
+		}
+		opts = append(opts, &tftp.Opts{
+			Address:             *tftpAddress,
+			Folder:              folder,
+			MaxBlockSize:        *tftpMaxBlockSize,
+			AllowUnsignedAccess: *tftpUnsigned,
 		})
 	}
 
@@ -87,6 +170,7 @@ func main() {
 	if err != nil {
 		log.Exit(err)
 	}
+	go reloadOnHangup(s)
 
 	if err := s.Start(); err != nil {
 		log.Exit(err)
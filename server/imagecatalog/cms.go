@@ -0,0 +1,191 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagecatalog
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// CMSVerifier verifies CMS/PKCS#7 detached signatures against vendor certificates. The signer certificate must be one
+// of the vendor certificates, or be issued by one of them through the certificates embedded in the signature.
+// The image is streamed through the SHA-256, SHA-384 or SHA-512 digest of the signers rather than read into memory.
+type CMSVerifier struct {
+	certs []*x509.Certificate
+	roots *x509.CertPool
+}
+
+// NewCMSVerifier returns a verifier of the signatures of the vendor certificates.
+func NewCMSVerifier(certs []*x509.Certificate) (*CMSVerifier, error) {
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no vendor certificates")
+	}
+	v := &CMSVerifier{certs: certs, roots: x509.NewCertPool()}
+	for _, c := range certs {
+		v.roots.AddCert(c)
+	}
+	return v, nil
+}
+
+// digestAlgorithms are the supported digest algorithms of the signers, by OID.
+var digestAlgorithms = map[string]crypto.Hash{
+	pkcs7.OIDDigestAlgorithmSHA256.String(): crypto.SHA256,
+	pkcs7.OIDDigestAlgorithmSHA384.String(): crypto.SHA384,
+	pkcs7.OIDDigestAlgorithmSHA512.String(): crypto.SHA512,
+}
+
+// Verify verifies the detached signature of the image.
+func (v *CMSVerifier) Verify(image io.Reader, signature []byte) error {
+	p7, err := pkcs7.Parse(signature)
+	if err != nil {
+		return fmt.Errorf("unable to parse signature: %v", err)
+	}
+	if len(p7.Content) != 0 {
+		return fmt.Errorf("signature is not detached")
+	}
+	if len(p7.Signers) == 0 {
+		return fmt.Errorf("signature has no signers")
+	}
+	// The image is hashed once with each digest algorithm of the signers.
+	hashes := map[crypto.Hash]hash.Hash{}
+	var writers []io.Writer
+	for _, s := range p7.Signers {
+		h, ok := digestAlgorithms[s.DigestAlgorithm.Algorithm.String()]
+		if !ok {
+			return fmt.Errorf("unsupported digest algorithm %v", s.DigestAlgorithm.Algorithm)
+		}
+		if _, ok := hashes[h]; !ok {
+			hashes[h] = h.New()
+			writers = append(writers, hashes[h])
+		}
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), image); err != nil {
+		return fmt.Errorf("unable to read image: %v", err)
+	}
+	// The signatures of the vendor certificates need not embed them.
+	certs := append(p7.Certificates, v.certs...)
+	for i, s := range p7.Signers {
+		h := digestAlgorithms[s.DigestAlgorithm.Algorithm.String()]
+		if err := v.verifySigner(p7, i, certs, h, hashes[h].Sum(nil)); err != nil {
+			return fmt.Errorf("invalid signature: %v", err)
+		}
+	}
+	return nil
+}
+
+// verifySigner verifies the signature of the i-th signer of p7, given the digest of the image with its digest
+// algorithm h, and the chain of its certificate to the vendor certificates.
+func (v *CMSVerifier) verifySigner(p7 *pkcs7.PKCS7, i int, certs []*x509.Certificate, h crypto.Hash, imageDigest []byte) error {
+	s := p7.Signers[i]
+	var cert *x509.Certificate
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, s.IssuerAndSerialNumber.IssuerName.FullBytes) && c.SerialNumber.Cmp(s.IssuerAndSerialNumber.SerialNumber) == 0 {
+			cert = c
+			break
+		}
+	}
+	if cert == nil {
+		return fmt.Errorf("no certificate for signer")
+	}
+	digest := imageDigest
+	verifyTime := time.Now()
+	if len(s.AuthenticatedAttributes) > 0 {
+		// The signer signs the signed attributes, which carry the digest of the image.
+		var messageDigest []byte
+		for _, a := range s.AuthenticatedAttributes {
+			switch {
+			case a.Type.Equal(pkcs7.OIDAttributeMessageDigest):
+				if _, err := asn1.Unmarshal(a.Value.Bytes, &messageDigest); err != nil {
+					return fmt.Errorf("unable to parse message digest: %v", err)
+				}
+			case a.Type.Equal(pkcs7.OIDAttributeSigningTime):
+				var signingTime time.Time
+				if _, err := asn1.Unmarshal(a.Value.Bytes, &signingTime); err != nil {
+					return fmt.Errorf("unable to parse signing time: %v", err)
+				}
+				if signingTime.Before(cert.NotBefore) || signingTime.After(cert.NotAfter) {
+					return fmt.Errorf("signing time %v is outside of the validity of the certificate", signingTime)
+				}
+				verifyTime = signingTime
+			}
+		}
+		if subtle.ConstantTimeCompare(messageDigest, imageDigest) != 1 {
+			return fmt.Errorf("image digest does not match the signed message digest")
+		}
+		attrs, err := asn1.MarshalWithParams(s.AuthenticatedAttributes, "set")
+		if err != nil {
+			return fmt.Errorf("unable to encode signed attributes: %v", err)
+		}
+		d := h.New()
+		d.Write(attrs)
+		digest = d.Sum(nil)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		CurrentTime:   verifyTime,
+	}); err != nil {
+		return fmt.Errorf("unable to verify certificate chain: %v", err)
+	}
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, h, digest, s.EncryptedDigest)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, s.EncryptedDigest) {
+			return fmt.Errorf("ecdsa verification failure")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key %T", pub)
+	}
+}
+
+// newVerifier returns the CMS verifier of the vendor certificates of the config.
+func newVerifier(config *cpb.ImageVerification) (*CMSVerifier, error) {
+	var certs []*x509.Certificate
+	for _, c := range config.GetVendorCerts() {
+		der, err := base64.StdEncoding.DecodeString(c)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode vendor certificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse vendor certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return NewCMSVerifier(certs)
+}
@@ -0,0 +1,194 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagecatalog
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
+	"go.mozilla.org/pkcs7"
+
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+func newCert(t *testing.T, serial int64, cn string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+// sign returns a detached CMS signature of the image with SHA-256, embedding the signer certificate and the parents.
+func sign(t *testing.T, image string, cert *x509.Certificate, key crypto.Signer, parents ...*x509.Certificate) []byte {
+	t.Helper()
+	return signWith(t, pkcs7.OIDDigestAlgorithmSHA256, image, cert, key, parents...)
+}
+
+// signWith returns a detached CMS signature of the image with the digest algorithm.
+func signWith(t *testing.T, digest asn1.ObjectIdentifier, image string, cert *x509.Certificate, key crypto.Signer, parents ...*x509.Certificate) []byte {
+	t.Helper()
+	sd, err := pkcs7.NewSignedData([]byte(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd.SetDigestAlgorithm(digest)
+	if err := sd.AddSignerChain(cert, key, parents, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	sd.Detach()
+	sig, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestCMSVerifier(t *testing.T) {
+	vendorCA, vendorCAKey := newCert(t, 1, "Vendor CA", true, nil, nil)
+	signer, signerKey := newCert(t, 2, "Vendor image signing", false, vendorCA, vendorCAKey)
+	selfSigned, selfSignedKey := newCert(t, 3, "Vendor image signing", false, nil, nil)
+	other, otherKey := newCert(t, 4, "Other", false, nil, nil)
+	rsaSigner, rsaSignerKey, err := ownercertificate.NewRSACertificate("Vendor RSA image signing", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attached, err := pkcs7.NewSignedData([]byte("image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := attached.AddSigner(selfSigned, selfSignedKey, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	attachedSig, err := attached.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewCMSVerifier([]*x509.Certificate{vendorCA, selfSigned, rsaSigner})
+	if err != nil {
+		t.Fatalf("NewCMSVerifier() err = %v, want nil", err)
+	}
+	tests := []struct {
+		desc      string
+		image     string
+		signature []byte
+		wantErr   bool
+	}{{
+		desc:      "Signed by a certificate of the vendor CA",
+		image:     "image",
+		signature: sign(t, "image", signer, signerKey),
+	}, {
+		desc:      "Signed by a vendor certificate",
+		image:     "image",
+		signature: sign(t, "image", selfSigned, selfSignedKey),
+	}, {
+		desc:      "Signed with SHA-512",
+		image:     "image",
+		signature: signWith(t, pkcs7.OIDDigestAlgorithmSHA512, "image", signer, signerKey),
+	}, {
+		desc:      "Signed with RSA",
+		image:     "image",
+		signature: sign(t, "image", rsaSigner, rsaSignerKey),
+	}, {
+		desc:      "Signed with SHA-1",
+		image:     "image",
+		signature: signWith(t, pkcs7.OIDDigestAlgorithmSHA1, "image", signer, signerKey),
+		wantErr:   true,
+	}, {
+		desc:      "Other image",
+		image:     "forged image",
+		signature: sign(t, "image", signer, signerKey),
+		wantErr:   true,
+	}, {
+		desc:      "Signed by another certificate",
+		image:     "image",
+		signature: sign(t, "image", other, otherKey),
+		wantErr:   true,
+	}, {
+		desc:      "Attached signature",
+		image:     "image",
+		signature: attachedSig,
+		wantErr:   true,
+	}, {
+		desc:      "Malformed signature",
+		image:     "image",
+		signature: []byte("signature"),
+		wantErr:   true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := v.Verify(strings.NewReader(test.image), test.signature); (err != nil) != test.wantErr {
+				t.Errorf("Verify() err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCatalogCMSVerification(t *testing.T) {
+	vendorCA, vendorCAKey := newCert(t, 1, "Vendor CA", true, nil, nil)
+	signer, signerKey := newCert(t, 2, "Vendor image signing", false, vendorCA, vendorCAKey)
+	folder := t.TempDir()
+	writeImage(t, folder, "os", "1.0", "os.bin", "image")
+	writeImage(t, folder, "os", "1.0", "os.bin.sig", string(sign(t, "image", signer, signerKey)))
+
+	if _, err := New(&cpb.ImageCatalog{Folder: folder, Url: "http://10.0.0.1", Verification: &cpb.ImageVerification{}}); err == nil {
+		t.Errorf("New() without vendor certificates err = nil, want error")
+	}
+	c := newCatalog(t, &cpb.ImageCatalog{
+		Folder:       folder,
+		Url:          "http://10.0.0.1",
+		Verification: &cpb.ImageVerification{VendorCerts: []string{base64.StdEncoding.EncodeToString(vendorCA.Raw)}},
+	})
+	if i, _ := c.Lookup("os", "1.0"); !i.Verified {
+		t.Errorf("Lookup() = %+v, want a verified image", i)
+	}
+}
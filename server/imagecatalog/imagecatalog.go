@@ -0,0 +1,539 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagecatalog implements a content-addressed catalog of the OS images served to the devices.
+// Images are dropped in, or uploaded to, "<folder>/<name>/<version>/". The catalog computes their digests and stores
+// them by SHA-256 digest in "<folder>/sha256/", from where the devices download them. With a Verifier, the images are
+// verified by the detached vendor signature in "<image file>.sig" when they are added.
+package imagecatalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	"github.com/openconfig/bootz/common/imagehash"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+const (
+	// HashSHA256 identifies the SHA-256 hash algorithm in a SoftwareImage.
+	HashSHA256 = imagehash.SHA256
+	// HashSHA384 identifies the SHA-384 hash algorithm in a SoftwareImage.
+	HashSHA384 = imagehash.SHA384
+	// HashSHA512 identifies the SHA-512 hash algorithm in a SoftwareImage.
+	HashSHA512 = imagehash.SHA512
+
+	// SignatureSuffix is the suffix of the detached signature file of an image file.
+	SignatureSuffix = ".sig"
+
+	// casDir is the folder of the catalog in which the images are stored by SHA-256 digest.
+	casDir              = "sha256"
+	defaultScanInterval = 60 * time.Second
+)
+
+// ErrNotFound is returned when an image is not in the catalog.
+var ErrNotFound = errors.New("image not found")
+
+// Image is an image of the catalog.
+type Image struct {
+	Name    string
+	Version string
+	// File is the path of the image file relative to the catalog folder.
+	File    string
+	Size    int64
+	ModTime time.Time
+	// SHA256, SHA384 and SHA512 are the lowercase hex encoded digests of the image.
+	SHA256 string
+	SHA384 string
+	SHA512 string
+	// Signature is the path of the detached signature file relative to the catalog folder, empty if there is none.
+	Signature string
+	// Verified is whether the signature of the image was verified.
+	Verified bool
+
+	signatureModTime time.Time
+}
+
+// Path returns the content-addressed path of the image relative to the catalog folder.
+func (i *Image) Path() string {
+	return path.Join(casDir, i.SHA256)
+}
+
+// Verifier verifies the detached signature of an image file, against the vendor public keys it is configured with.
+type Verifier interface {
+	Verify(image io.Reader, signature []byte) error
+}
+
+// Option configures a Catalog.
+type Option func(*Catalog)
+
+// WithVerifier verifies the images with the verifier, instead of the CMS verifier of the config.
+func WithVerifier(v Verifier) Option {
+	return func(c *Catalog) {
+		c.verifier = v
+	}
+}
+
+// Catalog is a content-addressed catalog of images.
+type Catalog struct {
+	folder    string
+	url       string
+	algorithm string
+	verifier  Verifier
+
+	// scanMu serializes the scans and uploads.
+	scanMu sync.Mutex
+	mu     sync.RWMutex
+	images map[string]*Image
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func key(name, version string) string {
+	return name + "/" + version
+}
+
+// Lookup returns the image with the given name and version.
+func (c *Catalog) Lookup(name, version string) (*Image, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i, ok := c.images[key(name, version)]
+	return i, ok
+}
+
+// Images returns the images of the catalog, ordered by name and version.
+func (c *Catalog) Images() []*Image {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]*Image, 0, len(c.images))
+	for _, i := range c.images {
+		out = append(out, i)
+	}
+	sort.Slice(out, func(a, b int) bool {
+		return key(out[a].Name, out[a].Version) < key(out[b].Name, out[b].Version)
+	})
+	return out
+}
+
+// digest returns the digest of the image for the hash algorithm.
+func (i *Image) digest(algorithm string) string {
+	switch algorithm {
+	case HashSHA384:
+		return i.SHA384
+	case HashSHA512:
+		return i.SHA512
+	default:
+		return i.SHA256
+	}
+}
+
+// Resolve returns the software image with the given name and version, with its download URL and hash in the canonical
+// representation of os_image_hash. The returned
+// error wraps ErrNotFound if the image is not in the catalog.
+func (c *Catalog) Resolve(name, version string) (*bpb.SoftwareImage, error) {
+	i, ok := c.Lookup(name, version)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v version %v", ErrNotFound, name, version)
+	}
+	hash, err := imagehash.Normalize(i.digest(c.algorithm))
+	if err != nil {
+		return nil, err
+	}
+	return &bpb.SoftwareImage{
+		Name:          name,
+		Version:       version,
+		Url:           c.url + "/" + i.Path(),
+		OsImageHash:   hash,
+		HashAlgorithm: c.algorithm,
+	}, nil
+}
+
+// Verified returns whether the image with the hash of the software image is in the catalog and its signature was
+// verified.
+func (c *Catalog) Verified(image *bpb.SoftwareImage) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, i := range c.images {
+		if i.Verified && imagehash.Equal(i.digest(image.GetHashAlgorithm()), image.GetOsImageHash()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan updates the catalog from the image files in the folder. The digests of the files which did not change since the
+// previous scan are not computed again, and the content-addressed files no image refers to are removed.
+func (c *Catalog) Scan() error {
+	c.scanMu.Lock()
+	defer c.scanMu.Unlock()
+
+	previous := map[string]*Image{}
+	for _, i := range c.Images() {
+		previous[i.File] = i
+	}
+	images := map[string]*Image{}
+	names, err := os.ReadDir(c.folder)
+	if err != nil {
+		return fmt.Errorf("unable to read image folder: %v", err)
+	}
+	for _, n := range names {
+		if !n.IsDir() || n.Name() == casDir || hidden(n.Name()) {
+			continue
+		}
+		versions, err := os.ReadDir(filepath.Join(c.folder, n.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to read image folder: %v", err)
+		}
+		for _, v := range versions {
+			if !v.IsDir() || hidden(v.Name()) {
+				continue
+			}
+			i, err := c.scanVersion(n.Name(), v.Name(), previous)
+			if err != nil {
+				log.Warningf("Skipping image %v version %v: %v", n.Name(), v.Name(), err)
+				continue
+			}
+			images[key(i.Name, i.Version)] = i
+		}
+	}
+
+	c.mu.Lock()
+	c.images = images
+	c.mu.Unlock()
+	c.prune(images)
+	return nil
+}
+
+// scanVersion returns the image of a version folder, which must contain exactly one image file, and possibly its
+// signature file.
+func (c *Catalog) scanVersion(name, version string, previous map[string]*Image) (*Image, error) {
+	entries, err := os.ReadDir(filepath.Join(c.folder, name, version))
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	signatures := map[string]os.FileInfo{}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || hidden(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(e.Name(), SignatureSuffix) {
+			signatures[e.Name()] = info
+			continue
+		}
+		files = append(files, info)
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("found %d image files, want 1", len(files))
+	}
+	file := path.Join(name, version, files[0].Name())
+	i := &Image{Name: name, Version: version, File: file, Size: files[0].Size(), ModTime: files[0].ModTime()}
+	if sig, ok := signatures[files[0].Name()+SignatureSuffix]; ok {
+		i.Signature = file + SignatureSuffix
+		i.signatureModTime = sig.ModTime()
+	}
+	p, ok := previous[file]
+	if ok && p.Size == i.Size && p.ModTime.Equal(i.ModTime) {
+		if p.Signature == i.Signature && p.signatureModTime.Equal(i.signatureModTime) {
+			return p, nil
+		}
+		// Only the signature changed.
+		i.SHA256, i.SHA384, i.SHA512 = p.SHA256, p.SHA384, p.SHA512
+	} else {
+		if err := c.store(i); err != nil {
+			return nil, err
+		}
+		log.Infof("Added image %v version %v with SHA-256 digest %v", name, version, i.SHA256)
+	}
+	c.verify(i)
+	return i, nil
+}
+
+// verify verifies the signature of the image with the verifier of the catalog, if any.
+func (c *Catalog) verify(i *Image) {
+	if c.verifier == nil {
+		return
+	}
+	err := func() error {
+		if i.Signature == "" {
+			return fmt.Errorf("no signature file")
+		}
+		signature, err := os.ReadFile(filepath.Join(c.folder, filepath.FromSlash(i.Signature)))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(filepath.Join(c.folder, filepath.FromSlash(i.Path())))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return c.verifier.Verify(f, signature)
+	}()
+	if err != nil {
+		log.Warningf("Image %v version %v is not verified: %v", i.Name, i.Version, err)
+		return
+	}
+	i.Verified = true
+	log.Infof("Verified the signature of image %v version %v", i.Name, i.Version)
+}
+
+// store copies the image file to its content-addressed path, read-only, and computes the digests of the copy. The
+// catalog serves and verifies the copy, which later changes of the image file do not affect.
+func (c *Catalog) store(i *Image) error {
+	f, err := os.Open(filepath.Join(c.folder, filepath.FromSlash(i.File)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tmp, err := os.CreateTemp(filepath.Join(c.folder, casDir), ".store-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	h256, h384, h512 := sha256.New(), sha512.New384(), sha512.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h256, h384, h512), f); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to read image: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	i.SHA256 = hex.EncodeToString(h256.Sum(nil))
+	i.SHA384 = hex.EncodeToString(h384.Sum(nil))
+	i.SHA512 = hex.EncodeToString(h512.Sum(nil))
+
+	dst := filepath.Join(c.folder, filepath.FromSlash(i.Path()))
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	if err := os.Chmod(tmp.Name(), 0o444); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// prune removes the content-addressed files no image refers to.
+func (c *Catalog) prune(images map[string]*Image) {
+	used := map[string]bool{}
+	for _, i := range images {
+		used[i.SHA256] = true
+	}
+	entries, err := os.ReadDir(filepath.Join(c.folder, casDir))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !used[e.Name()] {
+			if err := os.Remove(filepath.Join(c.folder, casDir, e.Name())); err != nil {
+				log.Warningf("Unable to remove unused image %v: %v", e.Name(), err)
+			}
+		}
+	}
+}
+
+// Add uploads an image file to the catalog, replacing the image with the same name and version. A file name ending
+// with SignatureSuffix uploads the signature of the image file, which must have been uploaded first.
+func (c *Catalog) Add(name, version, filename string, r io.Reader) (*Image, error) {
+	for _, s := range []string{name, version, filename} {
+		if s == "" || hidden(s) || s != filepath.Base(s) || strings.ContainsAny(s, `/\`) {
+			return nil, fmt.Errorf("invalid image name, version or file name %q", s)
+		}
+	}
+	if name == casDir {
+		return nil, fmt.Errorf("image name %q is reserved", name)
+	}
+	dir := filepath.Join(c.folder, name, version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create image folder: %v", err)
+	}
+
+	c.scanMu.Lock()
+	err := func() error {
+		defer c.scanMu.Unlock()
+		signature := strings.HasSuffix(filename, SignatureSuffix)
+		if signature {
+			if _, err := os.Stat(filepath.Join(dir, strings.TrimSuffix(filename, SignatureSuffix))); err != nil {
+				return fmt.Errorf("signature %q uploaded without its image file: %v", filename, err)
+			}
+		}
+		if err := writeFile(dir, filepath.Join(dir, filename), r); err != nil {
+			return fmt.Errorf("unable to write image: %v", err)
+		}
+		if signature {
+			return nil
+		}
+		// The uploaded file replaces the previous image file of the version, and the signatures of other files.
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.Name() != filename && e.Name() != filename+SignatureSuffix && e.Type().IsRegular() && !hidden(e.Name()) {
+				os.Remove(filepath.Join(dir, e.Name()))
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Scan(); err != nil {
+		return nil, err
+	}
+	i, ok := c.Lookup(name, version)
+	if !ok {
+		return nil, fmt.Errorf("uploaded image %v version %v was not added to the catalog", name, version)
+	}
+	return i, nil
+}
+
+// ServeHTTP uploads the image file of a PUT request to "<name>/<version>/<file name>", relative to the handler path.
+func (c *Catalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", http.MethodPut)
+		http.Error(w, "images must be uploaded with PUT", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 3 {
+		http.Error(w, "upload path must be <name>/<version>/<file name>", http.StatusBadRequest)
+		return
+	}
+	i, err := c.Add(parts[0], parts[1], parts[2], r.Body)
+	if err != nil {
+		log.Warningf("Unable to upload image %v: %v", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, "%v\n", i.Path())
+}
+
+// writeFile atomically writes the content of r to the path, through a temporary file in dir.
+func writeFile(dir, path string, r io.Reader) error {
+	f, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func hidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func (c *Catalog) scanLoop(ctx context.Context, interval time.Duration) {
+	defer close(c.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Scan(); err != nil {
+				log.Warningf("Unable to scan the image catalog: %v", err)
+			}
+		}
+	}
+}
+
+// Close stops scanning the folder.
+func (c *Catalog) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// New returns a new catalog of the images in the folder of the config, which is scanned periodically.
+func New(config *cpb.ImageCatalog, opts ...Option) (*Catalog, error) {
+	if config.GetFolder() == "" {
+		return nil, fmt.Errorf("image catalog folder must be set")
+	}
+	u, err := url.Parse(config.GetUrl())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid image catalog URL %q", config.GetUrl())
+	}
+	c := &Catalog{
+		folder:    config.GetFolder(),
+		url:       strings.TrimSuffix(config.GetUrl(), "/"),
+		algorithm: config.GetHashAlgorithm(),
+		images:    map[string]*Image{},
+		done:      make(chan struct{}),
+	}
+	switch c.algorithm {
+	case "":
+		c.algorithm = HashSHA256
+	case HashSHA256, HashSHA384, HashSHA512:
+	default:
+		return nil, fmt.Errorf("unsupported image hash algorithm %q", c.algorithm)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.verifier == nil && config.GetVerification() != nil {
+		v, err := newVerifier(config.GetVerification())
+		if err != nil {
+			return nil, fmt.Errorf("invalid image verification: %v", err)
+		}
+		c.verifier = v
+	}
+	if err := os.MkdirAll(filepath.Join(c.folder, casDir), 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create image catalog folder: %v", err)
+	}
+	if err := c.Scan(); err != nil {
+		return nil, err
+	}
+	interval := defaultScanInterval
+	if s := config.GetScanIntervalSeconds(); s != 0 {
+		interval = time.Duration(s) * time.Second
+	}
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.scanLoop(ctx, interval)
+	return c, nil
+}
@@ -0,0 +1,350 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagecatalog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/openconfig/bootz/common/imagehash"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	bpb "github.com/openconfig/bootz/proto/bootz"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+func sha256Hex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func sha384Hex(s string) string {
+	h := sha512.Sum384([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func sha512Hex(s string) string {
+	h := sha512.Sum512([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// canonical returns the canonical representation of a hex digest in a SoftwareImage.
+func canonical(t *testing.T, digest string) string {
+	t.Helper()
+	c, err := imagehash.Normalize(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func writeImage(t *testing.T, folder, name, version, file, content string) {
+	t.Helper()
+	dir := filepath.Join(folder, name, version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newCatalog(t *testing.T, conf *cpb.ImageCatalog) *Catalog {
+	t.Helper()
+	c, err := New(conf)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		desc    string
+		conf    *cpb.ImageCatalog
+		wantErr bool
+	}{{
+		desc: "Default algorithm",
+		conf: &cpb.ImageCatalog{Url: "http://10.0.0.1:8080"},
+	}, {
+		desc: "SHA-512",
+		conf: &cpb.ImageCatalog{Url: "https://images.example.com/os", HashAlgorithm: HashSHA512},
+	}, {
+		desc:    "Missing URL",
+		conf:    &cpb.ImageCatalog{},
+		wantErr: true,
+	}, {
+		desc:    "Unsupported URL scheme",
+		conf:    &cpb.ImageCatalog{Url: "ftp://10.0.0.1"},
+		wantErr: true,
+	}, {
+		desc:    "Unsupported algorithm",
+		conf:    &cpb.ImageCatalog{Url: "http://10.0.0.1", HashAlgorithm: "md5"},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			test.conf.Folder = t.TempDir()
+			c, err := New(test.conf)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("New() err = %v, want error %v", err, test.wantErr)
+			}
+			if err == nil {
+				c.Close()
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	folder := t.TempDir()
+	writeImage(t, folder, "os", "1.0", "os-1.0.bin", "image 1.0")
+	writeImage(t, folder, "os", "2.0", "os-2.0.bin", "image 2.0")
+	// Versions without exactly one image file are skipped.
+	writeImage(t, folder, "os", "3.0", "a.bin", "a")
+	writeImage(t, folder, "os", "3.0", "b.bin", "b")
+	writeImage(t, folder, ".hidden", "1.0", "os.bin", "hidden")
+	c := newCatalog(t, &cpb.ImageCatalog{Folder: folder, Url: "http://10.0.0.1:8080/"})
+
+	want := []*Image{{
+		Name: "os", Version: "1.0", File: "os/1.0/os-1.0.bin", Size: 9, SHA256: sha256Hex("image 1.0"), SHA384: sha384Hex("image 1.0"), SHA512: sha512Hex("image 1.0"),
+	}, {
+		Name: "os", Version: "2.0", File: "os/2.0/os-2.0.bin", Size: 9, SHA256: sha256Hex("image 2.0"), SHA384: sha384Hex("image 2.0"), SHA512: sha512Hex("image 2.0"),
+	}}
+	if diff := cmp.Diff(want, c.Images(), cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".ModTime" }, cmp.Ignore()), cmpopts.IgnoreUnexported(Image{})); diff != "" {
+		t.Errorf("Images() diff (-want, +got):\n%s", diff)
+	}
+	got, err := os.ReadFile(filepath.Join(folder, "sha256", sha256Hex("image 1.0")))
+	if err != nil || string(got) != "image 1.0" {
+		t.Errorf("content-addressed image = %q, %v, want %q", got, err, "image 1.0")
+	}
+	if info, err := os.Stat(filepath.Join(folder, "sha256", sha256Hex("image 1.0"))); err != nil || info.Mode().Perm() != 0o444 {
+		t.Errorf("content-addressed image mode = %v, %v, want read-only", info.Mode().Perm(), err)
+	}
+	// Overwriting the image file in place does not change the content-addressed file.
+	if err := os.WriteFile(filepath.Join(folder, "os", "2.0", "os-2.0.bin"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = os.ReadFile(filepath.Join(folder, "sha256", sha256Hex("image 2.0")))
+	if err != nil || string(got) != "image 2.0" {
+		t.Errorf("content-addressed image after overwrite = %q, %v, want %q", got, err, "image 2.0")
+	}
+	writeImage(t, folder, "os", "2.0", "os-2.0.bin", "image 2.0")
+
+	wantImage := &bpb.SoftwareImage{
+		Name:          "os",
+		Version:       "2.0",
+		Url:           "http://10.0.0.1:8080/sha256/" + sha256Hex("image 2.0"),
+		OsImageHash:   canonical(t, sha256Hex("image 2.0")),
+		HashAlgorithm: HashSHA256,
+	}
+	gotImage, err := c.Resolve("os", "2.0")
+	if err != nil {
+		t.Fatalf("Resolve() err = %v, want nil", err)
+	}
+	if diff := cmp.Diff(wantImage, gotImage, protocmp.Transform()); diff != "" {
+		t.Errorf("Resolve() diff (-want, +got):\n%s", diff)
+	}
+	if _, err := c.Resolve("os", "3.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve() of a version with two files err = %v, want %v", err, ErrNotFound)
+	}
+
+	// Removing an image removes its content-addressed file.
+	if err := os.RemoveAll(filepath.Join(folder, "os", "1.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Scan(); err != nil {
+		t.Fatalf("Scan() err = %v, want nil", err)
+	}
+	if _, ok := c.Lookup("os", "1.0"); ok {
+		t.Errorf("Lookup() of a removed image found it")
+	}
+	if _, err := os.Stat(filepath.Join(folder, "sha256", sha256Hex("image 1.0"))); !os.IsNotExist(err) {
+		t.Errorf("content-addressed file of a removed image: err = %v, want not exist", err)
+	}
+}
+
+func TestResolveAlgorithm(t *testing.T) {
+	folder := t.TempDir()
+	writeImage(t, folder, "os", "1.0", "os.bin", "image")
+	for _, test := range []struct {
+		algorithm string
+		want      string
+	}{
+		{HashSHA384, sha384Hex("image")},
+		{HashSHA512, sha512Hex("image")},
+	} {
+		c := newCatalog(t, &cpb.ImageCatalog{Folder: folder, Url: "http://10.0.0.1", HashAlgorithm: test.algorithm})
+		got, err := c.Resolve("os", "1.0")
+		if err != nil {
+			t.Fatalf("Resolve() err = %v, want nil", err)
+		}
+		if got.GetOsImageHash() != canonical(t, test.want) || got.GetHashAlgorithm() != test.algorithm {
+			t.Errorf("Resolve() = %v, want the %v digest", got, test.algorithm)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	folder := t.TempDir()
+	c := newCatalog(t, &cpb.ImageCatalog{Folder: folder, Url: "http://10.0.0.1"})
+	tests := []struct {
+		desc       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{{
+		desc:       "Upload",
+		method:     http.MethodPut,
+		path:       "/os/1.0/os.bin",
+		body:       "image",
+		wantStatus: http.StatusCreated,
+	}, {
+		desc:       "Replace",
+		method:     http.MethodPut,
+		path:       "/os/1.0/os-new.bin",
+		body:       "new image",
+		wantStatus: http.StatusCreated,
+	}, {
+		desc:       "Signature",
+		method:     http.MethodPut,
+		path:       "/os/1.0/os-new.bin.sig",
+		body:       "signature",
+		wantStatus: http.StatusCreated,
+	}, {
+		desc:       "Signature without image",
+		method:     http.MethodPut,
+		path:       "/os/2.0/os.bin.sig",
+		body:       "signature",
+		wantStatus: http.StatusBadRequest,
+	}, {
+		desc:       "Wrong method",
+		method:     http.MethodPost,
+		path:       "/os/1.0/os.bin",
+		wantStatus: http.StatusMethodNotAllowed,
+	}, {
+		desc:       "Missing file name",
+		method:     http.MethodPut,
+		path:       "/os/1.0",
+		wantStatus: http.StatusBadRequest,
+	}, {
+		desc:       "Reserved name",
+		method:     http.MethodPut,
+		path:       "/sha256/1.0/os.bin",
+		wantStatus: http.StatusBadRequest,
+	}, {
+		desc:       "Hidden file",
+		method:     http.MethodPut,
+		path:       "/os/1.0/.os.bin",
+		wantStatus: http.StatusBadRequest,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c.ServeHTTP(w, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+			if w.Code != test.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", w.Code, test.wantStatus)
+			}
+		})
+	}
+
+	i, ok := c.Lookup("os", "1.0")
+	if !ok {
+		t.Fatalf("Lookup() of the uploaded image found nothing")
+	}
+	if i.File != "os/1.0/os-new.bin" || i.SHA256 != sha256Hex("new image") || i.Signature != "os/1.0/os-new.bin.sig" {
+		t.Errorf("Lookup() = %+v, want the replacing image", i)
+	}
+	if _, err := os.Stat(filepath.Join(folder, "sha256", sha256Hex("image"))); !os.IsNotExist(err) {
+		t.Errorf("content-addressed file of the replaced image: err = %v, want not exist", err)
+	}
+}
+
+// fakeVerifier verifies the signatures which are the image content reversed.
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(image io.Reader, signature []byte) error {
+	b, err := io.ReadAll(image)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(b)
+	if !bytes.Equal(b, signature) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func TestVerification(t *testing.T) {
+	folder := t.TempDir()
+	writeImage(t, folder, "os", "1.0", "os.bin", "image 1.0")
+	writeImage(t, folder, "os", "1.0", "os.bin.sig", "0.1 egami")
+	writeImage(t, folder, "os", "2.0", "os.bin", "image 2.0")
+	writeImage(t, folder, "os", "2.0", "os.bin.sig", "forged")
+	writeImage(t, folder, "os", "3.0", "os.bin", "image 3.0")
+	c, err := New(&cpb.ImageCatalog{Folder: folder, Url: "http://10.0.0.1"}, WithVerifier(fakeVerifier{}))
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	for _, v := range []struct {
+		version string
+		want    bool
+	}{{"1.0", true}, {"2.0", false}, {"3.0", false}} {
+		image, err := c.Resolve("os", v.version)
+		if err != nil {
+			t.Fatalf("Resolve() err = %v, want nil", err)
+		}
+		if got := c.Verified(image); got != v.want {
+			t.Errorf("Verified() of version %v = %v, want %v", v.version, got, v.want)
+		}
+	}
+	colonHash, err := imagehash.Normalize(sha256Hex("image 1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Verified(&bpb.SoftwareImage{OsImageHash: colonHash, HashAlgorithm: HashSHA256}) {
+		t.Errorf("Verified() of the colon separated hash = false, want true")
+	}
+	if c.Verified(&bpb.SoftwareImage{OsImageHash: sha256Hex("unknown")}) {
+		t.Errorf("Verified() of an image outside of the catalog = true, want false")
+	}
+
+	// Fixing the signature verifies the image on the next scan.
+	writeImage(t, folder, "os", "2.0", "os.bin.sig", "0.2 egami")
+	if err := c.Scan(); err != nil {
+		t.Fatalf("Scan() err = %v, want nil", err)
+	}
+	if i, _ := c.Lookup("os", "2.0"); !i.Verified {
+		t.Errorf("Lookup() of the image with a fixed signature = %+v, want verified", i)
+	}
+}
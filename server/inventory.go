@@ -0,0 +1,105 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	log "github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+
+	dpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// inventoryDHCPConfig returns a copy of the DHCP config with the records derived from the management addresses of the
+// control cards in the inventory, and with the bootz server URL derived from the server address if it sets none.
+// Records of the DHCP config take precedence over the derived ones.
+func inventoryDHCPConfig(base *dpb.Config, config *cpb.Config) (*dpb.Config, error) {
+	conf := proto.Clone(base).(*dpb.Config)
+	if len(conf.GetBootzUrls()) == 0 {
+		conf.BootzUrls = []string{"bootz://" + config.GetServerAddress()}
+	}
+
+	explicit := map[string]bool{}
+	for _, r := range base.GetRecords() {
+		ip, _, err := net.ParseCIDR(r.GetIp())
+		if r.GetMachine() == "" || err != nil {
+			// Invalid records are reported by the DHCP server.
+			continue
+		}
+		explicit[recordID(r.GetMachine(), ip)] = true
+	}
+
+	for _, ch := range config.GetChassis() {
+		for _, cc := range ch.GetControlCards() {
+			if cc.GetManagementIp() == "" {
+				continue
+			}
+			desc := fmt.Sprintf("chassis %q control card %q", ch.GetHostname(), cc.GetSerialNumber())
+			ip, _, err := net.ParseCIDR(cc.GetManagementIp())
+			if err != nil {
+				return nil, fmt.Errorf("%v: invalid management_ip %q, which must be in CIDR notation", desc, cc.GetManagementIp())
+			}
+			gw := cc.GetManagementGateway()
+			if ip.To4() != nil {
+				if g := net.ParseIP(gw); g == nil || g.To4() == nil {
+					return nil, fmt.Errorf("%v: invalid management_gateway %q", desc, gw)
+				}
+			} else if gw != "" {
+				return nil, fmt.Errorf("%v: management_gateway must not be set for an IPv6 management_ip", desc)
+			}
+
+			var machines []string
+			for _, m := range cc.GetManagementMacs() {
+				mac, err := net.ParseMAC(m)
+				if err != nil {
+					return nil, fmt.Errorf("%v: invalid management_macs entry %q", desc, m)
+				}
+				machines = append(machines, mac.String())
+			}
+			if len(machines) == 0 {
+				if cc.GetSerialNumber() == "" {
+					return nil, fmt.Errorf("%v: serial_number or management_macs must be set", desc)
+				}
+				machines = []string{cc.GetSerialNumber()}
+			}
+			// The management ports of the control card share one record, and its address.
+			var derived []string
+			for _, m := range machines {
+				if explicit[recordID(m, ip)] {
+					log.Warningf("DHCP record of %v for %v is overridden by the DHCP config", desc, m)
+					continue
+				}
+				derived = append(derived, m)
+			}
+			if len(derived) == 0 {
+				continue
+			}
+			conf.Records = append(conf.Records, &dpb.Record{Machine: derived[0], AlternateMachines: derived[1:], Ip: cc.GetManagementIp(), Gateway: gw})
+		}
+	}
+	return conf, nil
+}
+
+// recordID identifies the DHCP record of a machine for the family of the address.
+func recordID(machine string, ip net.IP) string {
+	if mac, err := net.ParseMAC(machine); err == nil {
+		machine = mac.String()
+	}
+	return fmt.Sprintf("%v/%v", strings.ToLower(machine), ip.To4() == nil)
+}
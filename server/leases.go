@@ -0,0 +1,98 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/openconfig/bootz/common/types"
+	"github.com/openconfig/bootz/server/service"
+
+	plslease "github.com/openconfig/bootz/dhcp/plugins/slease"
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// dhcpLeases looks up the leases of the bundled DHCP server for the service.
+type dhcpLeases struct {
+	mu sync.RWMutex
+	// macs are the management MAC addresses of the control cards, keyed by serial number.
+	macs map[string][]string
+	// leases returns the current leases. It is replaced by tests.
+	leases func() []plslease.Lease
+}
+
+func newDHCPLeases(config *cpb.Config) *dhcpLeases {
+	d := &dhcpLeases{leases: plslease.Leases}
+	d.update(config)
+	return d
+}
+
+// update replaces the management MAC addresses with the ones of the inventory of the config.
+func (d *dhcpLeases) update(config *cpb.Config) {
+	macs := map[string][]string{}
+	for _, ch := range config.GetChassis() {
+		for _, cc := range ch.GetControlCards() {
+			for _, m := range cc.GetManagementMacs() {
+				if mac, err := net.ParseMAC(m); err == nil {
+					macs[cc.GetSerialNumber()] = append(macs[cc.GetSerialNumber()], mac.String())
+				}
+			}
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.macs = macs
+}
+
+// LeaseByIP implements service.LeaseLookup.
+func (d *dhcpLeases) LeaseByIP(ip string) *types.DHCPLease {
+	for _, l := range d.leases() {
+		if service.SameIP(l.IP, ip) {
+			return &types.DHCPLease{IP: l.IP, MAC: l.MAC, ClientID: l.ClientID}
+		}
+	}
+	return nil
+}
+
+// LeasedIPs implements service.LeaseLookup. The leases of a control card are those of its serial number, sent as
+// client identifier, and those of its management MAC addresses.
+func (d *dhcpLeases) LeasedIPs(serial string) []string {
+	d.mu.RLock()
+	ids := append([]string{serial}, d.macs[serial]...)
+	d.mu.RUnlock()
+	var ips []string
+	for _, l := range d.leases() {
+		for _, id := range ids {
+			if strings.EqualFold(l.Key, id) || strings.EqualFold(l.MAC, id) || strings.EqualFold(l.ClientID, id) {
+				ips = append(ips, l.IP)
+				break
+			}
+		}
+	}
+	return ips
+}
+
+// leaseMismatchPolicy converts the lease mismatch policy of the config.
+func leaseMismatchPolicy(p cpb.LeaseMismatchPolicy) service.LeaseMismatchPolicy {
+	switch p {
+	case cpb.LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_FLAG:
+		return service.LeaseMismatchFlag
+	case cpb.LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_REJECT:
+		return service.LeaseMismatchReject
+	}
+	return service.LeaseMismatchIgnore
+}
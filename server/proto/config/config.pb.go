@@ -25,32 +25,589 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type LeaseMismatchPolicy int32
+
+const (
+	LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_IGNORE LeaseMismatchPolicy = 0
+	LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_FLAG   LeaseMismatchPolicy = 1
+	LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_REJECT LeaseMismatchPolicy = 2
+)
+
+// Enum value maps for LeaseMismatchPolicy.
+var (
+	LeaseMismatchPolicy_name = map[int32]string{
+		0: "LEASE_MISMATCH_POLICY_IGNORE",
+		1: "LEASE_MISMATCH_POLICY_FLAG",
+		2: "LEASE_MISMATCH_POLICY_REJECT",
+	}
+	LeaseMismatchPolicy_value = map[string]int32{
+		"LEASE_MISMATCH_POLICY_IGNORE": 0,
+		"LEASE_MISMATCH_POLICY_FLAG":   1,
+		"LEASE_MISMATCH_POLICY_REJECT": 2,
+	}
+)
+
+func (x LeaseMismatchPolicy) Enum() *LeaseMismatchPolicy {
+	p := new(LeaseMismatchPolicy)
+	*p = x
+	return p
+}
+
+func (x LeaseMismatchPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LeaseMismatchPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_enumTypes[0].Descriptor()
+}
+
+func (LeaseMismatchPolicy) Type() protoreflect.EnumType {
+	return &file_github_com_openconfig_bootz_server_proto_config_proto_enumTypes[0]
+}
+
+func (x LeaseMismatchPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LeaseMismatchPolicy.Descriptor instead.
+func (LeaseMismatchPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+type Revocation_Policy int32
+
+const (
+	Revocation_POLICY_UNSPECIFIED Revocation_Policy = 0
+	Revocation_POLICY_OFF         Revocation_Policy = 1
+	Revocation_POLICY_SOFT_FAIL   Revocation_Policy = 2
+	Revocation_POLICY_HARD_FAIL   Revocation_Policy = 3
+)
+
+// Enum value maps for Revocation_Policy.
+var (
+	Revocation_Policy_name = map[int32]string{
+		0: "POLICY_UNSPECIFIED",
+		1: "POLICY_OFF",
+		2: "POLICY_SOFT_FAIL",
+		3: "POLICY_HARD_FAIL",
+	}
+	Revocation_Policy_value = map[string]int32{
+		"POLICY_UNSPECIFIED": 0,
+		"POLICY_OFF":         1,
+		"POLICY_SOFT_FAIL":   2,
+		"POLICY_HARD_FAIL":   3,
+	}
+)
+
+func (x Revocation_Policy) Enum() *Revocation_Policy {
+	p := new(Revocation_Policy)
+	*p = x
+	return p
+}
+
+func (x Revocation_Policy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Revocation_Policy) Descriptor() protoreflect.EnumDescriptor {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_enumTypes[1].Descriptor()
+}
+
+func (Revocation_Policy) Type() protoreflect.EnumType {
+	return &file_github_com_openconfig_bootz_server_proto_config_proto_enumTypes[1]
+}
+
+func (x Revocation_Policy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Revocation_Policy.Descriptor instead.
+func (Revocation_Policy) EnumDescriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{9, 0}
+}
+
 type Config struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	ServerAddress    string                 `protobuf:"bytes,1,opt,name=server_address,json=serverAddress,proto3" json:"server_address,omitempty"`
-	TrustAnchor      *CertKeyPair           `protobuf:"bytes,2,opt,name=trust_anchor,json=trustAnchor,proto3" json:"trust_anchor,omitempty"`
-	OwnerCertificate *CertKeyPair           `protobuf:"bytes,3,opt,name=owner_certificate,json=ownerCertificate,proto3" json:"owner_certificate,omitempty"`
-	VendorCaCerts    []string               `protobuf:"bytes,4,rep,name=vendor_ca_certs,json=vendorCaCerts,proto3" json:"vendor_ca_certs,omitempty"`
-	Chassis          []*Chassis             `protobuf:"bytes,5,rep,name=chassis,proto3" json:"chassis,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	ServerAddress          string                 `protobuf:"bytes,1,opt,name=server_address,json=serverAddress,proto3" json:"server_address,omitempty"`
+	TrustAnchor            *CertKeyPair           `protobuf:"bytes,2,opt,name=trust_anchor,json=trustAnchor,proto3" json:"trust_anchor,omitempty"`
+	OwnerCertificate       *CertKeyPair           `protobuf:"bytes,3,opt,name=owner_certificate,json=ownerCertificate,proto3" json:"owner_certificate,omitempty"`
+	VendorCaCerts          []string               `protobuf:"bytes,4,rep,name=vendor_ca_certs,json=vendorCaCerts,proto3" json:"vendor_ca_certs,omitempty"`
+	Chassis                []*Chassis             `protobuf:"bytes,5,rep,name=chassis,proto3" json:"chassis,omitempty"`
+	VoucherService         *VoucherService        `protobuf:"bytes,6,opt,name=voucher_service,json=voucherService,proto3" json:"voucher_service,omitempty"`
+	Revocation             *Revocation            `protobuf:"bytes,7,opt,name=revocation,proto3" json:"revocation,omitempty"`
+	IdevidSerialExtractors []*SerialExtractor     `protobuf:"bytes,8,rep,name=idevid_serial_extractors,json=idevidSerialExtractors,proto3" json:"idevid_serial_extractors,omitempty"`
+	LeaseMismatchPolicy    LeaseMismatchPolicy    `protobuf:"varint,9,opt,name=lease_mismatch_policy,json=leaseMismatchPolicy,proto3,enum=config.LeaseMismatchPolicy" json:"lease_mismatch_policy,omitempty"`
+	ImageCatalog           *ImageCatalog          `protobuf:"bytes,10,opt,name=image_catalog,json=imageCatalog,proto3" json:"image_catalog,omitempty"`
+	ImageUrlSigning        *ImageURLSigning       `protobuf:"bytes,11,opt,name=image_url_signing,json=imageUrlSigning,proto3" json:"image_url_signing,omitempty"`
+	ImageTargets           []*ImageTarget         `protobuf:"bytes,12,rep,name=image_targets,json=imageTargets,proto3" json:"image_targets,omitempty"`
+	Rollouts               []*Rollout             `protobuf:"bytes,13,rep,name=rollouts,proto3" json:"rollouts,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *Config) Reset() {
+	*x = Config{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Config) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Config) ProtoMessage() {}
+
+func (x *Config) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Config.ProtoReflect.Descriptor instead.
+func (*Config) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Config) GetServerAddress() string {
+	if x != nil {
+		return x.ServerAddress
+	}
+	return ""
+}
+
+func (x *Config) GetTrustAnchor() *CertKeyPair {
+	if x != nil {
+		return x.TrustAnchor
+	}
+	return nil
+}
+
+func (x *Config) GetOwnerCertificate() *CertKeyPair {
+	if x != nil {
+		return x.OwnerCertificate
+	}
+	return nil
+}
+
+func (x *Config) GetVendorCaCerts() []string {
+	if x != nil {
+		return x.VendorCaCerts
+	}
+	return nil
+}
+
+func (x *Config) GetChassis() []*Chassis {
+	if x != nil {
+		return x.Chassis
+	}
+	return nil
+}
+
+func (x *Config) GetVoucherService() *VoucherService {
+	if x != nil {
+		return x.VoucherService
+	}
+	return nil
+}
+
+func (x *Config) GetRevocation() *Revocation {
+	if x != nil {
+		return x.Revocation
+	}
+	return nil
+}
+
+func (x *Config) GetIdevidSerialExtractors() []*SerialExtractor {
+	if x != nil {
+		return x.IdevidSerialExtractors
+	}
+	return nil
+}
+
+func (x *Config) GetLeaseMismatchPolicy() LeaseMismatchPolicy {
+	if x != nil {
+		return x.LeaseMismatchPolicy
+	}
+	return LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_IGNORE
+}
+
+func (x *Config) GetImageCatalog() *ImageCatalog {
+	if x != nil {
+		return x.ImageCatalog
+	}
+	return nil
+}
+
+func (x *Config) GetImageUrlSigning() *ImageURLSigning {
+	if x != nil {
+		return x.ImageUrlSigning
+	}
+	return nil
+}
+
+func (x *Config) GetImageTargets() []*ImageTarget {
+	if x != nil {
+		return x.ImageTargets
+	}
+	return nil
+}
+
+func (x *Config) GetRollouts() []*Rollout {
+	if x != nil {
+		return x.Rollouts
+	}
+	return nil
+}
+
+type ImageTarget struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Image         *bootz.SoftwareImage   `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageTarget) Reset() {
+	*x = ImageTarget{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageTarget) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageTarget) ProtoMessage() {}
+
+func (x *ImageTarget) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageTarget.ProtoReflect.Descriptor instead.
+func (*ImageTarget) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ImageTarget) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ImageTarget) GetImage() *bootz.SoftwareImage {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+type Rollout struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Target         string                 `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	Previous       string                 `protobuf:"bytes,3,opt,name=previous,proto3" json:"previous,omitempty"`
+	Groups         []*RolloutGroup        `protobuf:"bytes,4,rep,name=groups,proto3" json:"groups,omitempty"`
+	MaxFailureRate float64                `protobuf:"fixed64,5,opt,name=max_failure_rate,json=maxFailureRate,proto3" json:"max_failure_rate,omitempty"`
+	MinReports     uint32                 `protobuf:"varint,6,opt,name=min_reports,json=minReports,proto3" json:"min_reports,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Rollout) Reset() {
+	*x = Rollout{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rollout) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rollout) ProtoMessage() {}
+
+func (x *Rollout) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rollout.ProtoReflect.Descriptor instead.
+func (*Rollout) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Rollout) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Rollout) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Rollout) GetPrevious() string {
+	if x != nil {
+		return x.Previous
+	}
+	return ""
+}
+
+func (x *Rollout) GetGroups() []*RolloutGroup {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *Rollout) GetMaxFailureRate() float64 {
+	if x != nil {
+		return x.MaxFailureRate
+	}
+	return 0
+}
+
+func (x *Rollout) GetMinReports() uint32 {
+	if x != nil {
+		return x.MinReports
+	}
+	return 0
+}
+
+type RolloutGroup struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	PartNumbers     []string               `protobuf:"bytes,2,rep,name=part_numbers,json=partNumbers,proto3" json:"part_numbers,omitempty"`
+	HostnamePattern string                 `protobuf:"bytes,3,opt,name=hostname_pattern,json=hostnamePattern,proto3" json:"hostname_pattern,omitempty"`
+	Chassis         []string               `protobuf:"bytes,4,rep,name=chassis,proto3" json:"chassis,omitempty"`
+	Percentage      uint32                 `protobuf:"varint,5,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RolloutGroup) Reset() {
+	*x = RolloutGroup{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RolloutGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RolloutGroup) ProtoMessage() {}
+
+func (x *RolloutGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RolloutGroup.ProtoReflect.Descriptor instead.
+func (*RolloutGroup) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RolloutGroup) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RolloutGroup) GetPartNumbers() []string {
+	if x != nil {
+		return x.PartNumbers
+	}
+	return nil
+}
+
+func (x *RolloutGroup) GetHostnamePattern() string {
+	if x != nil {
+		return x.HostnamePattern
+	}
+	return ""
+}
+
+func (x *RolloutGroup) GetChassis() []string {
+	if x != nil {
+		return x.Chassis
+	}
+	return nil
+}
+
+func (x *RolloutGroup) GetPercentage() uint32 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+type ImageURLSigning struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Keys            []*URLSigningKey       `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	LifetimeSeconds uint32                 `protobuf:"varint,2,opt,name=lifetime_seconds,json=lifetimeSeconds,proto3" json:"lifetime_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ImageURLSigning) Reset() {
+	*x = ImageURLSigning{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageURLSigning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageURLSigning) ProtoMessage() {}
+
+func (x *ImageURLSigning) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageURLSigning.ProtoReflect.Descriptor instead.
+func (*ImageURLSigning) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ImageURLSigning) GetKeys() []*URLSigningKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *ImageURLSigning) GetLifetimeSeconds() uint32 {
+	if x != nil {
+		return x.LifetimeSeconds
+	}
+	return 0
+}
+
+type URLSigningKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Secret        string                 `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLSigningKey) Reset() {
+	*x = URLSigningKey{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLSigningKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLSigningKey) ProtoMessage() {}
+
+func (x *URLSigningKey) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (x *Config) Reset() {
-	*x = Config{}
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[0]
+// Deprecated: Use URLSigningKey.ProtoReflect.Descriptor instead.
+func (*URLSigningKey) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *URLSigningKey) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *URLSigningKey) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+type ImageCatalog struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Folder              string                 `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
+	Url                 string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	HashAlgorithm       string                 `protobuf:"bytes,3,opt,name=hash_algorithm,json=hashAlgorithm,proto3" json:"hash_algorithm,omitempty"`
+	ScanIntervalSeconds uint32                 `protobuf:"varint,4,opt,name=scan_interval_seconds,json=scanIntervalSeconds,proto3" json:"scan_interval_seconds,omitempty"`
+	Verification        *ImageVerification     `protobuf:"bytes,5,opt,name=verification,proto3" json:"verification,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *ImageCatalog) Reset() {
+	*x = ImageCatalog{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Config) String() string {
+func (x *ImageCatalog) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Config) ProtoMessage() {}
+func (*ImageCatalog) ProtoMessage() {}
 
-func (x *Config) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[0]
+func (x *ImageCatalog) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -61,46 +618,302 @@ func (x *Config) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Config.ProtoReflect.Descriptor instead.
-func (*Config) Descriptor() ([]byte, []int) {
-	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use ImageCatalog.ProtoReflect.Descriptor instead.
+func (*ImageCatalog) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *Config) GetServerAddress() string {
+func (x *ImageCatalog) GetFolder() string {
 	if x != nil {
-		return x.ServerAddress
+		return x.Folder
 	}
 	return ""
 }
 
-func (x *Config) GetTrustAnchor() *CertKeyPair {
+func (x *ImageCatalog) GetUrl() string {
 	if x != nil {
-		return x.TrustAnchor
+		return x.Url
+	}
+	return ""
+}
+
+func (x *ImageCatalog) GetHashAlgorithm() string {
+	if x != nil {
+		return x.HashAlgorithm
+	}
+	return ""
+}
+
+func (x *ImageCatalog) GetScanIntervalSeconds() uint32 {
+	if x != nil {
+		return x.ScanIntervalSeconds
+	}
+	return 0
+}
+
+func (x *ImageCatalog) GetVerification() *ImageVerification {
+	if x != nil {
+		return x.Verification
 	}
 	return nil
 }
 
-func (x *Config) GetOwnerCertificate() *CertKeyPair {
+type ImageVerification struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VendorCerts   []string               `protobuf:"bytes,1,rep,name=vendor_certs,json=vendorCerts,proto3" json:"vendor_certs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageVerification) Reset() {
+	*x = ImageVerification{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageVerification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageVerification) ProtoMessage() {}
+
+func (x *ImageVerification) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[7]
 	if x != nil {
-		return x.OwnerCertificate
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageVerification.ProtoReflect.Descriptor instead.
+func (*ImageVerification) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ImageVerification) GetVendorCerts() []string {
+	if x != nil {
+		return x.VendorCerts
 	}
 	return nil
 }
 
-func (x *Config) GetVendorCaCerts() []string {
+type SerialExtractor struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manufacturer  string                 `protobuf:"bytes,1,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	Extractors    []string               `protobuf:"bytes,2,rep,name=extractors,proto3" json:"extractors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SerialExtractor) Reset() {
+	*x = SerialExtractor{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SerialExtractor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SerialExtractor) ProtoMessage() {}
+
+func (x *SerialExtractor) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[8]
 	if x != nil {
-		return x.VendorCaCerts
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SerialExtractor.ProtoReflect.Descriptor instead.
+func (*SerialExtractor) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SerialExtractor) GetManufacturer() string {
+	if x != nil {
+		return x.Manufacturer
+	}
+	return ""
+}
+
+func (x *SerialExtractor) GetExtractors() []string {
+	if x != nil {
+		return x.Extractors
 	}
 	return nil
 }
 
-func (x *Config) GetChassis() []*Chassis {
+type Revocation struct {
+	state                   protoimpl.MessageState       `protogen:"open.v1"`
+	DefaultPolicy           Revocation_Policy            `protobuf:"varint,1,opt,name=default_policy,json=defaultPolicy,proto3,enum=config.Revocation_Policy" json:"default_policy,omitempty"`
+	ManufacturerPolicies    map[string]Revocation_Policy `protobuf:"bytes,2,rep,name=manufacturer_policies,json=manufacturerPolicies,proto3" json:"manufacturer_policies,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value,enum=config.Revocation_Policy"`
+	CrlFiles                []string                     `protobuf:"bytes,3,rep,name=crl_files,json=crlFiles,proto3" json:"crl_files,omitempty"`
+	FetchDistributionPoints bool                         `protobuf:"varint,4,opt,name=fetch_distribution_points,json=fetchDistributionPoints,proto3" json:"fetch_distribution_points,omitempty"`
+	FetchTimeoutSeconds     uint32                       `protobuf:"varint,5,opt,name=fetch_timeout_seconds,json=fetchTimeoutSeconds,proto3" json:"fetch_timeout_seconds,omitempty"`
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *Revocation) Reset() {
+	*x = Revocation{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Revocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Revocation) ProtoMessage() {}
+
+func (x *Revocation) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[9]
 	if x != nil {
-		return x.Chassis
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Revocation.ProtoReflect.Descriptor instead.
+func (*Revocation) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Revocation) GetDefaultPolicy() Revocation_Policy {
+	if x != nil {
+		return x.DefaultPolicy
+	}
+	return Revocation_POLICY_UNSPECIFIED
+}
+
+func (x *Revocation) GetManufacturerPolicies() map[string]Revocation_Policy {
+	if x != nil {
+		return x.ManufacturerPolicies
+	}
+	return nil
+}
+
+func (x *Revocation) GetCrlFiles() []string {
+	if x != nil {
+		return x.CrlFiles
+	}
+	return nil
+}
+
+func (x *Revocation) GetFetchDistributionPoints() bool {
+	if x != nil {
+		return x.FetchDistributionPoints
+	}
+	return false
+}
+
+func (x *Revocation) GetFetchTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.FetchTimeoutSeconds
+	}
+	return 0
+}
+
+type VoucherService struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Url                    string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	CacheDir               string                 `protobuf:"bytes,2,opt,name=cache_dir,json=cacheDir,proto3" json:"cache_dir,omitempty"`
+	CaCerts                []string               `protobuf:"bytes,3,rep,name=ca_certs,json=caCerts,proto3" json:"ca_certs,omitempty"`
+	RefreshBeforeSeconds   uint32                 `protobuf:"varint,4,opt,name=refresh_before_seconds,json=refreshBeforeSeconds,proto3" json:"refresh_before_seconds,omitempty"`
+	RefreshIntervalSeconds uint32                 `protobuf:"varint,5,opt,name=refresh_interval_seconds,json=refreshIntervalSeconds,proto3" json:"refresh_interval_seconds,omitempty"`
+	TimeoutSeconds         uint32                 `protobuf:"varint,6,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *VoucherService) Reset() {
+	*x = VoucherService{}
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoucherService) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoucherService) ProtoMessage() {}
+
+func (x *VoucherService) ProtoReflect() protoreflect.Message {
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoucherService.ProtoReflect.Descriptor instead.
+func (*VoucherService) Descriptor() ([]byte, []int) {
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *VoucherService) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *VoucherService) GetCacheDir() string {
+	if x != nil {
+		return x.CacheDir
+	}
+	return ""
+}
+
+func (x *VoucherService) GetCaCerts() []string {
+	if x != nil {
+		return x.CaCerts
 	}
 	return nil
 }
 
+func (x *VoucherService) GetRefreshBeforeSeconds() uint32 {
+	if x != nil {
+		return x.RefreshBeforeSeconds
+	}
+	return 0
+}
+
+func (x *VoucherService) GetRefreshIntervalSeconds() uint32 {
+	if x != nil {
+		return x.RefreshIntervalSeconds
+	}
+	return 0
+}
+
+func (x *VoucherService) GetTimeoutSeconds() uint32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
 type CertKeyPair struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Cert          string                 `protobuf:"bytes,1,opt,name=cert,proto3" json:"cert,omitempty"`
@@ -111,7 +924,7 @@ type CertKeyPair struct {
 
 func (x *CertKeyPair) Reset() {
 	*x = CertKeyPair{}
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[1]
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -123,7 +936,7 @@ func (x *CertKeyPair) String() string {
 func (*CertKeyPair) ProtoMessage() {}
 
 func (x *CertKeyPair) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[1]
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -136,7 +949,7 @@ func (x *CertKeyPair) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CertKeyPair.ProtoReflect.Descriptor instead.
 func (*CertKeyPair) Descriptor() ([]byte, []int) {
-	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{1}
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *CertKeyPair) GetCert() string {
@@ -167,13 +980,14 @@ type Chassis struct {
 	Pathz              *pathz.UploadRequest   `protobuf:"bytes,10,opt,name=pathz,proto3" json:"pathz,omitempty"`
 	Authz              *authz.UploadRequest   `protobuf:"bytes,11,opt,name=authz,proto3" json:"authz,omitempty"`
 	CertzProfiles      *bootz.CertzProfiles   `protobuf:"bytes,12,opt,name=certz_profiles,json=certzProfiles,proto3" json:"certz_profiles,omitempty"`
+	Lab                bool                   `protobuf:"varint,13,opt,name=lab,proto3" json:"lab,omitempty"`
 	unknownFields      protoimpl.UnknownFields
 	sizeCache          protoimpl.SizeCache
 }
 
 func (x *Chassis) Reset() {
 	*x = Chassis{}
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[2]
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -185,7 +999,7 @@ func (x *Chassis) String() string {
 func (*Chassis) ProtoMessage() {}
 
 func (x *Chassis) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[2]
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -198,7 +1012,7 @@ func (x *Chassis) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Chassis.ProtoReflect.Descriptor instead.
 func (*Chassis) Descriptor() ([]byte, []int) {
-	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{2}
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *Chassis) GetManufacturer() string {
@@ -285,20 +1099,34 @@ func (x *Chassis) GetCertzProfiles() *bootz.CertzProfiles {
 	return nil
 }
 
+func (x *Chassis) GetLab() bool {
+	if x != nil {
+		return x.Lab
+	}
+	return false
+}
+
 type ControlCard struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	SerialNumber     string                 `protobuf:"bytes,1,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
-	OwnershipVoucher string                 `protobuf:"bytes,2,opt,name=ownership_voucher,json=ownershipVoucher,proto3" json:"ownership_voucher,omitempty"`
-	PublicKey        string                 `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
-	PublicKeyType    tpm_enrollz.Key        `protobuf:"varint,4,opt,name=public_key_type,json=publicKeyType,proto3,enum=openconfig.attestz.Key" json:"public_key_type,omitempty"`
-	Idevid           *CertKeyPair           `protobuf:"bytes,5,opt,name=idevid,proto3" json:"idevid,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	SerialNumber      string                 `protobuf:"bytes,1,opt,name=serial_number,json=serialNumber,proto3" json:"serial_number,omitempty"`
+	OwnershipVoucher  string                 `protobuf:"bytes,2,opt,name=ownership_voucher,json=ownershipVoucher,proto3" json:"ownership_voucher,omitempty"`
+	PublicKey         string                 `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	PublicKeyType     tpm_enrollz.Key        `protobuf:"varint,4,opt,name=public_key_type,json=publicKeyType,proto3,enum=openconfig.attestz.Key" json:"public_key_type,omitempty"`
+	ManagementMacs    []string               `protobuf:"bytes,6,rep,name=management_macs,json=managementMacs,proto3" json:"management_macs,omitempty"`
+	ManagementIp      string                 `protobuf:"bytes,7,opt,name=management_ip,json=managementIp,proto3" json:"management_ip,omitempty"`
+	ManagementGateway string                 `protobuf:"bytes,8,opt,name=management_gateway,json=managementGateway,proto3" json:"management_gateway,omitempty"`
+	PartNumber        string                 `protobuf:"bytes,9,opt,name=part_number,json=partNumber,proto3" json:"part_number,omitempty"`
+	IntendedImage     *bootz.SoftwareImage   `protobuf:"bytes,10,opt,name=intended_image,json=intendedImage,proto3" json:"intended_image,omitempty"`
+	BootConfig        *bootz.BootConfig      `protobuf:"bytes,11,opt,name=boot_config,json=bootConfig,proto3" json:"boot_config,omitempty"`
+	BootPasswordHash  string                 `protobuf:"bytes,12,opt,name=boot_password_hash,json=bootPasswordHash,proto3" json:"boot_password_hash,omitempty"`
+	Idevid            *CertKeyPair           `protobuf:"bytes,5,opt,name=idevid,proto3" json:"idevid,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *ControlCard) Reset() {
 	*x = ControlCard{}
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[3]
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -310,7 +1138,7 @@ func (x *ControlCard) String() string {
 func (*ControlCard) ProtoMessage() {}
 
 func (x *ControlCard) ProtoReflect() protoreflect.Message {
-	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[3]
+	mi := &file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -323,7 +1151,7 @@ func (x *ControlCard) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ControlCard.ProtoReflect.Descriptor instead.
 func (*ControlCard) Descriptor() ([]byte, []int) {
-	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{3}
+	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ControlCard) GetSerialNumber() string {
@@ -354,6 +1182,55 @@ func (x *ControlCard) GetPublicKeyType() tpm_enrollz.Key {
 	return tpm_enrollz.Key(0)
 }
 
+func (x *ControlCard) GetManagementMacs() []string {
+	if x != nil {
+		return x.ManagementMacs
+	}
+	return nil
+}
+
+func (x *ControlCard) GetManagementIp() string {
+	if x != nil {
+		return x.ManagementIp
+	}
+	return ""
+}
+
+func (x *ControlCard) GetManagementGateway() string {
+	if x != nil {
+		return x.ManagementGateway
+	}
+	return ""
+}
+
+func (x *ControlCard) GetPartNumber() string {
+	if x != nil {
+		return x.PartNumber
+	}
+	return ""
+}
+
+func (x *ControlCard) GetIntendedImage() *bootz.SoftwareImage {
+	if x != nil {
+		return x.IntendedImage
+	}
+	return nil
+}
+
+func (x *ControlCard) GetBootConfig() *bootz.BootConfig {
+	if x != nil {
+		return x.BootConfig
+	}
+	return nil
+}
+
+func (x *ControlCard) GetBootPasswordHash() string {
+	if x != nil {
+		return x.BootPasswordHash
+	}
+	return ""
+}
+
 func (x *ControlCard) GetIdevid() *CertKeyPair {
 	if x != nil {
 		return x.Idevid
@@ -365,16 +1242,88 @@ var File_github_com_openconfig_bootz_server_proto_config_proto protoreflect.File
 
 const file_github_com_openconfig_bootz_server_proto_config_proto_rawDesc = "" +
 	"\n" +
-	"5github.com/openconfig/bootz/server/proto/config.proto\x12\x06config\x1a5github.com/openconfig/attestz/proto/tpm_enrollz.proto\x1a-github.com/openconfig/bootz/proto/bootz.proto\x1a,github.com/openconfig/gnsi/authz/authz.proto\x1a,github.com/openconfig/gnsi/pathz/pathz.proto\"\xfc\x01\n" +
+	"5github.com/openconfig/bootz/server/proto/config.proto\x12\x06config\x1a5github.com/openconfig/attestz/proto/tpm_enrollz.proto\x1a-github.com/openconfig/bootz/proto/bootz.proto\x1a,github.com/openconfig/gnsi/authz/authz.proto\x1a,github.com/openconfig/gnsi/pathz/pathz.proto\"\xfc\x05\n" +
 	"\x06Config\x12%\n" +
 	"\x0eserver_address\x18\x01 \x01(\tR\rserverAddress\x126\n" +
 	"\ftrust_anchor\x18\x02 \x01(\v2\x13.config.CertKeyPairR\vtrustAnchor\x12@\n" +
 	"\x11owner_certificate\x18\x03 \x01(\v2\x13.config.CertKeyPairR\x10ownerCertificate\x12&\n" +
 	"\x0fvendor_ca_certs\x18\x04 \x03(\tR\rvendorCaCerts\x12)\n" +
-	"\achassis\x18\x05 \x03(\v2\x0f.config.ChassisR\achassis\"3\n" +
+	"\achassis\x18\x05 \x03(\v2\x0f.config.ChassisR\achassis\x12?\n" +
+	"\x0fvoucher_service\x18\x06 \x01(\v2\x16.config.VoucherServiceR\x0evoucherService\x122\n" +
+	"\n" +
+	"revocation\x18\a \x01(\v2\x12.config.RevocationR\n" +
+	"revocation\x12Q\n" +
+	"\x18idevid_serial_extractors\x18\b \x03(\v2\x17.config.SerialExtractorR\x16idevidSerialExtractors\x12O\n" +
+	"\x15lease_mismatch_policy\x18\t \x01(\x0e2\x1b.config.LeaseMismatchPolicyR\x13leaseMismatchPolicy\x129\n" +
+	"\rimage_catalog\x18\n" +
+	" \x01(\v2\x14.config.ImageCatalogR\fimageCatalog\x12C\n" +
+	"\x11image_url_signing\x18\v \x01(\v2\x17.config.ImageURLSigningR\x0fimageUrlSigning\x128\n" +
+	"\rimage_targets\x18\f \x03(\v2\x13.config.ImageTargetR\fimageTargets\x12+\n" +
+	"\brollouts\x18\r \x03(\v2\x0f.config.RolloutR\brollouts\"M\n" +
+	"\vImageTarget\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12*\n" +
+	"\x05image\x18\x02 \x01(\v2\x14.bootz.SoftwareImageR\x05image\"\xca\x01\n" +
+	"\aRollout\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\tR\x06target\x12\x1a\n" +
+	"\bprevious\x18\x03 \x01(\tR\bprevious\x12,\n" +
+	"\x06groups\x18\x04 \x03(\v2\x14.config.RolloutGroupR\x06groups\x12(\n" +
+	"\x10max_failure_rate\x18\x05 \x01(\x01R\x0emaxFailureRate\x12\x1f\n" +
+	"\vmin_reports\x18\x06 \x01(\rR\n" +
+	"minReports\"\xaa\x01\n" +
+	"\fRolloutGroup\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12!\n" +
+	"\fpart_numbers\x18\x02 \x03(\tR\vpartNumbers\x12)\n" +
+	"\x10hostname_pattern\x18\x03 \x01(\tR\x0fhostnamePattern\x12\x18\n" +
+	"\achassis\x18\x04 \x03(\tR\achassis\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x05 \x01(\rR\n" +
+	"percentage\"g\n" +
+	"\x0fImageURLSigning\x12)\n" +
+	"\x04keys\x18\x01 \x03(\v2\x15.config.URLSigningKeyR\x04keys\x12)\n" +
+	"\x10lifetime_seconds\x18\x02 \x01(\rR\x0flifetimeSeconds\"7\n" +
+	"\rURLSigningKey\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06secret\x18\x02 \x01(\tR\x06secret\"\xd2\x01\n" +
+	"\fImageCatalog\x12\x16\n" +
+	"\x06folder\x18\x01 \x01(\tR\x06folder\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12%\n" +
+	"\x0ehash_algorithm\x18\x03 \x01(\tR\rhashAlgorithm\x122\n" +
+	"\x15scan_interval_seconds\x18\x04 \x01(\rR\x13scanIntervalSeconds\x12=\n" +
+	"\fverification\x18\x05 \x01(\v2\x19.config.ImageVerificationR\fverification\"6\n" +
+	"\x11ImageVerification\x12!\n" +
+	"\fvendor_certs\x18\x01 \x03(\tR\vvendorCerts\"U\n" +
+	"\x0fSerialExtractor\x12\"\n" +
+	"\fmanufacturer\x18\x01 \x01(\tR\fmanufacturer\x12\x1e\n" +
+	"\n" +
+	"extractors\x18\x02 \x03(\tR\n" +
+	"extractors\"\x80\x04\n" +
+	"\n" +
+	"Revocation\x12@\n" +
+	"\x0edefault_policy\x18\x01 \x01(\x0e2\x19.config.Revocation.PolicyR\rdefaultPolicy\x12a\n" +
+	"\x15manufacturer_policies\x18\x02 \x03(\v2,.config.Revocation.ManufacturerPoliciesEntryR\x14manufacturerPolicies\x12\x1b\n" +
+	"\tcrl_files\x18\x03 \x03(\tR\bcrlFiles\x12:\n" +
+	"\x19fetch_distribution_points\x18\x04 \x01(\bR\x17fetchDistributionPoints\x122\n" +
+	"\x15fetch_timeout_seconds\x18\x05 \x01(\rR\x13fetchTimeoutSeconds\x1ab\n" +
+	"\x19ManufacturerPoliciesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\x05value\x18\x02 \x01(\x0e2\x19.config.Revocation.PolicyR\x05value:\x028\x01\"\\\n" +
+	"\x06Policy\x12\x16\n" +
+	"\x12POLICY_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"POLICY_OFF\x10\x01\x12\x14\n" +
+	"\x10POLICY_SOFT_FAIL\x10\x02\x12\x14\n" +
+	"\x10POLICY_HARD_FAIL\x10\x03\"\xf3\x01\n" +
+	"\x0eVoucherService\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x1b\n" +
+	"\tcache_dir\x18\x02 \x01(\tR\bcacheDir\x12\x19\n" +
+	"\bca_certs\x18\x03 \x03(\tR\acaCerts\x124\n" +
+	"\x16refresh_before_seconds\x18\x04 \x01(\rR\x14refreshBeforeSeconds\x128\n" +
+	"\x18refresh_interval_seconds\x18\x05 \x01(\rR\x16refreshIntervalSeconds\x12'\n" +
+	"\x0ftimeout_seconds\x18\x06 \x01(\rR\x0etimeoutSeconds\"3\n" +
 	"\vCertKeyPair\x12\x12\n" +
 	"\x04cert\x18\x01 \x01(\tR\x04cert\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\"\xdc\x04\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"\xee\x04\n" +
 	"\aChassis\x12\"\n" +
 	"\fmanufacturer\x18\x01 \x01(\tR\fmanufacturer\x128\n" +
 	"\rcontrol_cards\x18\x02 \x03(\v2\x13.config.ControlCardR\fcontrolCards\x12\x1a\n" +
@@ -389,14 +1338,29 @@ const file_github_com_openconfig_bootz_server_proto_config_proto_rawDesc = "" +
 	"\x05pathz\x18\n" +
 	" \x01(\v2\x1c.gnsi.pathz.v1.UploadRequestR\x05pathz\x122\n" +
 	"\x05authz\x18\v \x01(\v2\x1c.gnsi.authz.v1.UploadRequestR\x05authz\x12;\n" +
-	"\x0ecertz_profiles\x18\f \x01(\v2\x14.bootz.CertzProfilesR\rcertzProfiles\"\xec\x01\n" +
+	"\x0ecertz_profiles\x18\f \x01(\v2\x14.bootz.CertzProfilesR\rcertzProfiles\x12\x10\n" +
+	"\x03lab\x18\r \x01(\bR\x03lab\"\xa9\x04\n" +
 	"\vControlCard\x12#\n" +
 	"\rserial_number\x18\x01 \x01(\tR\fserialNumber\x12+\n" +
 	"\x11ownership_voucher\x18\x02 \x01(\tR\x10ownershipVoucher\x12\x1d\n" +
 	"\n" +
 	"public_key\x18\x03 \x01(\tR\tpublicKey\x12?\n" +
-	"\x0fpublic_key_type\x18\x04 \x01(\x0e2\x17.openconfig.attestz.KeyR\rpublicKeyType\x12+\n" +
-	"\x06idevid\x18\x05 \x01(\v2\x13.config.CertKeyPairR\x06idevidB1Z/github.com/openconfig/bootz/server/proto/configb\x06proto3"
+	"\x0fpublic_key_type\x18\x04 \x01(\x0e2\x17.openconfig.attestz.KeyR\rpublicKeyType\x12'\n" +
+	"\x0fmanagement_macs\x18\x06 \x03(\tR\x0emanagementMacs\x12#\n" +
+	"\rmanagement_ip\x18\a \x01(\tR\fmanagementIp\x12-\n" +
+	"\x12management_gateway\x18\b \x01(\tR\x11managementGateway\x12\x1f\n" +
+	"\vpart_number\x18\t \x01(\tR\n" +
+	"partNumber\x12;\n" +
+	"\x0eintended_image\x18\n" +
+	" \x01(\v2\x14.bootz.SoftwareImageR\rintendedImage\x122\n" +
+	"\vboot_config\x18\v \x01(\v2\x11.bootz.BootConfigR\n" +
+	"bootConfig\x12,\n" +
+	"\x12boot_password_hash\x18\f \x01(\tR\x10bootPasswordHash\x12+\n" +
+	"\x06idevid\x18\x05 \x01(\v2\x13.config.CertKeyPairR\x06idevid*y\n" +
+	"\x13LeaseMismatchPolicy\x12 \n" +
+	"\x1cLEASE_MISMATCH_POLICY_IGNORE\x10\x00\x12\x1e\n" +
+	"\x1aLEASE_MISMATCH_POLICY_FLAG\x10\x01\x12 \n" +
+	"\x1cLEASE_MISMATCH_POLICY_REJECT\x10\x02B1Z/github.com/openconfig/bootz/server/proto/configb\x06proto3"
 
 var (
 	file_github_com_openconfig_bootz_server_proto_config_proto_rawDescOnce sync.Once
@@ -410,40 +1374,71 @@ func file_github_com_openconfig_bootz_server_proto_config_proto_rawDescGZIP() []
 	return file_github_com_openconfig_bootz_server_proto_config_proto_rawDescData
 }
 
-var file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_github_com_openconfig_bootz_server_proto_config_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_github_com_openconfig_bootz_server_proto_config_proto_goTypes = []any{
-	(*Config)(nil),              // 0: config.Config
-	(*CertKeyPair)(nil),         // 1: config.CertKeyPair
-	(*Chassis)(nil),             // 2: config.Chassis
-	(*ControlCard)(nil),         // 3: config.ControlCard
-	(bootz.BootMode)(0),         // 4: bootz.BootMode
-	(*bootz.SoftwareImage)(nil), // 5: bootz.SoftwareImage
-	(*bootz.BootConfig)(nil),    // 6: bootz.BootConfig
-	(*bootz.Credentials)(nil),   // 7: bootz.Credentials
-	(*pathz.UploadRequest)(nil), // 8: gnsi.pathz.v1.UploadRequest
-	(*authz.UploadRequest)(nil), // 9: gnsi.authz.v1.UploadRequest
-	(*bootz.CertzProfiles)(nil), // 10: bootz.CertzProfiles
-	(tpm_enrollz.Key)(0),        // 11: openconfig.attestz.Key
+	(LeaseMismatchPolicy)(0),    // 0: config.LeaseMismatchPolicy
+	(Revocation_Policy)(0),      // 1: config.Revocation.Policy
+	(*Config)(nil),              // 2: config.Config
+	(*ImageTarget)(nil),         // 3: config.ImageTarget
+	(*Rollout)(nil),             // 4: config.Rollout
+	(*RolloutGroup)(nil),        // 5: config.RolloutGroup
+	(*ImageURLSigning)(nil),     // 6: config.ImageURLSigning
+	(*URLSigningKey)(nil),       // 7: config.URLSigningKey
+	(*ImageCatalog)(nil),        // 8: config.ImageCatalog
+	(*ImageVerification)(nil),   // 9: config.ImageVerification
+	(*SerialExtractor)(nil),     // 10: config.SerialExtractor
+	(*Revocation)(nil),          // 11: config.Revocation
+	(*VoucherService)(nil),      // 12: config.VoucherService
+	(*CertKeyPair)(nil),         // 13: config.CertKeyPair
+	(*Chassis)(nil),             // 14: config.Chassis
+	(*ControlCard)(nil),         // 15: config.ControlCard
+	nil,                         // 16: config.Revocation.ManufacturerPoliciesEntry
+	(*bootz.SoftwareImage)(nil), // 17: bootz.SoftwareImage
+	(bootz.BootMode)(0),         // 18: bootz.BootMode
+	(*bootz.BootConfig)(nil),    // 19: bootz.BootConfig
+	(*bootz.Credentials)(nil),   // 20: bootz.Credentials
+	(*pathz.UploadRequest)(nil), // 21: gnsi.pathz.v1.UploadRequest
+	(*authz.UploadRequest)(nil), // 22: gnsi.authz.v1.UploadRequest
+	(*bootz.CertzProfiles)(nil), // 23: bootz.CertzProfiles
+	(tpm_enrollz.Key)(0),        // 24: openconfig.attestz.Key
 }
 var file_github_com_openconfig_bootz_server_proto_config_proto_depIdxs = []int32{
-	1,  // 0: config.Config.trust_anchor:type_name -> config.CertKeyPair
-	1,  // 1: config.Config.owner_certificate:type_name -> config.CertKeyPair
-	2,  // 2: config.Config.chassis:type_name -> config.Chassis
-	3,  // 3: config.Chassis.control_cards:type_name -> config.ControlCard
-	4,  // 4: config.Chassis.boot_mode:type_name -> bootz.BootMode
-	5,  // 5: config.Chassis.intended_image:type_name -> bootz.SoftwareImage
-	6,  // 6: config.Chassis.boot_config:type_name -> bootz.BootConfig
-	7,  // 7: config.Chassis.credentials:type_name -> bootz.Credentials
-	8,  // 8: config.Chassis.pathz:type_name -> gnsi.pathz.v1.UploadRequest
-	9,  // 9: config.Chassis.authz:type_name -> gnsi.authz.v1.UploadRequest
-	10, // 10: config.Chassis.certz_profiles:type_name -> bootz.CertzProfiles
-	11, // 11: config.ControlCard.public_key_type:type_name -> openconfig.attestz.Key
-	1,  // 12: config.ControlCard.idevid:type_name -> config.CertKeyPair
-	13, // [13:13] is the sub-list for method output_type
-	13, // [13:13] is the sub-list for method input_type
-	13, // [13:13] is the sub-list for extension type_name
-	13, // [13:13] is the sub-list for extension extendee
-	0,  // [0:13] is the sub-list for field type_name
+	13, // 0: config.Config.trust_anchor:type_name -> config.CertKeyPair
+	13, // 1: config.Config.owner_certificate:type_name -> config.CertKeyPair
+	14, // 2: config.Config.chassis:type_name -> config.Chassis
+	12, // 3: config.Config.voucher_service:type_name -> config.VoucherService
+	11, // 4: config.Config.revocation:type_name -> config.Revocation
+	10, // 5: config.Config.idevid_serial_extractors:type_name -> config.SerialExtractor
+	0,  // 6: config.Config.lease_mismatch_policy:type_name -> config.LeaseMismatchPolicy
+	8,  // 7: config.Config.image_catalog:type_name -> config.ImageCatalog
+	6,  // 8: config.Config.image_url_signing:type_name -> config.ImageURLSigning
+	3,  // 9: config.Config.image_targets:type_name -> config.ImageTarget
+	4,  // 10: config.Config.rollouts:type_name -> config.Rollout
+	17, // 11: config.ImageTarget.image:type_name -> bootz.SoftwareImage
+	5,  // 12: config.Rollout.groups:type_name -> config.RolloutGroup
+	7,  // 13: config.ImageURLSigning.keys:type_name -> config.URLSigningKey
+	9,  // 14: config.ImageCatalog.verification:type_name -> config.ImageVerification
+	1,  // 15: config.Revocation.default_policy:type_name -> config.Revocation.Policy
+	16, // 16: config.Revocation.manufacturer_policies:type_name -> config.Revocation.ManufacturerPoliciesEntry
+	15, // 17: config.Chassis.control_cards:type_name -> config.ControlCard
+	18, // 18: config.Chassis.boot_mode:type_name -> bootz.BootMode
+	17, // 19: config.Chassis.intended_image:type_name -> bootz.SoftwareImage
+	19, // 20: config.Chassis.boot_config:type_name -> bootz.BootConfig
+	20, // 21: config.Chassis.credentials:type_name -> bootz.Credentials
+	21, // 22: config.Chassis.pathz:type_name -> gnsi.pathz.v1.UploadRequest
+	22, // 23: config.Chassis.authz:type_name -> gnsi.authz.v1.UploadRequest
+	23, // 24: config.Chassis.certz_profiles:type_name -> bootz.CertzProfiles
+	24, // 25: config.ControlCard.public_key_type:type_name -> openconfig.attestz.Key
+	17, // 26: config.ControlCard.intended_image:type_name -> bootz.SoftwareImage
+	19, // 27: config.ControlCard.boot_config:type_name -> bootz.BootConfig
+	13, // 28: config.ControlCard.idevid:type_name -> config.CertKeyPair
+	1,  // 29: config.Revocation.ManufacturerPoliciesEntry.value:type_name -> config.Revocation.Policy
+	30, // [30:30] is the sub-list for method output_type
+	30, // [30:30] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
 }
 
 func init() { file_github_com_openconfig_bootz_server_proto_config_proto_init() }
@@ -456,13 +1451,14 @@ func file_github_com_openconfig_bootz_server_proto_config_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_github_com_openconfig_bootz_server_proto_config_proto_rawDesc), len(file_github_com_openconfig_bootz_server_proto_config_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_github_com_openconfig_bootz_server_proto_config_proto_goTypes,
 		DependencyIndexes: file_github_com_openconfig_bootz_server_proto_config_proto_depIdxs,
+		EnumInfos:         file_github_com_openconfig_bootz_server_proto_config_proto_enumTypes,
 		MessageInfos:      file_github_com_openconfig_bootz_server_proto_config_proto_msgTypes,
 	}.Build()
 	File_github_com_openconfig_bootz_server_proto_config_proto = out.File
@@ -0,0 +1,258 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revocation checks the revocation status of IDevID certificate chains against CRLs.
+// CRLs are loaded from files, or fetched from the CRL distribution points of the certificates and cached until their next update.
+package revocation
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+const (
+	defaultFetchTimeout = 10 * time.Second
+	// defaultCacheDuration is how long fetched CRLs without a next update are cached for.
+	defaultCacheDuration = time.Hour
+	// maxCRLSize bounds the size of a fetched CRL.
+	maxCRLSize = 32 << 20
+)
+
+var (
+	// ErrRevoked is returned when a certificate of the chain has been revoked.
+	ErrRevoked = errors.New("certificate revoked")
+	// ErrStatusUnknown is returned when the revocation status of a certificate of the chain cannot be determined.
+	ErrStatusUnknown = errors.New("revocation status unknown")
+)
+
+// Metrics, exported with expvar and keyed by manufacturer.
+var (
+	// RevokedIdentities counts the revoked IDevID certificate chains presented to the server.
+	RevokedIdentities = expvar.NewMap("bootz_revoked_idevids")
+	// UnknownStatusIdentities counts the IDevID certificate chains whose revocation status could not be determined.
+	UnknownStatusIdentities = expvar.NewMap("bootz_idevid_revocation_status_unknown")
+)
+
+// cachedCRL is a CRL fetched from a distribution point.
+type cachedCRL struct {
+	crl *x509.RevocationList
+	// expiry is when the CRL has to be fetched again.
+	expiry time.Time
+}
+
+// Checker checks the revocation status of certificate chains.
+type Checker struct {
+	defaultPolicy cpb.Revocation_Policy
+	policies      map[string]cpb.Revocation_Policy
+	files         []*x509.RevocationList
+	fetch         bool
+	client        *http.Client
+	// now returns the current time used to check the CRL validity.
+	now func() time.Time
+
+	mu      sync.Mutex
+	fetched map[string]*cachedCRL
+}
+
+// Policy returns the revocation policy for the manufacturer.
+func (c *Checker) Policy(manufacturer string) cpb.Revocation_Policy {
+	if p, ok := c.policies[strings.ToLower(manufacturer)]; ok && p != cpb.Revocation_POLICY_UNSPECIFIED {
+		return p
+	}
+	if c.defaultPolicy == cpb.Revocation_POLICY_UNSPECIFIED {
+		return cpb.Revocation_POLICY_OFF
+	}
+	return c.defaultPolicy
+}
+
+// Check checks the revocation status of every certificate of the chain, except the root, according to the policy of the manufacturer.
+// The chain must be ordered from the leaf to the root, as returned by x509.Certificate.Verify.
+// The returned error wraps ErrRevoked if a certificate is revoked, or ErrStatusUnknown if the status of a certificate cannot be
+// determined under the hard-fail policy.
+func (c *Checker) Check(ctx context.Context, chain []*x509.Certificate, manufacturer string) error {
+	policy := c.Policy(manufacturer)
+	if policy == cpb.Revocation_POLICY_OFF {
+		return nil
+	}
+	var unknown error
+	for i := 0; i < len(chain)-1; i++ {
+		cert, issuer := chain[i], chain[i+1]
+		crl, err := c.crl(ctx, cert, issuer)
+		if err != nil {
+			if unknown == nil {
+				unknown = fmt.Errorf("%w for certificate %q: %v", ErrStatusUnknown, cert.Subject, err)
+			}
+			continue
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				RevokedIdentities.Add(manufacturer, 1)
+				return fmt.Errorf("%w: certificate %q with serial number %v was revoked on %v", ErrRevoked, cert.Subject, cert.SerialNumber, entry.RevocationTime)
+			}
+		}
+	}
+	if unknown != nil {
+		UnknownStatusIdentities.Add(manufacturer, 1)
+		if policy == cpb.Revocation_POLICY_HARD_FAIL {
+			return unknown
+		}
+		log.Warningf("Accepting IDevID certificate chain of %v chassis under soft-fail policy: %v", manufacturer, unknown)
+	}
+	return nil
+}
+
+// crl returns a current CRL for the certificate, signed by its issuer.
+func (c *Checker) crl(ctx context.Context, cert, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	now := c.now()
+	for _, crl := range c.files {
+		if c.current(crl, cert, issuer, now) == nil {
+			return crl, nil
+		}
+	}
+	if !c.fetch {
+		return nil, fmt.Errorf("no current CRL available")
+	}
+	if len(cert.CRLDistributionPoints) == 0 {
+		return nil, fmt.Errorf("no current CRL available and certificate has no CRL distribution point")
+	}
+	var errs []error
+	for _, url := range cert.CRLDistributionPoints {
+		crl, err := c.fetchCRL(ctx, url, now)
+		if err == nil {
+			err = c.current(crl, cert, issuer, now)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", url, err))
+			continue
+		}
+		return crl, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// current checks that the CRL covers the certificate, is signed by its issuer and is not past its next update.
+func (c *Checker) current(crl *x509.RevocationList, cert, issuer *x509.Certificate, now time.Time) error {
+	if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) {
+		return fmt.Errorf("CRL issuer %q does not match certificate issuer %q", crl.Issuer, cert.Issuer)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return fmt.Errorf("CRL signature verification failed: %v", err)
+	}
+	if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+		return fmt.Errorf("CRL is stale since %v", crl.NextUpdate)
+	}
+	return nil
+}
+
+// fetchCRL returns the CRL of the distribution point, from the cache if it has not expired.
+func (c *Checker) fetchCRL(ctx context.Context, url string, now time.Time) (*x509.RevocationList, error) {
+	c.mu.Lock()
+	cached, ok := c.fetched[url]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiry) {
+		return cached.crl, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create CRL request: %v", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CRL request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CRL request returned %v", resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CRL: %v", err)
+	}
+	if len(raw) > maxCRLSize {
+		return nil, fmt.Errorf("CRL exceeds %d bytes", maxCRLSize)
+	}
+	crl, err := parseCRL(raw)
+	if err != nil {
+		return nil, err
+	}
+	expiry := crl.NextUpdate
+	if expiry.IsZero() {
+		expiry = now.Add(defaultCacheDuration)
+	}
+	c.mu.Lock()
+	c.fetched[url] = &cachedCRL{crl: crl, expiry: expiry}
+	c.mu.Unlock()
+	log.Infof("Fetched CRL from %v, next update %v", url, crl.NextUpdate)
+	return crl, nil
+}
+
+// parseCRL parses a PEM or DER encoded CRL.
+func parseCRL(raw []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(raw); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+		}
+		raw = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse CRL: %v", err)
+	}
+	return crl, nil
+}
+
+// New returns a new revocation checker.
+func New(config *cpb.Revocation) (*Checker, error) {
+	c := &Checker{
+		defaultPolicy: config.GetDefaultPolicy(),
+		policies:      make(map[string]cpb.Revocation_Policy),
+		fetch:         config.GetFetchDistributionPoints(),
+		client:        &http.Client{Timeout: defaultFetchTimeout},
+		now:           time.Now,
+		fetched:       make(map[string]*cachedCRL),
+	}
+	if t := config.GetFetchTimeoutSeconds(); t != 0 {
+		c.client.Timeout = time.Duration(t) * time.Second
+	}
+	for k, v := range config.GetManufacturerPolicies() {
+		c.policies[strings.ToLower(k)] = v
+	}
+	for _, f := range config.GetCrlFiles() {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CRL file: %v", err)
+		}
+		crl, err := parseCRL(raw)
+		if err != nil {
+			return nil, fmt.Errorf("CRL file %v: %v", f, err)
+		}
+		c.files = append(c.files, crl)
+	}
+	return c, nil
+}
@@ -0,0 +1,306 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"expvar"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	cpb "github.com/openconfig/bootz/server/proto/config"
+)
+
+// testPKI is a root CA, an intermediate CA and a leaf IDevID certificate.
+type testPKI struct {
+	root, intermediate, leaf *x509.Certificate
+	rootKey, intermediateKey crypto.Signer
+}
+
+func newCert(t *testing.T, serial int64, cn string, isCA bool, cdp string, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	if cdp != "" {
+		template.CRLDistributionPoints = []string{cdp}
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func newTestPKI(t *testing.T, baseURL string) *testPKI {
+	t.Helper()
+	p := &testPKI{}
+	p.root, p.rootKey = newCert(t, 1, "Root CA", true, "", nil, nil)
+	p.intermediate, p.intermediateKey = newCert(t, 2, "Intermediate CA", true, baseURL+"/root.crl", p.root, p.rootKey)
+	p.leaf, _ = newCert(t, 3, "IDevID", false, baseURL+"/intermediate.crl", p.intermediate, p.intermediateKey)
+	return p
+}
+
+func (p *testPKI) chain() []*x509.Certificate {
+	return []*x509.Certificate{p.leaf, p.intermediate, p.root}
+}
+
+// newCRL returns a DER CRL signed by the issuer, revoking the given certificates.
+func newCRL(t *testing.T, issuer *x509.Certificate, key crypto.Signer, nextUpdate time.Time, revoked ...*x509.Certificate) []byte {
+	t.Helper()
+	var entries []x509.RevocationListEntry
+	for _, c := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: c.SerialNumber, RevocationTime: time.Now().Add(-time.Minute)})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, issuer, key)
+	if err != nil {
+		t.Fatalf("unable to create CRL: %v", err)
+	}
+	return der
+}
+
+func writeFile(t *testing.T, data []byte) string {
+	t.Helper()
+	f := filepath.Join(t.TempDir(), "crl")
+	if err := os.WriteFile(f, data, 0o600); err != nil {
+		t.Fatalf("unable to write CRL file: %v", err)
+	}
+	return f
+}
+
+func TestCheck(t *testing.T) {
+	p := newTestPKI(t, "http://127.0.0.1:0")
+	other := newTestPKI(t, "http://127.0.0.1:0")
+	nextUpdate := time.Now().Add(time.Hour)
+	rootCRL := writeFile(t, newCRL(t, p.root, p.rootKey, nextUpdate))
+	// The intermediate CRL is PEM encoded to cover both encodings.
+	intermediateCRL := writeFile(t, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: newCRL(t, p.intermediate, p.intermediateKey, nextUpdate)}))
+	leafRevoked := writeFile(t, newCRL(t, p.intermediate, p.intermediateKey, nextUpdate, p.leaf))
+	intermediateRevoked := writeFile(t, newCRL(t, p.root, p.rootKey, nextUpdate, p.intermediate))
+	staleCRL := writeFile(t, newCRL(t, p.intermediate, p.intermediateKey, time.Now().Add(-time.Minute)))
+	// A CRL with the same issuer name, signed by another key.
+	forgedCRL := writeFile(t, newCRL(t, other.intermediate, other.intermediateKey, nextUpdate))
+
+	tests := []struct {
+		desc         string
+		config       *cpb.Revocation
+		manufacturer string
+		wantErr      error
+	}{{
+		desc:    "Unspecified policy is off",
+		config:  &cpb.Revocation{CrlFiles: []string{rootCRL, leafRevoked}},
+		wantErr: nil,
+	}, {
+		desc: "Off",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_OFF,
+			CrlFiles:      []string{rootCRL, leafRevoked},
+		},
+	}, {
+		desc: "Soft-fail not revoked",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_SOFT_FAIL,
+			CrlFiles:      []string{rootCRL, intermediateCRL},
+		},
+	}, {
+		desc: "Soft-fail leaf revoked",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_SOFT_FAIL,
+			CrlFiles:      []string{rootCRL, leafRevoked},
+		},
+		wantErr: ErrRevoked,
+	}, {
+		desc: "Soft-fail intermediate revoked",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_SOFT_FAIL,
+			CrlFiles:      []string{intermediateRevoked, intermediateCRL},
+		},
+		wantErr: ErrRevoked,
+	}, {
+		desc: "Soft-fail no CRL",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_SOFT_FAIL,
+		},
+	}, {
+		desc: "Hard-fail not revoked",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_HARD_FAIL,
+			CrlFiles:      []string{rootCRL, intermediateCRL},
+		},
+	}, {
+		desc: "Hard-fail missing intermediate CRL",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_HARD_FAIL,
+			CrlFiles:      []string{rootCRL},
+		},
+		wantErr: ErrStatusUnknown,
+	}, {
+		desc: "Hard-fail stale CRL",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_HARD_FAIL,
+			CrlFiles:      []string{rootCRL, staleCRL},
+		},
+		wantErr: ErrStatusUnknown,
+	}, {
+		desc: "Hard-fail forged CRL",
+		config: &cpb.Revocation{
+			DefaultPolicy: cpb.Revocation_POLICY_HARD_FAIL,
+			CrlFiles:      []string{rootCRL, forgedCRL},
+		},
+		wantErr: ErrStatusUnknown,
+	}, {
+		desc: "Manufacturer policy",
+		config: &cpb.Revocation{
+			DefaultPolicy:        cpb.Revocation_POLICY_OFF,
+			ManufacturerPolicies: map[string]cpb.Revocation_Policy{"cisco": cpb.Revocation_POLICY_HARD_FAIL},
+		},
+		manufacturer: "Cisco",
+		wantErr:      ErrStatusUnknown,
+	}, {
+		desc: "Other manufacturer uses default policy",
+		config: &cpb.Revocation{
+			DefaultPolicy:        cpb.Revocation_POLICY_OFF,
+			ManufacturerPolicies: map[string]cpb.Revocation_Policy{"cisco": cpb.Revocation_POLICY_HARD_FAIL},
+		},
+		manufacturer: "Arista",
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c, err := New(test.config)
+			if err != nil {
+				t.Fatalf("New() err = %v", err)
+			}
+			err = c.Check(context.Background(), p.chain(), test.manufacturer)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("Check() err = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(&cpb.Revocation{CrlFiles: []string{filepath.Join(t.TempDir(), "missing")}}); err == nil {
+		t.Errorf("New() with missing CRL file err = nil, want error")
+	}
+	if _, err := New(&cpb.Revocation{CrlFiles: []string{writeFile(t, []byte("not a CRL"))}}); err == nil {
+		t.Errorf("New() with invalid CRL file err = nil, want error")
+	}
+}
+
+func TestCheckFetch(t *testing.T) {
+	var mu sync.Mutex
+	crls := make(map[string][]byte)
+	requests := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.Path]++
+		crl, ok := crls[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(crl)
+	}))
+	defer ts.Close()
+	p := newTestPKI(t, ts.URL)
+	nextUpdate := time.Now().Add(time.Hour)
+	crls["/root.crl"] = newCRL(t, p.root, p.rootKey, nextUpdate)
+	crls["/intermediate.crl"] = newCRL(t, p.intermediate, p.intermediateKey, nextUpdate)
+
+	c, err := New(&cpb.Revocation{
+		DefaultPolicy:           cpb.Revocation_POLICY_HARD_FAIL,
+		FetchDistributionPoints: true,
+	})
+	if err != nil {
+		t.Fatalf("New() err = %v", err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := c.Check(ctx, p.chain(), "Cisco"); err != nil {
+			t.Fatalf("Check() err = %v", err)
+		}
+	}
+	mu.Lock()
+	if requests["/root.crl"] != 1 || requests["/intermediate.crl"] != 1 {
+		t.Errorf("CRL requests = %v, want one per distribution point", requests)
+	}
+	// Revoke the leaf; the new CRL is fetched once the cached one passes its next update.
+	crls["/intermediate.crl"] = newCRL(t, p.intermediate, p.intermediateKey, time.Now().Add(3*time.Hour), p.leaf)
+	mu.Unlock()
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	before := revokedCount("Cisco")
+	if err := c.Check(ctx, p.chain(), "Cisco"); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Check() err = %v, want %v", err, ErrRevoked)
+	}
+	if got := revokedCount("Cisco"); got != before+1 {
+		t.Errorf("revoked identities = %d, want %d", got, before+1)
+	}
+
+	// The status is unknown when the distribution point is unavailable.
+	mu.Lock()
+	delete(crls, "/root.crl")
+	mu.Unlock()
+	c.now = func() time.Time { return time.Now().Add(4 * time.Hour) }
+	if err := c.Check(ctx, p.chain(), "Cisco"); !errors.Is(err, ErrStatusUnknown) {
+		t.Errorf("Check() err = %v, want %v", err, ErrStatusUnknown)
+	}
+}
+
+func revokedCount(manufacturer string) int64 {
+	if v, ok := RevokedIdentities.Get(manufacturer).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -20,32 +20,56 @@
 package server
 
 import (
+	"crypto/tls"
 	"crypto/x509/pkix"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"net"
 	"strings"
+	"time"
 
 	log "github.com/golang/glog"
 	"github.com/openconfig/attestz/service/biz"
+	"github.com/openconfig/bootz/common/idevid"
+	"github.com/openconfig/bootz/common/imagehash"
 	bootztls "github.com/openconfig/bootz/common/tls"
+	"github.com/openconfig/bootz/common/urlsig"
 	"github.com/openconfig/bootz/dhcp"
 	"github.com/openconfig/bootz/http"
 	"github.com/openconfig/bootz/server/artifactmanager"
 	"github.com/openconfig/bootz/server/chassismanager"
+	"github.com/openconfig/bootz/server/imagecatalog"
+	"github.com/openconfig/bootz/server/revocation"
 	"github.com/openconfig/bootz/server/service"
+	"github.com/openconfig/bootz/tftp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 
+	dpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
 	bpb "github.com/openconfig/bootz/proto/bootz"
 	cpb "github.com/openconfig/bootz/server/proto/config"
 )
 
+// defaultSignedURLLifetime is how long a signed image URL is valid if the config does not set it.
+const defaultSignedURLLifetime = time.Hour
+
 // Server is the bootz emulator server.
 type Server struct {
 	serv    *grpc.Server
 	lis     net.Listener
 	service *service.Service
+	am      service.ArtifactManager
+	cm      *chassismanager.InMemoryChassisManager
+	// inventory is the config of the current chassis inventory.
+	inventory *cpb.Config
+	// dhcpConfig is the DHCP config the inventory records are added to, nil if the DHCP server is disabled.
+	dhcpConfig *dpb.Config
+	leases     *dhcpLeases
+	// leasePolicy is the lease mismatch policy the service was created with.
+	leasePolicy cpb.LeaseMismatchPolicy
+	catalog     *imagecatalog.Catalog
 }
 
 // Start starts up the bootz emulator server.
@@ -56,6 +80,58 @@ func (s *Server) Start() error {
 // Stop shuts down the bootz emulator server.
 func (s *Server) Stop() {
 	s.serv.GracefulStop()
+	if c, ok := s.am.(io.Closer); ok {
+		c.Close()
+	}
+	if s.catalog != nil {
+		s.catalog.Close()
+	}
+}
+
+// controlCardUpdater is implemented by the artifact managers whose control cards can be replaced.
+type controlCardUpdater interface {
+	UpdateControlCards(config *cpb.Config) error
+}
+
+// UpdateInventory replaces the chassis inventory with the one of the config. If the DHCP server is enabled, it is
+// reloaded with the records derived from the new inventory. The lease mismatch policy cannot be changed.
+func (s *Server) UpdateInventory(config *cpb.Config) error {
+	if err := validateInventory(config); err != nil {
+		return err
+	}
+	if config.GetLeaseMismatchPolicy() != s.leasePolicy {
+		return fmt.Errorf("lease_mismatch_policy cannot be changed from %v to %v without restarting the server", s.leasePolicy, config.GetLeaseMismatchPolicy())
+	}
+	var dhcpConf *dpb.Config
+	if s.dhcpConfig != nil {
+		var err error
+		if dhcpConf, err = inventoryDHCPConfig(s.dhcpConfig, config); err != nil {
+			return err
+		}
+	}
+	// The ownership vouchers and public keys of the new control cards are looked up by the artifact manager.
+	u, _ := s.am.(controlCardUpdater)
+	if u != nil {
+		if err := u.UpdateControlCards(config); err != nil {
+			return err
+		}
+	}
+	if dhcpConf != nil {
+		if err := dhcp.Reload(dhcpConf); err != nil {
+			if u != nil {
+				if err := u.UpdateControlCards(s.inventory); err != nil {
+					log.Errorf("Unable to restore the previous control cards: %v", err)
+				}
+			}
+			return fmt.Errorf("unable to reload dhcp server: %v", err)
+		}
+	}
+	s.inventory = config
+	s.cm.Update(config)
+	if s.leases != nil {
+		s.leases.update(config)
+	}
+	return nil
 }
 
 // Opts is used to pass optional args to NewServer.
@@ -81,11 +157,57 @@ func NewServer(config *cpb.Config, opts ...Opts) (*Server, error) {
 	if ip == nil {
 		return nil, fmt.Errorf("invalid Bootz server IP address: %q", addrParts[0])
 	}
-	am, err := artifactmanager.New(config)
+	if err := validateInventory(config); err != nil {
+		return nil, err
+	}
+	am, err := newArtifactManager(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ArtifactManager: %v", err)
 	}
-	cm := chassismanager.New(config)
+	var cmOpts []chassismanager.Option
+	var catalog *imagecatalog.Catalog
+	if config.GetImageCatalog() != nil {
+		if catalog, err = imagecatalog.New(config.GetImageCatalog()); err != nil {
+			return nil, fmt.Errorf("failed to create image catalog: %v", err)
+		}
+		cmOpts = append(cmOpts, chassismanager.WithImageResolver(catalog))
+		if config.GetImageCatalog().GetVerification() != nil {
+			cmOpts = append(cmOpts, chassismanager.WithImageVerifier(catalog))
+		}
+	}
+	var signer *urlsig.Signer
+	if config.GetImageUrlSigning() != nil {
+		if signer, err = newURLSigner(config.GetImageUrlSigning()); err != nil {
+			return nil, err
+		}
+	}
+	imageServer := false
+	for _, opt := range opts {
+		opt, ok := opt.(*http.Opts)
+		if !ok || (!opt.HTTPS && signer == nil) {
+			continue
+		}
+		imageServer = true
+		host, _, err := imageServerHost(opt.Address, ip)
+		if err != nil {
+			return nil, err
+		}
+		if opt.HTTPS {
+			cmOpts = append(cmOpts, chassismanager.WithHTTPSImageHost(host))
+		}
+		if signer != nil {
+			cmOpts = append(cmOpts, chassismanager.WithImageURLSigner(host, signer))
+		}
+	}
+	if signer != nil && !imageServer {
+		return nil, fmt.Errorf("image_url_signing requires the HTTP server")
+	}
+	for _, opt := range opts {
+		if opt, ok := opt.(*tftp.Opts); ok && signer != nil && !opt.AllowUnsignedAccess {
+			return nil, fmt.Errorf("image_url_signing is bypassed by the TFTP server, which serves the images to any client: set AllowUnsignedAccess of the TFTP options to start it anyway")
+		}
+	}
+	cm := chassismanager.New(config, cmOpts...)
 	trustAnchorCert, trustAnchorKey := am.BootzServerTrustAnchorKeyPair()
 	conf, err := bootztls.TLSConfiguration(&bootztls.Opts{
 		CAPrivateKey: trustAnchorKey,
@@ -101,16 +223,46 @@ func NewServer(config *cpb.Config, opts ...Opts) (*Server, error) {
 	}
 
 	var interceptor grpc.ServerOption
+	var dhcpConfig *dpb.Config
 	for _, opt := range opts {
 		switch opt := opt.(type) {
 		case *dhcp.Opts:
-			if err := dhcp.Start(opt.Config); err != nil {
+			// The DHCP records of the control cards are derived from the inventory.
+			conf, err := inventoryDHCPConfig(opt.Config, config)
+			if err != nil {
+				return nil, fmt.Errorf("unable to derive dhcp records from the inventory: %v", err)
+			}
+			if err := dhcp.Start(conf); err != nil {
 				return nil, fmt.Errorf("unable to start dhcp server %v", err)
 			}
+			dhcpConfig = opt.Config
 		case *http.Opts:
+			o := *opt
+			if catalog != nil && o.Admin != nil && o.Admin.Upload == nil {
+				admin := *o.Admin
+				admin.Upload = catalog
+				o.Admin = &admin
+			}
+			if signer != nil && o.URLVerifier == nil {
+				o.URLVerifier = signer
+			}
+			if o.HTTPS && o.TLSConfig == nil {
+				if o.TLSConfig, err = imageServerTLS(am, o.Address, ip); err != nil {
+					return nil, err
+				}
+			}
+			opt = &o
 			if err := http.Start(opt); err != nil {
 				return nil, fmt.Errorf("unable to start http server %v", err)
 			}
+		case *tftp.Opts:
+			o := *opt
+			if o.Folder == "" {
+				o.Folder = config.GetImageCatalog().GetFolder()
+			}
+			if err := tftp.Start(&o); err != nil {
+				return nil, fmt.Errorf("unable to start tftp server %v", err)
+			}
 		case *InterceptorOpts:
 			interceptor = grpc.UnaryInterceptor(opt.BootzInterceptor)
 		default:
@@ -119,7 +271,26 @@ func NewServer(config *cpb.Config, opts ...Opts) (*Server, error) {
 	}
 
 	log.Infof("Creating Bootz server...")
-	c, err := service.New(am, cm, &biz.DefaultTPM20Utils{})
+	serials, err := newSerialRegistry(config)
+	if err != nil {
+		return nil, err
+	}
+	serviceOpts := []service.Option{service.WithSerialExtractors(serials)}
+	var leases *dhcpLeases
+	if dhcpConfig != nil {
+		leases = newDHCPLeases(config)
+		serviceOpts = append(serviceOpts, service.WithLeaseLookup(leases, leaseMismatchPolicy(config.GetLeaseMismatchPolicy())))
+	} else if config.GetLeaseMismatchPolicy() != cpb.LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_IGNORE {
+		return nil, fmt.Errorf("lease_mismatch_policy requires the DHCP server")
+	}
+	if config.GetRevocation() != nil {
+		rc, err := revocation.New(config.GetRevocation())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create revocation checker: %v", err)
+		}
+		serviceOpts = append(serviceOpts, service.WithRevocationChecker(rc))
+	}
+	c, err := service.New(am, cm, &biz.DefaultTPM20Utils{}, serviceOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("error creating service: %v", err)
 	}
@@ -141,8 +312,141 @@ func NewServer(config *cpb.Config, opts ...Opts) (*Server, error) {
 	log.Infof("=============================================================================")
 
 	return &Server{
-		serv:    s,
-		lis:     lis,
-		service: c,
+		serv:        s,
+		lis:         lis,
+		service:     c,
+		am:          am,
+		cm:          cm,
+		inventory:   config,
+		dhcpConfig:  dhcpConfig,
+		leases:      leases,
+		leasePolicy: config.GetLeaseMismatchPolicy(),
+		catalog:     catalog,
 	}, nil
 }
+
+// imageServerHost returns the host:port of the image server listening at the address, and its IP address, which
+// defaults to the one of the Bootz server.
+func imageServerHost(address string, serverIP net.IP) (string, net.IP, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid http server address %q: %v", address, err)
+	}
+	ip := serverIP
+	if host != "" {
+		if ip = net.ParseIP(host); ip == nil {
+			return "", nil, fmt.Errorf("image server address must be in the format of 'IP:Port' or ':Port', got: %q", address)
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), ip, nil
+}
+
+// newURLSigner returns the signer of the image URLs.
+func newURLSigner(config *cpb.ImageURLSigning) (*urlsig.Signer, error) {
+	var keys []urlsig.Key
+	for _, k := range config.GetKeys() {
+		secret, err := base64.StdEncoding.DecodeString(k.GetSecret())
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode secret of url signing key %q: %v", k.GetId(), err)
+		}
+		keys = append(keys, urlsig.Key{ID: k.GetId(), Secret: secret})
+	}
+	lifetime := defaultSignedURLLifetime
+	if s := config.GetLifetimeSeconds(); s != 0 {
+		lifetime = time.Duration(s) * time.Second
+	}
+	s, err := urlsig.New(keys, lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image_url_signing: %v", err)
+	}
+	return s, nil
+}
+
+// imageServerTLS returns the TLS config of the HTTPS image server, with a certificate issued from the trust anchor
+// conveyed to the devices, and which verifies the IDevID of the devices.
+func imageServerTLS(am service.ArtifactManager, address string, serverIP net.IP) (*tls.Config, error) {
+	_, ip, err := imageServerHost(address, serverIP)
+	if err != nil {
+		return nil, err
+	}
+	trustAnchorCert, trustAnchorKey := am.BootzServerTrustAnchorKeyPair()
+	conf, err := bootztls.TLSConfiguration(&bootztls.Opts{
+		CAPrivateKey: trustAnchorKey,
+		CACert:       trustAnchorCert,
+		IPAddress:    ip,
+		ClientCAs:    am.VendorCABundle(),
+		ServerCertSubject: &pkix.Name{
+			CommonName: "Bootz Image Server TLS Certificate",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating image server cert: %v", err)
+	}
+	return conf, nil
+}
+
+// validateInventory checks the hash algorithm and the hash of the intended images of the chassis, of their control
+// cards and of the image targets, the control card overrides and the rollouts. The images which are only referenced by
+// name and version, to be resolved from the image catalog, are not checked.
+func validateInventory(config *cpb.Config) error {
+	check := func(image *bpb.SoftwareImage) error {
+		if image.GetUrl() == "" && image.GetOsImageHash() == "" && image.GetHashAlgorithm() == "" {
+			return nil
+		}
+		return imagehash.Validate(image)
+	}
+	for _, c := range config.GetChassis() {
+		if err := check(c.GetIntendedImage()); err != nil {
+			return fmt.Errorf("invalid intended image of chassis %v: %v", c.GetHostname(), err)
+		}
+		for _, cc := range c.GetControlCards() {
+			if err := check(cc.GetIntendedImage()); err != nil {
+				return fmt.Errorf("invalid intended image of control card %v: %v", cc.GetSerialNumber(), err)
+			}
+		}
+	}
+	for _, t := range config.GetImageTargets() {
+		if err := check(t.GetImage()); err != nil {
+			return fmt.Errorf("invalid image of image target %v: %v", t.GetName(), err)
+		}
+	}
+	if err := chassismanager.ValidateOverrides(config); err != nil {
+		return err
+	}
+	if err := chassismanager.ValidateRollouts(config); err != nil {
+		return fmt.Errorf("invalid rollouts: %v", err)
+	}
+	return nil
+}
+
+// newSerialRegistry returns the registry of IDevID serial extractors defined in the config.
+func newSerialRegistry(config *cpb.Config) (*idevid.Registry, error) {
+	r := idevid.NewRegistry()
+	for _, se := range config.GetIdevidSerialExtractors() {
+		var es []idevid.SerialExtractor
+		for _, name := range se.GetExtractors() {
+			e, err := idevid.ExtractorByName(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid serial extractors for manufacturer %q: %v", se.GetManufacturer(), err)
+			}
+			es = append(es, e)
+		}
+		if len(es) == 0 {
+			return nil, fmt.Errorf("no serial extractors for manufacturer %q", se.GetManufacturer())
+		}
+		if se.GetManufacturer() == "" {
+			r.SetDefault(idevid.FirstOf(es...))
+		} else {
+			r.Register(se.GetManufacturer(), idevid.FirstOf(es...))
+		}
+	}
+	return r, nil
+}
+
+// newArtifactManager returns an artifact manager which fetches ownership vouchers from the voucher service if one is configured.
+func newArtifactManager(config *cpb.Config) (service.ArtifactManager, error) {
+	if config.GetVoucherService() != nil {
+		return artifactmanager.NewVoucherService(config)
+	}
+	return artifactmanager.New(config)
+}
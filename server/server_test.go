@@ -15,13 +15,26 @@
 package server
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/base64"
 	"flag"
+	"net"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
 	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
+	"github.com/openconfig/bootz/common/types"
+	"github.com/openconfig/bootz/http"
+	"github.com/openconfig/bootz/tftp"
+
+	plslease "github.com/openconfig/bootz/dhcp/plugins/slease"
 
+	dpb "github.com/openconfig/bootz/dhcp/proto/dhcpconfig"
+	bpb "github.com/openconfig/bootz/proto/bootz"
 	cpb "github.com/openconfig/bootz/server/proto/config"
 )
 
@@ -50,3 +63,358 @@ func TestStartup(t *testing.T) {
 		t.Fatalf("newServer() err = %v, want nil", err)
 	}
 }
+
+func TestUpdateInventory(t *testing.T) {
+	cert, key, err := ownercertificate.NewRSACertificate("Server Test", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate certificate: %v", err)
+	}
+	keyRaw, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	pubRaw, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	pair := &cpb.CertKeyPair{Cert: base64.StdEncoding.EncodeToString(cert.Raw), Key: base64.StdEncoding.EncodeToString(keyRaw)}
+	config := &cpb.Config{ServerAddress: "127.0.0.1:0", TrustAnchor: pair, OwnerCertificate: pair}
+	s, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("NewServer() err = %v, want nil", err)
+	}
+	defer s.Stop()
+
+	if _, _, err := s.am.PublicKey(context.Background(), "123A", ""); err == nil {
+		t.Fatalf("PublicKey() of an unknown control card err = nil, want error")
+	}
+	config.Chassis = []*cpb.Chassis{{
+		Hostname:     "added",
+		ControlCards: []*cpb.ControlCard{{SerialNumber: "123A", PublicKey: base64.StdEncoding.EncodeToString(pubRaw)}},
+	}}
+	if err := s.UpdateInventory(config); err != nil {
+		t.Fatalf("UpdateInventory() err = %v, want nil", err)
+	}
+	if _, _, err := s.am.PublicKey(context.Background(), "123A", ""); err != nil {
+		t.Errorf("PublicKey() of an added control card err = %v, want nil", err)
+	}
+
+	config.Chassis[0].ControlCards[0].OwnershipVoucher = base64.StdEncoding.EncodeToString([]byte("not a voucher"))
+	if err := s.UpdateInventory(config); err == nil {
+		t.Errorf("UpdateInventory() with an invalid ownership voucher err = nil, want error")
+	}
+
+	changed := &cpb.Config{ServerAddress: config.GetServerAddress(), TrustAnchor: pair, OwnerCertificate: pair, LeaseMismatchPolicy: cpb.LeaseMismatchPolicy_LEASE_MISMATCH_POLICY_FLAG}
+	if err := s.UpdateInventory(changed); err == nil {
+		t.Errorf("UpdateInventory() with a changed lease mismatch policy err = nil, want error")
+	}
+}
+
+func TestURLSigningWithTFTP(t *testing.T) {
+	cert, key, err := ownercertificate.NewRSACertificate("Server Test", "", nil, nil)
+	if err != nil {
+		t.Fatalf("unable to generate certificate: %v", err)
+	}
+	keyRaw, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	pair := &cpb.CertKeyPair{Cert: base64.StdEncoding.EncodeToString(cert.Raw), Key: base64.StdEncoding.EncodeToString(keyRaw)}
+	config := &cpb.Config{
+		ServerAddress:    "127.0.0.1:0",
+		TrustAnchor:      pair,
+		OwnerCertificate: pair,
+		ImageUrlSigning: &cpb.ImageURLSigning{
+			Keys: []*cpb.URLSigningKey{{Id: "2026-10", Secret: base64.StdEncoding.EncodeToString(make([]byte, 32))}},
+		},
+	}
+	folder := t.TempDir()
+	if _, err := NewServer(config, &http.Opts{Address: "127.0.0.1:0", Folder: folder}, &tftp.Opts{Address: "127.0.0.1:0", Folder: folder}); err == nil || !strings.Contains(err.Error(), "TFTP") {
+		t.Errorf("NewServer() with image_url_signing and TFTP err = %v, want a TFTP error", err)
+	}
+}
+
+func TestNewSerialRegistry(t *testing.T) {
+	tests := []struct {
+		desc    string
+		config  *cpb.Config
+		wantErr bool
+	}{{
+		desc:   "No extractors",
+		config: &cpb.Config{},
+	}, {
+		desc: "Default and manufacturer extractors",
+		config: &cpb.Config{
+			IdevidSerialExtractors: []*cpb.SerialExtractor{
+				{Extractors: []string{"pid-sn", "subject-serial-number"}},
+				{Manufacturer: "Cisco", Extractors: []string{"hardware-module-name"}},
+			},
+		},
+	}, {
+		desc: "Unknown extractor",
+		config: &cpb.Config{
+			IdevidSerialExtractors: []*cpb.SerialExtractor{
+				{Manufacturer: "Cisco", Extractors: []string{"unknown"}},
+			},
+		},
+		wantErr: true,
+	}, {
+		desc: "Empty extractors",
+		config: &cpb.Config{
+			IdevidSerialExtractors: []*cpb.SerialExtractor{
+				{Manufacturer: "Cisco"},
+			},
+		},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := newSerialRegistry(test.config); (err != nil) != test.wantErr {
+				t.Errorf("newSerialRegistry() err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestInventoryDHCPConfig(t *testing.T) {
+	inventory := func(cards ...*cpb.ControlCard) *cpb.Config {
+		return &cpb.Config{
+			ServerAddress: "10.0.0.1:15006",
+			Chassis:       []*cpb.Chassis{{Hostname: "router1", ControlCards: cards}},
+		}
+	}
+	tests := []struct {
+		desc    string
+		base    *dpb.Config
+		config  *cpb.Config
+		want    *dpb.Config
+		wantErr bool
+	}{{
+		desc: "Records from macs and serial",
+		base: &dpb.Config{Interface: "eth0"},
+		config: inventory(
+			&cpb.ControlCard{SerialNumber: "SN1", ManagementMacs: []string{"00-1C-73-00-00-01", "00:1c:73:00:00:02"}, ManagementIp: "10.0.0.11/24", ManagementGateway: "10.0.0.1"},
+			&cpb.ControlCard{SerialNumber: "SN2", ManagementIp: "2001:db8::12/64"},
+			&cpb.ControlCard{SerialNumber: "SN3"},
+		),
+		want: &dpb.Config{
+			Interface: "eth0",
+			BootzUrls: []string{"bootz://10.0.0.1:15006"},
+			Records: []*dpb.Record{
+				{Machine: "00:1c:73:00:00:01", AlternateMachines: []string{"00:1c:73:00:00:02"}, Ip: "10.0.0.11/24", Gateway: "10.0.0.1"},
+				{Machine: "SN2", Ip: "2001:db8::12/64"},
+			},
+		},
+	}, {
+		desc: "DHCP config records and bootz urls take precedence",
+		base: &dpb.Config{
+			Interface: "eth0",
+			BootzUrls: []string{"bootz://10.0.0.2:15006"},
+			Records:   []*dpb.Record{{Machine: "00:1C:73:00:00:01", Ip: "10.0.0.99/24", Gateway: "10.0.0.1"}},
+		},
+		config: inventory(
+			&cpb.ControlCard{SerialNumber: "SN1", ManagementMacs: []string{"00:1c:73:00:00:01"}, ManagementIp: "10.0.0.11/24", ManagementGateway: "10.0.0.1"},
+		),
+		want: &dpb.Config{
+			Interface: "eth0",
+			BootzUrls: []string{"bootz://10.0.0.2:15006"},
+			Records:   []*dpb.Record{{Machine: "00:1C:73:00:00:01", Ip: "10.0.0.99/24", Gateway: "10.0.0.1"}},
+		},
+	}, {
+		desc:    "Invalid management ip",
+		base:    &dpb.Config{},
+		config:  inventory(&cpb.ControlCard{SerialNumber: "SN1", ManagementIp: "10.0.0.11", ManagementGateway: "10.0.0.1"}),
+		wantErr: true,
+	}, {
+		desc:    "Missing ipv4 gateway",
+		base:    &dpb.Config{},
+		config:  inventory(&cpb.ControlCard{SerialNumber: "SN1", ManagementIp: "10.0.0.11/24"}),
+		wantErr: true,
+	}, {
+		desc:    "Invalid management mac",
+		base:    &dpb.Config{},
+		config:  inventory(&cpb.ControlCard{SerialNumber: "SN1", ManagementMacs: []string{"router1"}, ManagementIp: "2001:db8::12/64"}),
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := inventoryDHCPConfig(test.base, test.config)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("inventoryDHCPConfig() err = %v, want error %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("inventoryDHCPConfig() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+	base := &dpb.Config{Interface: "eth0"}
+	if _, err := inventoryDHCPConfig(base, inventory(&cpb.ControlCard{SerialNumber: "SN2", ManagementIp: "2001:db8::12/64"})); err != nil {
+		t.Fatalf("inventoryDHCPConfig() err = %v", err)
+	}
+	if len(base.GetRecords()) != 0 || len(base.GetBootzUrls()) != 0 {
+		t.Errorf("inventoryDHCPConfig() modified the DHCP config: %v", base)
+	}
+}
+
+func TestDHCPLeases(t *testing.T) {
+	d := newDHCPLeases(&cpb.Config{
+		Chassis: []*cpb.Chassis{{ControlCards: []*cpb.ControlCard{
+			{SerialNumber: "SN1", ManagementMacs: []string{"00-1C-73-00-00-01"}},
+			{SerialNumber: "SN2"},
+		}}},
+	})
+	d.leases = func() []plslease.Lease {
+		return []plslease.Lease{
+			{Key: "00:1c:73:00:00:01", MAC: "00:1c:73:00:00:01", IP: "10.0.0.11"},
+			{Key: "sn2", ClientID: "SN2", MAC: "00:1c:73:00:00:02", IP: "10.0.0.12"},
+			{Key: "00:1c:73:00:00:01", MAC: "00:1c:73:00:00:01", IP: "2001:db8::11"},
+		}
+	}
+
+	if diff := cmp.Diff([]string{"10.0.0.11", "2001:db8::11"}, d.LeasedIPs("SN1")); diff != "" {
+		t.Errorf("LeasedIPs(SN1) diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"10.0.0.12"}, d.LeasedIPs("SN2")); diff != "" {
+		t.Errorf("LeasedIPs(SN2) diff (-want +got):\n%s", diff)
+	}
+	if got := d.LeasedIPs("SN3"); len(got) != 0 {
+		t.Errorf("LeasedIPs(SN3) = %v, want none", got)
+	}
+	want := &types.DHCPLease{IP: "10.0.0.12", MAC: "00:1c:73:00:00:02", ClientID: "SN2"}
+	if diff := cmp.Diff(want, d.LeaseByIP("::ffff:10.0.0.12")); diff != "" {
+		t.Errorf("LeaseByIP() diff (-want +got):\n%s", diff)
+	}
+	if got := d.LeaseByIP("10.0.0.13"); got != nil {
+		t.Errorf("LeaseByIP() of an unleased address = %+v, want nil", got)
+	}
+
+	// The management MAC addresses follow the inventory.
+	d.update(&cpb.Config{})
+	if got := d.LeasedIPs("SN1"); len(got) != 0 {
+		t.Errorf("LeasedIPs(SN1) after update = %v, want none", got)
+	}
+}
+
+func TestImageServerHost(t *testing.T) {
+	serverIP := net.ParseIP("10.0.0.1")
+	tests := []struct {
+		desc     string
+		address  string
+		wantHost string
+		wantErr  bool
+	}{{
+		desc:     "Port only",
+		address:  ":8080",
+		wantHost: "10.0.0.1:8080",
+	}, {
+		desc:     "IPv4",
+		address:  "10.0.0.2:443",
+		wantHost: "10.0.0.2:443",
+	}, {
+		desc:     "IPv6",
+		address:  "[2001:db8::1]:443",
+		wantHost: "[2001:db8::1]:443",
+	}, {
+		desc:    "Host name",
+		address: "images.example.com:443",
+		wantErr: true,
+	}, {
+		desc:    "Missing port",
+		address: "10.0.0.2",
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, _, err := imageServerHost(test.address, serverIP)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("imageServerHost() err = %v, want error %v", err, test.wantErr)
+			}
+			if got != test.wantHost {
+				t.Errorf("imageServerHost() = %q, want %q", got, test.wantHost)
+			}
+		})
+	}
+}
+
+func TestNewURLSigner(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	tests := []struct {
+		desc    string
+		config  *cpb.ImageURLSigning
+		wantErr bool
+	}{{
+		desc: "Valid",
+		config: &cpb.ImageURLSigning{
+			Keys:            []*cpb.URLSigningKey{{Id: "2026-10", Secret: secret}, {Id: "2026-09", Secret: secret}},
+			LifetimeSeconds: 600,
+		},
+	}, {
+		desc:    "No keys",
+		config:  &cpb.ImageURLSigning{},
+		wantErr: true,
+	}, {
+		desc:    "Invalid base64",
+		config:  &cpb.ImageURLSigning{Keys: []*cpb.URLSigningKey{{Id: "2026-10", Secret: "not base64!"}}},
+		wantErr: true,
+	}, {
+		desc:    "Short secret",
+		config:  &cpb.ImageURLSigning{Keys: []*cpb.URLSigningKey{{Id: "2026-10", Secret: "c2VjcmV0"}}},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := newURLSigner(test.config); (err != nil) != test.wantErr {
+				t.Errorf("newURLSigner() err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateInventory(t *testing.T) {
+	sha384 := strings.Repeat("ab", 48)
+	tests := []struct {
+		desc    string
+		image   *bpb.SoftwareImage
+		wantErr bool
+	}{{
+		desc:  "No intended image",
+		image: nil,
+	}, {
+		desc:  "Resolved from the catalog",
+		image: &bpb.SoftwareImage{Name: "os", Version: "1.0"},
+	}, {
+		desc:  "SHA-384",
+		image: &bpb.SoftwareImage{Url: "http://10.0.0.1/os.bin", OsImageHash: sha384, HashAlgorithm: "ietf-sztp-conveyed-info:sha-384"},
+	}, {
+		desc:  "Colon separated SHA-256",
+		image: &bpb.SoftwareImage{Url: "http://10.0.0.1/os.bin", OsImageHash: strings.TrimSuffix(strings.Repeat("AB:", 32), ":"), HashAlgorithm: "ietf-sztp-conveyed-info:sha-256"},
+	}, {
+		desc:    "Digest of another algorithm",
+		image:   &bpb.SoftwareImage{Url: "http://10.0.0.1/os.bin", OsImageHash: sha384, HashAlgorithm: "ietf-sztp-conveyed-info:sha-512"},
+		wantErr: true,
+	}, {
+		desc:    "Unsupported algorithm",
+		image:   &bpb.SoftwareImage{Url: "http://10.0.0.1/os.bin", OsImageHash: sha384, HashAlgorithm: "md5"},
+		wantErr: true,
+	}, {
+		desc:    "Missing hash",
+		image:   &bpb.SoftwareImage{Url: "http://10.0.0.1/os.bin"},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config := &cpb.Config{Chassis: []*cpb.Chassis{{Hostname: "test", IntendedImage: test.image}}}
+			if err := validateInventory(config); (err != nil) != test.wantErr {
+				t.Errorf("validateInventory() err = %v, want error %v", err, test.wantErr)
+			}
+			config = &cpb.Config{Chassis: []*cpb.Chassis{{Hostname: "test", ControlCards: []*cpb.ControlCard{{SerialNumber: "123", PartNumber: "PN-A", IntendedImage: test.image}}}}}
+			if err := validateInventory(config); (err != nil) != test.wantErr {
+				t.Errorf("validateInventory() of a control card override err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+	config := &cpb.Config{Chassis: []*cpb.Chassis{{Hostname: "test", ControlCards: []*cpb.ControlCard{{SerialNumber: "123", BootPasswordHash: "hash"}}}}}
+	if err := validateInventory(config); err == nil {
+		t.Errorf("validateInventory() of a control card override without part number err = nil, want error")
+	}
+}
@@ -30,17 +30,22 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"slices"
 	"strings"
 
 	log "github.com/golang/glog"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/openconfig/attestz/service/biz"
+	"github.com/openconfig/bootz/common/idevid"
 	ownercertificate "github.com/openconfig/bootz/common/owner_certificate"
 	"github.com/openconfig/bootz/common/signature"
 	"github.com/openconfig/bootz/common/types"
+	"github.com/openconfig/bootz/server/revocation"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
@@ -88,12 +93,70 @@ type ChassisManager interface {
 	UpdateStatus(ctx context.Context, req *bpb.ReportStatusRequest) error
 }
 
+// RevocationChecker is an interface for checking the revocation status of IDevID certificate chains.
+type RevocationChecker interface {
+	// Check checks the revocation status of the certificate chain, ordered from the leaf to the root, presented by a chassis of the manufacturer.
+	// The returned error wraps revocation.ErrRevoked if a certificate is revoked.
+	Check(ctx context.Context, chain []*x509.Certificate, manufacturer string) error
+}
+
+// LeaseLookup is an interface for correlating bootstrap requests with the DHCP leases of the devices.
+type LeaseLookup interface {
+	// LeaseByIP returns the unexpired lease of the IP address, or nil if it is not leased.
+	LeaseByIP(ip string) *types.DHCPLease
+	// LeasedIPs returns the addresses currently leased to the control card with the given serial number.
+	LeasedIPs(serial string) []string
+}
+
+// LeaseMismatchPolicy defines what happens to requests whose source address is not the address leased to the
+// requesting control card.
+type LeaseMismatchPolicy int
+
+const (
+	// LeaseMismatchIgnore does not check the source address of requests.
+	LeaseMismatchIgnore LeaseMismatchPolicy = iota
+	// LeaseMismatchFlag logs the mismatch and flags the chassis.
+	LeaseMismatchFlag
+	// LeaseMismatchReject rejects the requests.
+	LeaseMismatchReject
+)
+
 // Service represents the server and entity manager.
 type Service struct {
 	bpb.UnimplementedBootstrapServer
-	am    ArtifactManager
-	cm    ChassisManager
-	tpm20 biz.TPM20Utils
+	am          ArtifactManager
+	cm          ChassisManager
+	tpm20       biz.TPM20Utils
+	rc          RevocationChecker
+	serials     *idevid.Registry
+	leases      LeaseLookup
+	leasePolicy LeaseMismatchPolicy
+}
+
+// Option configures optional behavior of the service.
+type Option func(*Service)
+
+// WithSerialExtractors extracts the serial numbers from IDevID certificates with the extractors of the given registry.
+func WithSerialExtractors(r *idevid.Registry) Option {
+	return func(s *Service) {
+		s.serials = r
+	}
+}
+
+// WithRevocationChecker checks the revocation status of IDevID certificates with the given checker.
+func WithRevocationChecker(rc RevocationChecker) Option {
+	return func(s *Service) {
+		s.rc = rc
+	}
+}
+
+// WithLeaseLookup attaches the DHCP lease of the source address of requests to the chassis, and checks that it is the
+// address leased to the requesting control card according to the policy.
+func WithLeaseLookup(ll LeaseLookup, policy LeaseMismatchPolicy) Option {
+	return func(s *Service) {
+		s.leases = ll
+		s.leasePolicy = policy
+	}
 }
 
 type streamSession struct {
@@ -151,12 +214,16 @@ func (s *Service) GetBootstrapData(ctx context.Context, req *bpb.GetBootstrapDat
 		Serials:      serials,
 		ActiveSerial: req.GetControlCardState().GetSerialNumber(),
 		IPAddress:    peerAddr,
+		PartNumbers:  partNumbers(chassisDesc),
 	}
 	// Validate the chassis can be serviced
 	err = s.cm.ResolveChassis(ctx, chassis)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "failed to resolve chassis to inventory %+v, err: %v", chassisDesc, err)
 	}
+	if err := s.correlateLease(chassis); err != nil {
+		return nil, err
+	}
 	log.Infof("Verified server can resolve chassis")
 
 	// If chassis can only be booted into secure mode then return error
@@ -234,7 +301,18 @@ func (s *Service) ReportStatus(ctx context.Context, req *bpb.ReportStatusRequest
 		return nil, err
 	}
 	log.Infof("Received ReportStatus request(%+v) from %v", req, peerAddr)
-	return &bpb.EmptyResponse{}, s.cm.UpdateStatus(ctx, req)
+	chassis := &types.Chassis{IPAddress: peerAddr}
+	if states := req.GetStates(); len(states) > 0 {
+		chassis.ActiveSerial = states[0].GetSerialNumber()
+	}
+	if err := s.correlateLease(chassis); err != nil {
+		return nil, err
+	}
+	if err := s.cm.UpdateStatus(ctx, req); err != nil {
+		return nil, err
+	}
+	log.Infof("Successfully set status for device %s%s", chassis.ActiveSerial, leaseDescription(chassis))
+	return &bpb.EmptyResponse{}, nil
 }
 
 // BootstrapStream implements the RPC handler for Streaming Bootz v0.6.
@@ -298,7 +376,7 @@ func (s *Service) BootstrapStream(stream bpb.Bootstrap_BootstrapStreamServer) er
 				if len(session.serverNonce) == 0 {
 					return status.Errorf(codes.FailedPrecondition, "received unexpected TPM 2.0 nonce challenge response")
 				}
-				cert, err := s.validateIDevID(session.chassis)
+				cert, err := s.validateIDevID(ctx, session.chassis)
 				if err != nil {
 					return err
 				}
@@ -485,7 +563,7 @@ func (s *Service) BootstrapStreamV1(stream bpb.Bootstrap_BootstrapStreamV1Server
 					return status.Errorf(codes.FailedPrecondition, "received unexpected TPM20IDevID challenge response")
 				}
 				// Verify IDevID certificate.
-				cert, err := s.validateIDevID(session.chassis)
+				cert, err := s.validateIDevID(stream.Context(), session.chassis)
 				if err != nil {
 					log.Errorf("Tpm20Idevid challenge certificate verification failed for device %s, error: %v", session.chassis.ActiveSerial, err)
 					return err
@@ -687,6 +765,9 @@ func (s *Service) createChallengeRequest(session *streamSessionV1, message proto
 	if err = s.cm.ResolveChassis(ctx, chassis); err != nil {
 		return nil, status.Errorf(codes.NotFound, "failed to resolve chassis: %v", err)
 	}
+	if err := s.correlateLease(chassis); err != nil {
+		return nil, err
+	}
 	log.Infof("Resolved device %s with identity %T to hostname %s", chassis.ActiveSerial, chassis.Identity.GetType(), chassis.Hostname)
 
 	if !chassis.StreamingSupported {
@@ -825,7 +906,7 @@ func (s *Service) createReportStatusResponse(session *streamSessionV1) (*bpb.Boo
 		log.Errorf("Failed to set status for device %s: %v", session.chassis.ActiveSerial, err)
 		return nil, status.Errorf(codes.Internal, "failed to set status: %v", err)
 	}
-	log.Infof("Successfully set status for device %s", session.chassis.ActiveSerial)
+	log.Infof("Successfully set status for device %s%s", session.chassis.ActiveSerial, leaseDescription(session.chassis))
 
 	reportStatusResponse := &bpb.BootstrapStreamResponseV1{
 		Type: &bpb.BootstrapStreamResponseV1_ReportStatusResponse{
@@ -837,7 +918,7 @@ func (s *Service) createReportStatusResponse(session *streamSessionV1) (*bpb.Boo
 }
 
 // validateIDevID validates the authenticity and authorization of an encoded IDevID presented by a chassis, and return it as a certificate.
-func (s *Service) validateIDevID(chassis *types.Chassis) (*x509.Certificate, error) {
+func (s *Service) validateIDevID(ctx context.Context, chassis *types.Chassis) (*x509.Certificate, error) {
 	var pemBlock *pem.Block
 	var intermediates []byte
 	idevid := chassis.Identity.GetIdevidCert()
@@ -865,16 +946,23 @@ func (s *Service) validateIDevID(chassis *types.Chassis) (*x509.Certificate, err
 			return nil, status.Errorf(codes.InvalidArgument, "failed to parse PEM encoded intermediate certificates: %v", intermediates)
 		}
 	}
-	if _, err := cert.Verify(opts); err != nil {
+	chains, err := cert.Verify(opts)
+	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "IDevID certificate chain validation failed: %v", err)
 	}
+	if s.rc != nil {
+		if err := s.rc.Check(ctx, chains[0], chassis.Manufacturer); err != nil {
+			if errors.Is(err, revocation.ErrRevoked) {
+				return nil, status.Errorf(codes.PermissionDenied, "IDevID certificate chain is revoked: %v", err)
+			}
+			return nil, status.Errorf(codes.Unavailable, "IDevID certificate chain revocation check failed: %v", err)
+		}
+	}
 
-	// cert.Subject.SerialNumber can come in the format PID:xxxxxxx SN:1234JF or just the serial number as it is. We need the value after "SN:".
-	var certSerial string
-	if sn := strings.Split(cert.Subject.SerialNumber, "SN:"); len(sn) != 2 {
-		certSerial = strings.TrimSpace(sn[0])
-	} else {
-		certSerial = strings.TrimSpace(sn[1])
+	// Vendors encode the serial number differently, e.g. as PID:xxxxxxx SN:1234JF in the subject serialNumber or in the hardwareModuleName.
+	certSerial, err := s.serials.Serial(cert, chassis.Manufacturer)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to extract serial number from IDevID certificate: %v", err)
 	}
 
 	if !slices.ContainsFunc(chassis.Serials, func(serial string) bool {
@@ -917,6 +1005,9 @@ func (s *Service) establishSessionAndSendChallenge(session *streamSession) error
 	if err != nil {
 		return status.Errorf(codes.NotFound, "failed to resolve chassis: %v", err)
 	}
+	if err := s.correlateLease(session.chassis); err != nil {
+		return err
+	}
 	log.Infof("Resolved device %s to hostname %s", session.chassis.ActiveSerial, session.chassis.Hostname)
 
 	if !session.chassis.StreamingSupported {
@@ -994,7 +1085,7 @@ func (s *Service) updateStatusAndSendAcknowledgement(session *streamSession) err
 		log.Errorf("Failed to set status for device %s: %v", session.chassis.ActiveSerial, err)
 		return status.Errorf(codes.Internal, "failed to set status: %v", err)
 	}
-	log.Infof("Successfully set status for device %s", session.chassis.ActiveSerial)
+	log.Infof("Successfully set status for device %s%s", session.chassis.ActiveSerial, leaseDescription(session.chassis))
 
 	resp := &bpb.BootstrapStreamResponse{
 		Type: &bpb.BootstrapStreamResponse_ReportStatusResponse{
@@ -1009,6 +1100,57 @@ func (s *Service) updateStatusAndSendAcknowledgement(session *streamSession) err
 	return nil
 }
 
+// correlateLease attaches the DHCP lease of the source address of the request to the chassis, and checks that it is an
+// address leased to the active control card according to the lease mismatch policy.
+func (s *Service) correlateLease(chassis *types.Chassis) error {
+	if s.leases == nil {
+		return nil
+	}
+	if l := s.leases.LeaseByIP(chassis.IPAddress); l != nil {
+		chassis.Lease = l
+		log.Infof("Request of device %s from %v matches the DHCP lease of MAC %q and client identifier %q", chassis.ActiveSerial, chassis.IPAddress, l.MAC, l.ClientID)
+	}
+	if s.leasePolicy == LeaseMismatchIgnore {
+		return nil
+	}
+	leased := s.leases.LeasedIPs(chassis.ActiveSerial)
+	if len(leased) == 0 || slices.ContainsFunc(leased, func(ip string) bool { return SameIP(ip, chassis.IPAddress) }) {
+		// Devices without a lease got their address from elsewhere.
+		return nil
+	}
+	chassis.LeaseMismatch = true
+	log.Warningf("Device %s sent a request from %v, but was leased %v by the DHCP server", chassis.ActiveSerial, chassis.IPAddress, leased)
+	if s.leasePolicy == LeaseMismatchReject {
+		return status.Errorf(codes.PermissionDenied, "request source address %v does not match the addresses %v leased to %v", chassis.IPAddress, leased, chassis.ActiveSerial)
+	}
+	return nil
+}
+
+// leaseDescription describes the source address of the requests of the chassis and its DHCP lease for the status logs.
+func leaseDescription(chassis *types.Chassis) string {
+	if chassis.IPAddress == "" {
+		return ""
+	}
+	desc := " from " + chassis.IPAddress
+	if l := chassis.Lease; l != nil {
+		desc += fmt.Sprintf(", leased to MAC %q and client identifier %q", l.MAC, l.ClientID)
+	}
+	if chassis.LeaseMismatch {
+		desc += ", which is not an address leased to it"
+	}
+	return desc
+}
+
+// SameIP returns whether the strings are the same IP address, comparing IPv4-mapped IPv6 addresses as IPv4.
+func SameIP(a, b string) bool {
+	ipA, errA := netip.ParseAddr(a)
+	ipB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ipA.Unmap() == ipB.Unmap()
+}
+
 func peerAddressFromContext(ctx context.Context) (string, error) {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
@@ -1032,10 +1174,12 @@ func initializeChassis(ctx context.Context, msg proto.Message) (*types.Chassis,
 	var cc *bpb.ControlCardState
 	var id *bpb.Identity
 	var serials []string
+	var parts map[string]string
 	switch m := msg.(type) {
 	case *bpb.GetBootstrapDataRequest:
 		cc = m.GetControlCardState()
 		id = m.GetIdentity()
+		parts = partNumbers(m.GetChassisDescriptor())
 		if cards := m.GetChassisDescriptor().GetControlCards(); len(cards) > 0 { // Modular chassis
 			for _, v := range cards {
 				serials = append(serials, v.GetSerialNumber())
@@ -1068,20 +1212,39 @@ func initializeChassis(ctx context.Context, msg proto.Message) (*types.Chassis,
 		ActiveSerial: activeSerial,
 		IPAddress:    peerAddr,
 		Identity:     id,
+		PartNumbers:  parts,
 	}, nil
 }
 
+// partNumbers returns the part numbers of the chassis descriptor, keyed by serial number.
+func partNumbers(desc *bpb.ChassisDescriptor) map[string]string {
+	parts := map[string]string{}
+	if len(desc.GetControlCards()) == 0 { // Fixed form factor chassis
+		parts[desc.GetSerialNumber()] = desc.GetPartNumber()
+		return parts
+	}
+	for _, v := range desc.GetControlCards() {
+		parts[v.GetSerialNumber()] = v.GetPartNumber()
+	}
+	return parts
+}
+
 // New creates a new service.
-func New(am ArtifactManager, cm ChassisManager, tpm20 biz.TPM20Utils) (*Service, error) {
+func New(am ArtifactManager, cm ChassisManager, tpm20 biz.TPM20Utils, opts ...Option) (*Service, error) {
 	if am == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "ArtifactManager cannot be nil")
 	}
 	if cm == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "ChassisManager cannot be nil")
 	}
-	return &Service{
-		am:    am,
-		cm:    cm,
-		tpm20: tpm20,
-	}, nil
+	s := &Service{
+		am:      am,
+		cm:      cm,
+		tpm20:   tpm20,
+		serials: idevid.NewRegistry(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
@@ -27,6 +27,7 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"math/big"
 	"net"
@@ -39,6 +40,7 @@ import (
 	ownershipvoucher "github.com/openconfig/bootz/common/ownership_voucher"
 	"github.com/openconfig/bootz/common/signature"
 	"github.com/openconfig/bootz/common/types"
+	"github.com/openconfig/bootz/server/revocation"
 	"go.mozilla.org/pkcs7"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -118,6 +120,19 @@ func (m *mockChassisManager) UpdateStatus(context.Context, *bpb.ReportStatusRequ
 	return nil
 }
 
+// mockRevocationChecker is for testing purposes.
+type mockRevocationChecker struct {
+	err          error
+	chain        []*x509.Certificate
+	manufacturer string
+}
+
+func (m *mockRevocationChecker) Check(ctx context.Context, chain []*x509.Certificate, manufacturer string) error {
+	m.chain = chain
+	m.manufacturer = manufacturer
+	return m.err
+}
+
 // mockTPM20Utils is for testing purposes.
 type mockTPM20Utils struct {
 	errGenerate error
@@ -766,6 +781,7 @@ func TestInitializeChassis(t *testing.T) {
 			msg: &bpb.GetBootstrapDataRequest{
 				ChassisDescriptor: &bpb.ChassisDescriptor{
 					SerialNumber: testSerial,
+					PartNumber:   "test-part-number",
 				},
 				ControlCardState: &bpb.ControlCardState{
 					SerialNumber: testSerial,
@@ -779,6 +795,7 @@ func TestInitializeChassis(t *testing.T) {
 				ActiveSerial: testSerial,
 				IPAddress:    testIPAddress,
 				Identity:     &bpb.Identity{Type: &bpb.Identity_IdevidCert{}},
+				PartNumbers:  map[string]string{testSerial: "test-part-number"},
 			},
 		},
 		{
@@ -842,3 +859,211 @@ func TestInitializeChassis(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateIDevIDRevocation(t *testing.T) {
+	vendorCA, vendorCAKey, err := ownercertificate.NewRSACertificate("Vendor CA", "", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create vendor certificate authority: %v", err)
+	}
+	deviceCert, _, err := ownercertificate.NewRSACertificate("test-device", testSerial, vendorCA, vendorCAKey)
+	if err != nil {
+		t.Fatalf("Failed to create IDevID certificate: %v", err)
+	}
+	am := &mockArtifactManager{vendorCA: vendorCA}
+	tests := []struct {
+		name        string
+		rc          *mockRevocationChecker
+		wantErrCode codes.Code
+	}{
+		{
+			name: "No revocation checker",
+		},
+		{
+			name: "Not revoked",
+			rc:   &mockRevocationChecker{},
+		},
+		{
+			name:        "Revoked",
+			rc:          &mockRevocationChecker{err: fmt.Errorf("%w: test", revocation.ErrRevoked)},
+			wantErrCode: codes.PermissionDenied,
+		},
+		{
+			name:        "Revocation status unknown",
+			rc:          &mockRevocationChecker{err: fmt.Errorf("%w: test", revocation.ErrStatusUnknown)},
+			wantErrCode: codes.Unavailable,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts []Option
+			if test.rc != nil {
+				opts = append(opts, WithRevocationChecker(test.rc))
+			}
+			s, err := New(am, &mockChassisManager{}, &mockTPM20Utils{}, opts...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			chassis := &types.Chassis{
+				Serials:      []string{testSerial},
+				ActiveSerial: testSerial,
+				Manufacturer: "Cisco",
+				Identity:     &bpb.Identity{Type: &bpb.Identity_IdevidCert{IdevidCert: base64.StdEncoding.EncodeToString(deviceCert.Raw)}},
+			}
+			_, err = s.validateIDevID(context.Background(), chassis)
+			if got := status.Code(err); got != test.wantErrCode {
+				t.Errorf("validateIDevID() got error code %v, want %v: %v", got, test.wantErrCode, err)
+			}
+			if test.rc == nil {
+				return
+			}
+			if test.rc.manufacturer != "Cisco" {
+				t.Errorf("Check() got manufacturer %q, want %q", test.rc.manufacturer, "Cisco")
+			}
+			if len(test.rc.chain) != 2 || !test.rc.chain[0].Equal(deviceCert) || !test.rc.chain[1].Equal(vendorCA) {
+				t.Errorf("Check() got unexpected chain %v", test.rc.chain)
+			}
+		})
+	}
+}
+
+// mockLeaseLookup is for testing purposes.
+type mockLeaseLookup struct {
+	leases map[string]*types.DHCPLease
+	leased map[string][]string
+}
+
+func (m *mockLeaseLookup) LeaseByIP(ip string) *types.DHCPLease {
+	return m.leases[ip]
+}
+
+func (m *mockLeaseLookup) LeasedIPs(serial string) []string {
+	return m.leased[serial]
+}
+
+func TestCorrelateLease(t *testing.T) {
+	lease := &types.DHCPLease{IP: testIPAddress, MAC: "00:1c:73:00:00:01", ClientID: testSerial}
+	ll := &mockLeaseLookup{
+		leases: map[string]*types.DHCPLease{testIPAddress: lease},
+		leased: map[string][]string{testSerial: {"2001:db8::1", testIPAddress}, "other-serial": {"10.0.0.99"}},
+	}
+	tests := []struct {
+		name         string
+		policy       LeaseMismatchPolicy
+		serial       string
+		ip           string
+		wantLease    *types.DHCPLease
+		wantMismatch bool
+		wantErrCode  codes.Code
+	}{
+		{
+			name:      "Leased address",
+			policy:    LeaseMismatchReject,
+			serial:    testSerial,
+			ip:        testIPAddress,
+			wantLease: lease,
+		},
+		{
+			name:   "No lease",
+			policy: LeaseMismatchReject,
+			serial: "unleased-serial",
+			ip:     "10.0.0.50",
+		},
+		{
+			name:      "Mismatch ignored",
+			policy:    LeaseMismatchIgnore,
+			serial:    "other-serial",
+			ip:        testIPAddress,
+			wantLease: lease,
+		},
+		{
+			name:         "Mismatch flagged",
+			policy:       LeaseMismatchFlag,
+			serial:       "other-serial",
+			ip:           testIPAddress,
+			wantLease:    lease,
+			wantMismatch: true,
+		},
+		{
+			name:         "Mismatch rejected",
+			policy:       LeaseMismatchReject,
+			serial:       "other-serial",
+			ip:           testIPAddress,
+			wantLease:    lease,
+			wantMismatch: true,
+			wantErrCode:  codes.PermissionDenied,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := New(&mockArtifactManager{}, &mockChassisManager{}, &mockTPM20Utils{}, WithLeaseLookup(ll, test.policy))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			chassis := &types.Chassis{ActiveSerial: test.serial, IPAddress: test.ip}
+			err = s.correlateLease(chassis)
+			if got := status.Code(err); got != test.wantErrCode {
+				t.Errorf("correlateLease() got error code %v, want %v: %v", got, test.wantErrCode, err)
+			}
+			if chassis.Lease != test.wantLease {
+				t.Errorf("correlateLease() got lease %+v, want %+v", chassis.Lease, test.wantLease)
+			}
+			if chassis.LeaseMismatch != test.wantMismatch {
+				t.Errorf("correlateLease() got lease mismatch %v, want %v", chassis.LeaseMismatch, test.wantMismatch)
+			}
+		})
+	}
+}
+
+func TestReportStatusLease(t *testing.T) {
+	ll := &mockLeaseLookup{leased: map[string][]string{testSerial: {testIPAddress}, "other-serial": {"10.0.0.99"}}}
+	s, err := New(&mockArtifactManager{}, &mockChassisManager{}, &mockTPM20Utils{}, WithLeaseLookup(ll, LeaseMismatchReject))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tests := []struct {
+		name        string
+		serial      string
+		wantErrCode codes.Code
+	}{
+		{name: "Leased address", serial: testSerial},
+		{name: "Mismatch rejected", serial: "other-serial", wantErrCode: codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &bpb.ReportStatusRequest{States: []*bpb.ControlCardState{{SerialNumber: test.serial}}}
+			_, err := s.ReportStatus(peerAddressContext(t, testIPAddress), req)
+			if got := status.Code(err); got != test.wantErrCode {
+				t.Errorf("ReportStatus() got error code %v, want %v: %v", got, test.wantErrCode, err)
+			}
+		})
+	}
+}
+
+func TestLeaseDescription(t *testing.T) {
+	lease := &types.DHCPLease{IP: testIPAddress, MAC: "00:1c:73:00:00:01", ClientID: testSerial}
+	tests := []struct {
+		name    string
+		chassis *types.Chassis
+		want    string
+	}{
+		{name: "No address", chassis: &types.Chassis{}},
+		{name: "No lease", chassis: &types.Chassis{IPAddress: testIPAddress}, want: " from 1.2.3.4"},
+		{
+			name:    "Lease",
+			chassis: &types.Chassis{IPAddress: testIPAddress, Lease: lease},
+			want:    ` from 1.2.3.4, leased to MAC "00:1c:73:00:00:01" and client identifier "test-serial-123"`,
+		},
+		{
+			name:    "Lease mismatch",
+			chassis: &types.Chassis{IPAddress: testIPAddress, Lease: lease, LeaseMismatch: true},
+			want:    ` from 1.2.3.4, leased to MAC "00:1c:73:00:00:01" and client identifier "test-serial-123", which is not an address leased to it`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := leaseDescription(test.chassis); got != test.want {
+				t.Errorf("leaseDescription() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,521 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tftp implements a read-only TFTP server (RFC 1350) for bootloaders which cannot download images over HTTP.
+// It negotiates the blksize (RFC 2348), timeout and tsize (RFC 2349) and windowsize (RFC 7440) options.
+package tftp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+)
+
+const (
+	opRRQ   = 1
+	opWRQ   = 2
+	opDATA  = 3
+	opACK   = 4
+	opERROR = 5
+	opOACK  = 6
+
+	errNotDefined       = 0
+	errFileNotFound     = 1
+	errAccessViolation  = 2
+	errIllegalOperation = 4
+	errUnknownTID       = 5
+	errOptionRejected   = 8
+
+	defaultBlockSize     = 512
+	minBlockSize         = 8
+	maxBlockSize         = 65464
+	defaultMaxWindowSize = 64
+	maxWindowSize        = 65535
+	defaultTimeout       = 5 * time.Second
+	defaultRetries       = 5
+)
+
+// Opts are the options of the TFTP server.
+type Opts struct {
+	// Address is the UDP address the server listens at, e.g. ":69".
+	Address string
+	// Folder is the folder the files are served from. The Bootz server defaults it to the folder of the image catalog.
+	// Every file of the folder is served to any client, which bypasses the access control of signed image URLs: the
+	// Bootz server refuses to start the TFTP server with image_url_signing unless AllowUnsignedAccess is set.
+	Folder string
+	// AllowUnsignedAccess lets the Bootz server start the TFTP server although it signs the image URLs.
+	AllowUnsignedAccess bool
+	// MaxBlockSize caps the negotiated block size, e.g. to avoid IP fragmentation. Defaults to 65464.
+	MaxBlockSize int
+	// MaxWindowSize caps the negotiated window size. Defaults to 64.
+	MaxWindowSize int
+	// Timeout is the retransmission timeout, unless the client negotiates one. Defaults to 5 seconds.
+	Timeout time.Duration
+	// Retries is how many times a packet is retransmitted before the transfer is aborted. Defaults to 5.
+	Retries int
+}
+
+func (*Opts) IsBootzServerOpts() {}
+
+// Server is a read-only TFTP server.
+type Server struct {
+	opts Opts
+	conn *net.UDPConn
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+var instance *Server = nil
+var lock = &sync.Mutex{}
+
+// Start starts the TFTP server with the given configuration.
+func Start(conf *Opts) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if instance != nil {
+		return fmt.Errorf("tftp server already started")
+	}
+	s, err := newServer(conf)
+	if err != nil {
+		return err
+	}
+	instance = s
+	log.Infof("Serving tftp at address %q for folder %q", s.conn.LocalAddr(), conf.Folder)
+	return nil
+}
+
+// Stop stops the TFTP server, and waits for the transfers in progress to be aborted.
+func Stop() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if instance != nil {
+		instance.close()
+	}
+	instance = nil
+}
+
+// newServer validates the configuration and starts serving.
+func newServer(conf *Opts) (*Server, error) {
+	if conf.Folder == "" {
+		return nil, fmt.Errorf("serving folder not specified")
+	}
+	if _, err := os.ReadDir(conf.Folder); err != nil {
+		return nil, fmt.Errorf("folder is not accessible: %v", err)
+	}
+	opts := *conf
+	if opts.MaxBlockSize == 0 {
+		opts.MaxBlockSize = maxBlockSize
+	}
+	if opts.MaxBlockSize < defaultBlockSize || opts.MaxBlockSize > maxBlockSize {
+		return nil, fmt.Errorf("max block size must be between %d and %d, got %d", defaultBlockSize, maxBlockSize, opts.MaxBlockSize)
+	}
+	if opts.MaxWindowSize == 0 {
+		opts.MaxWindowSize = defaultMaxWindowSize
+	}
+	if opts.MaxWindowSize < 1 || opts.MaxWindowSize > maxWindowSize {
+		return nil, fmt.Errorf("max window size must be between 1 and %d, got %d", maxWindowSize, opts.MaxWindowSize)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = defaultRetries
+	}
+	addr, err := net.ResolveUDPAddr("udp", opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tftp server address %q: %v", opts.Address, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen at %q: %v", opts.Address, err)
+	}
+	s := &Server{opts: opts, conn: conn, quit: make(chan struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) close() {
+	close(s.quit)
+	s.conn.Close()
+	s.wg.Wait()
+}
+
+// serve handles the requests until the server is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+	buf := make([]byte, maxBlockSize+4)
+	for {
+		n, peer, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			log.Warningf("Unable to read tftp request: %v", err)
+			continue
+		}
+		p := append([]byte(nil), buf[:n]...)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(p, peer)
+		}()
+	}
+}
+
+// request is a read request.
+type request struct {
+	filename string
+	mode     string
+	// options are the requested options, keyed by lowercase name.
+	options map[string]string
+}
+
+// parseRequest parses a read or write request.
+func parseRequest(p []byte) (*request, error) {
+	fields := bytes.Split(p[2:], []byte{0})
+	// The last field is the empty string after the final NUL.
+	if len(fields) < 3 || len(fields[len(fields)-1]) != 0 || len(fields)%2 != 1 {
+		return nil, fmt.Errorf("malformed request")
+	}
+	r := &request{filename: string(fields[0]), mode: strings.ToLower(string(fields[1])), options: map[string]string{}}
+	for i := 2; i+1 < len(fields); i += 2 {
+		r.options[strings.ToLower(string(fields[i]))] = string(fields[i+1])
+	}
+	return r, nil
+}
+
+// handle serves a request from a new transfer identifier, as required by RFC 1350.
+func (s *Server) handle(p []byte, peer *net.UDPAddr) {
+	local := &net.UDPAddr{IP: s.conn.LocalAddr().(*net.UDPAddr).IP}
+	conn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		log.Warningf("Unable to open tftp transfer socket for %v: %v", peer, err)
+		return
+	}
+	defer conn.Close()
+	t := &transfer{conn: conn, peer: peer, quit: s.quit, blockSize: defaultBlockSize, windowSize: 1, timeout: s.opts.Timeout, retries: s.opts.Retries}
+
+	if len(p) < 2 {
+		return
+	}
+	switch binary.BigEndian.Uint16(p) {
+	case opRRQ:
+	case opWRQ:
+		t.sendError(errAccessViolation, "server is read-only")
+		return
+	default:
+		t.sendError(errIllegalOperation, "illegal operation")
+		return
+	}
+	req, err := parseRequest(p)
+	if err != nil {
+		t.sendError(errIllegalOperation, err.Error())
+		return
+	}
+	start := time.Now()
+	n, err := s.serveFile(t, req)
+	if err != nil {
+		log.Warningf("tftp transfer of %q to %v failed after %d bytes: %v", req.filename, peer, n, err)
+		return
+	}
+	log.Infof("Served %q to %v over tftp: %d bytes, block size %d, window size %d in %v", req.filename, peer, n, t.blockSize, t.windowSize, time.Since(start))
+}
+
+// serveFile negotiates the options of the request and sends the file. It returns the number of bytes sent.
+func (s *Server) serveFile(t *transfer, req *request) (int64, error) {
+	if req.mode != "octet" && req.mode != "netascii" {
+		t.sendError(errIllegalOperation, "unsupported mode "+req.mode)
+		return 0, fmt.Errorf("unsupported mode %q", req.mode)
+	}
+	f, info, err := s.open(req.filename)
+	if err != nil {
+		t.sendError(errFileNotFound, "file not found")
+		return 0, err
+	}
+	defer f.Close()
+
+	oack := map[string]string{}
+	for name, value := range req.options {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "blksize":
+			if n >= minBlockSize {
+				t.blockSize = min(n, s.opts.MaxBlockSize)
+				oack[name] = strconv.Itoa(t.blockSize)
+			}
+		case "windowsize":
+			if n >= 1 && n <= maxWindowSize {
+				t.windowSize = min(n, s.opts.MaxWindowSize)
+				oack[name] = strconv.Itoa(t.windowSize)
+			}
+		case "timeout":
+			if n >= 1 && n <= 255 {
+				t.timeout = time.Duration(n) * time.Second
+				oack[name] = value
+			}
+		case "tsize":
+			// The transfer size of netascii files is only known after their conversion.
+			if req.mode == "octet" {
+				oack[name] = strconv.FormatInt(info.Size(), 10)
+			}
+		}
+	}
+	if len(oack) > 0 {
+		if err := t.negotiate(oack); err != nil {
+			return 0, err
+		}
+	}
+	var r io.Reader = bufio.NewReader(f)
+	if req.mode == "netascii" {
+		r = &netasciiReader{r: bufio.NewReader(f)}
+	}
+	return t.send(r)
+}
+
+// open opens the file of the request, which must be a regular file within the folder.
+func (s *Server) open(filename string) (*os.File, os.FileInfo, error) {
+	name := path.Clean("/" + strings.ReplaceAll(filename, `\`, "/"))
+	f, err := os.Open(filepath.Join(s.opts.Folder, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		f.Close()
+		return nil, nil, fmt.Errorf("%q is not a file", filename)
+	}
+	return f, info, nil
+}
+
+// errAborted is returned when a transfer is aborted by the client or the server shutdown.
+var errAborted = errors.New("transfer aborted")
+
+// transfer is the state of a transfer to a client.
+type transfer struct {
+	conn       *net.UDPConn
+	peer       *net.UDPAddr
+	quit       chan struct{}
+	blockSize  int
+	windowSize int
+	timeout    time.Duration
+	retries    int
+}
+
+func (t *transfer) write(p []byte) error {
+	_, err := t.conn.WriteToUDP(p, t.peer)
+	return err
+}
+
+func (t *transfer) sendError(code uint16, msg string) {
+	p := binary.BigEndian.AppendUint16(nil, opERROR)
+	p = binary.BigEndian.AppendUint16(p, code)
+	p = append(append(p, msg...), 0)
+	t.write(p)
+}
+
+// negotiate sends the option acknowledgment and waits for the client to acknowledge it with block 0.
+func (t *transfer) negotiate(oack map[string]string) error {
+	p := binary.BigEndian.AppendUint16(nil, opOACK)
+	for name, value := range oack {
+		p = append(append(p, name...), 0)
+		p = append(append(p, value...), 0)
+	}
+	for retry := 0; retry <= t.retries; retry++ {
+		if err := t.write(p); err != nil {
+			return err
+		}
+		block, err := t.readAck(time.Now().Add(t.timeout))
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if block == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("option acknowledgment timed out")
+}
+
+// send sends the content of the reader in windows of blocks. Block numbers roll over to 0 after 65535.
+func (t *transfer) send(r io.Reader) (int64, error) {
+	var (
+		// window holds the unacknowledged blocks, the first of which is block number first.
+		window [][]byte
+		first  = uint16(1)
+		eof    bool
+		sent   int64
+		retry  int
+	)
+	for {
+		for len(window) < t.windowSize && !eof {
+			b := make([]byte, t.blockSize)
+			n, err := io.ReadFull(r, b)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// A block shorter than the block size, possibly empty, ends the transfer.
+				eof = true
+			} else if err != nil {
+				t.sendError(errNotDefined, "unable to read file")
+				return sent, err
+			}
+			window = append(window, b[:n])
+		}
+		if len(window) == 0 {
+			return sent, nil
+		}
+		for i, b := range window {
+			p := binary.BigEndian.AppendUint16(nil, opDATA)
+			p = binary.BigEndian.AppendUint16(p, first+uint16(i))
+			if err := t.write(append(p, b...)); err != nil {
+				return sent, err
+			}
+		}
+		// The client acknowledges the last block it received in sequence, and the blocks after it are sent again.
+		// Acknowledgments of no new block, such as duplicates, are ignored.
+		acked := 0
+		deadline := time.Now().Add(t.timeout)
+		for acked == 0 {
+			block, err := t.readAck(deadline)
+			if err != nil {
+				if !errors.Is(err, os.ErrDeadlineExceeded) {
+					return sent, err
+				}
+				break
+			}
+			if n := int(block - (first - 1)); n <= len(window) {
+				acked = n
+			}
+		}
+		if acked == 0 {
+			if retry++; retry > t.retries {
+				return sent, fmt.Errorf("timed out waiting for block %d to be acknowledged", first)
+			}
+			continue
+		}
+		retry = 0
+		for _, b := range window[:acked] {
+			sent += int64(len(b))
+		}
+		window = window[acked:]
+		first += uint16(acked)
+	}
+}
+
+// readAck waits until the deadline for an acknowledgment from the client, and returns its block number. Packets from
+// other transfer identifiers are rejected.
+func (t *transfer) readAck(deadline time.Time) (uint16, error) {
+	buf := make([]byte, 1024)
+	for {
+		select {
+		case <-t.quit:
+			return 0, errAborted
+		default:
+		}
+		// The deadline is checked at least every second to notice the server shutdown.
+		t.conn.SetReadDeadline(minTime(deadline, time.Now().Add(time.Second)))
+		n, peer, err := t.conn.ReadFromUDP(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) && time.Now().Before(deadline) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !peer.IP.Equal(t.peer.IP) || peer.Port != t.peer.Port {
+			errT := &transfer{conn: t.conn, peer: peer}
+			errT.sendError(errUnknownTID, "unknown transfer id")
+			continue
+		}
+		if n < 4 {
+			continue
+		}
+		switch binary.BigEndian.Uint16(buf) {
+		case opACK:
+			return binary.BigEndian.Uint16(buf[2:]), nil
+		case opERROR:
+			if code := binary.BigEndian.Uint16(buf[2:]); code == errOptionRejected {
+				return 0, fmt.Errorf("%w: client rejected the options", errAborted)
+			}
+			return 0, fmt.Errorf("%w: %q", errAborted, bytes.TrimRight(buf[4:n], "\x00"))
+		}
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// netasciiReader converts the content of a reader to netascii: LF is sent as CR LF, and CR as CR NUL.
+type netasciiReader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+var (
+	crlf  = []byte("\r\n")
+	crnul = []byte("\r\x00")
+)
+
+func (n *netasciiReader) Read(p []byte) (int, error) {
+	i := 0
+	for i < len(p) {
+		if len(n.pending) > 0 {
+			c := copy(p[i:], n.pending)
+			n.pending = n.pending[c:]
+			i += c
+			continue
+		}
+		c, err := n.r.ReadByte()
+		if err != nil {
+			if i > 0 {
+				return i, nil
+			}
+			return 0, err
+		}
+		switch c {
+		case '\n':
+			n.pending = crlf
+		case '\r':
+			n.pending = crnul
+		default:
+			p[i] = c
+			i++
+		}
+	}
+	return i, nil
+}
@@ -0,0 +1,326 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tftp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// tftpError is an ERROR packet received by the client.
+type tftpError struct {
+	code uint16
+	msg  string
+}
+
+func (e *tftpError) Error() string {
+	return fmt.Sprintf("tftp error %d: %v", e.code, e.msg)
+}
+
+// result is the outcome of a read request of the test client.
+type result struct {
+	data []byte
+	// oack are the options acknowledged by the server.
+	oack map[string]string
+	// acks is the number of acknowledgments sent for data blocks.
+	acks int
+}
+
+// fetch reads a file from the server with an in-process TFTP client, which acknowledges each window of blocks, and
+// once the last block received in sequence when a block is missing. drop returns whether a data block is lost.
+func fetch(t *testing.T, server net.Addr, packet []byte, drop func(block uint16) bool) (*result, error) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.WriteTo(packet, server); err != nil {
+		t.Fatal(err)
+	}
+
+	res := &result{}
+	var peer net.Addr
+	ack := func(block uint16) {
+		p := binary.BigEndian.AppendUint16(nil, opACK)
+		conn.WriteTo(binary.BigEndian.AppendUint16(p, block), peer)
+	}
+	blockSize, windowSize := defaultBlockSize, 1
+	next, received := uint16(1), 0
+	// gap is set once a missing block has been acknowledged.
+	gap := false
+	buf := make([]byte, maxBlockSize+4)
+	for timeouts := 0; timeouts < 3; {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if peer == nil {
+				return nil, err
+			}
+			// Acknowledge the last block received in sequence so that the server sends the next ones again.
+			timeouts++
+			ack(next - 1)
+			received = 0
+			continue
+		}
+		peer = from
+		p := buf[:n]
+		switch binary.BigEndian.Uint16(p) {
+		case opERROR:
+			return nil, &tftpError{code: binary.BigEndian.Uint16(p[2:]), msg: string(bytes.TrimRight(p[4:], "\x00"))}
+		case opOACK:
+			res.oack = map[string]string{}
+			fields := bytes.Split(p[2:], []byte{0})
+			for i := 0; i+1 < len(fields); i += 2 {
+				res.oack[string(fields[i])] = string(fields[i+1])
+			}
+			if v, ok := res.oack["blksize"]; ok {
+				blockSize, _ = strconv.Atoi(v)
+			}
+			if v, ok := res.oack["windowsize"]; ok {
+				windowSize, _ = strconv.Atoi(v)
+			}
+			ack(0)
+		case opDATA:
+			block := binary.BigEndian.Uint16(p[2:])
+			if drop != nil && drop(block) {
+				continue
+			}
+			if block != next {
+				if !gap {
+					ack(next - 1)
+					res.acks++
+					received = 0
+					gap = true
+				}
+				continue
+			}
+			gap = false
+			res.data = append(res.data, p[4:]...)
+			next++
+			received++
+			if len(p[4:]) < blockSize {
+				ack(block)
+				res.acks++
+				return res, nil
+			}
+			if received == windowSize {
+				ack(block)
+				res.acks++
+				received = 0
+			}
+		}
+	}
+	return nil, fmt.Errorf("transfer timed out")
+}
+
+// rrq returns a read request for the file with the options, given as name and value pairs.
+func rrq(op uint16, filename, mode string, options ...string) []byte {
+	p := binary.BigEndian.AppendUint16(nil, op)
+	for _, f := range append([]string{filename, mode}, options...) {
+		p = append(append(p, f...), 0)
+	}
+	return p
+}
+
+func newTestServer(t *testing.T, opts *Opts, files map[string][]byte) *Server {
+	t.Helper()
+	folder := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(folder, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts.Address = "127.0.0.1:0"
+	opts.Folder = folder
+	s, err := newServer(opts)
+	if err != nil {
+		t.Fatalf("newServer() err = %v, want nil", err)
+	}
+	t.Cleanup(s.close)
+	return s
+}
+
+func content(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+func TestRead(t *testing.T) {
+	files := map[string][]byte{
+		"os/1.0/os.bin": content(100000),
+		"small.bin":     content(1000),
+		"exact.bin":     content(1024),
+		"empty.bin":     nil,
+		"boot.cfg":      []byte("a\nb\rc"),
+	}
+	s := newTestServer(t, &Opts{MaxBlockSize: 1468}, files)
+
+	tests := []struct {
+		desc     string
+		packet   []byte
+		drop     func(block uint16) bool
+		want     []byte
+		wantOACK map[string]string
+		wantAcks int
+		wantErr  uint16
+	}{{
+		desc:     "No options",
+		packet:   rrq(opRRQ, "small.bin", "octet"),
+		want:     files["small.bin"],
+		wantAcks: 2,
+	}, {
+		desc:     "Multiple of the block size",
+		packet:   rrq(opRRQ, "exact.bin", "octet"),
+		want:     files["exact.bin"],
+		wantAcks: 3,
+	}, {
+		desc:     "Empty file",
+		packet:   rrq(opRRQ, "/empty.bin", "OCTET"),
+		want:     nil,
+		wantAcks: 1,
+	}, {
+		desc:     "Block size and transfer size",
+		packet:   rrq(opRRQ, "os/1.0/os.bin", "octet", "blksize", "1024", "tsize", "0"),
+		want:     files["os/1.0/os.bin"],
+		wantOACK: map[string]string{"blksize": "1024", "tsize": "100000"},
+		wantAcks: 98,
+	}, {
+		desc:     "Capped block size and window size",
+		packet:   rrq(opRRQ, "os/1.0/os.bin", "octet", "blksize", "65464", "windowsize", "16"),
+		want:     files["os/1.0/os.bin"],
+		wantOACK: map[string]string{"blksize": "1468", "windowsize": "16"},
+		wantAcks: 5,
+	}, {
+		desc:   "Lost block in a window",
+		packet: rrq(opRRQ, "os/1.0/os.bin", "octet", "blksize", "1024", "windowsize", "8"),
+		drop: func() func(uint16) bool {
+			dropped := false
+			return func(block uint16) bool {
+				if block == 3 && !dropped {
+					dropped = true
+					return true
+				}
+				return false
+			}
+		}(),
+		want:     files["os/1.0/os.bin"],
+		wantOACK: map[string]string{"blksize": "1024", "windowsize": "8"},
+		wantAcks: 13,
+	}, {
+		desc:     "Netascii",
+		packet:   rrq(opRRQ, "boot.cfg", "netascii", "tsize", "0"),
+		want:     []byte("a\r\nb\r\x00c"),
+		wantAcks: 1,
+	}, {
+		desc:    "Missing file",
+		packet:  rrq(opRRQ, "missing.bin", "octet"),
+		wantErr: errFileNotFound,
+	}, {
+		desc:    "Directory",
+		packet:  rrq(opRRQ, "os", "octet"),
+		wantErr: errFileNotFound,
+	}, {
+		desc:    "Outside of the folder",
+		packet:  rrq(opRRQ, "../"+filepath.Base(s.opts.Folder)+"/small.bin/../../../etc/passwd", "octet"),
+		wantErr: errFileNotFound,
+	}, {
+		desc:    "Write request",
+		packet:  rrq(opWRQ, "small.bin", "octet"),
+		wantErr: errAccessViolation,
+	}, {
+		desc:    "Mail mode",
+		packet:  rrq(opRRQ, "small.bin", "mail"),
+		wantErr: errIllegalOperation,
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := fetch(t, s.conn.LocalAddr(), test.packet, test.drop)
+			if test.wantErr != 0 {
+				var tErr *tftpError
+				if !errors.As(err, &tErr) || tErr.code != test.wantErr {
+					t.Fatalf("fetch() err = %v, want tftp error %d", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetch() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got.data, test.want) {
+				t.Errorf("fetch() got %d bytes, want %d bytes", len(got.data), len(test.want))
+			}
+			if diff := cmp.Diff(test.wantOACK, got.oack); diff != "" {
+				t.Errorf("fetch() option acknowledgment diff (-want, +got):\n%s", diff)
+			}
+			if got.acks != test.wantAcks {
+				t.Errorf("fetch() sent %d acknowledgments, want %d", got.acks, test.wantAcks)
+			}
+		})
+	}
+}
+
+func TestBlockRollover(t *testing.T) {
+	// 70000 blocks of 8 bytes roll the block number over.
+	want := content(70000 * 8)
+	s := newTestServer(t, &Opts{}, map[string][]byte{"os.bin": want})
+	got, err := fetch(t, s.conn.LocalAddr(), rrq(opRRQ, "os.bin", "octet", "blksize", "8", "windowsize", "64"), nil)
+	if err != nil {
+		t.Fatalf("fetch() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got.data, want) {
+		t.Errorf("fetch() got %d bytes, want %d bytes", len(got.data), len(want))
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		desc string
+		opts *Opts
+	}{{
+		desc: "Missing folder",
+		opts: &Opts{Address: "127.0.0.1:0"},
+	}, {
+		desc: "Block size too small",
+		opts: &Opts{Address: "127.0.0.1:0", Folder: t.TempDir(), MaxBlockSize: 100},
+	}, {
+		desc: "Window size too large",
+		opts: &Opts{Address: "127.0.0.1:0", Folder: t.TempDir(), MaxWindowSize: 70000},
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if s, err := newServer(test.opts); err == nil {
+				s.close()
+				t.Errorf("newServer() err = nil, want error")
+			}
+		})
+	}
+}